@@ -1,6 +1,7 @@
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -28,10 +29,385 @@ type OpenAPISpecSpec struct {
 	// +optional
 	Mock bool `json:"mock,omitempty"`
 
+	// Options tuning the generation of fake examples when mock is enabled
+	// +optional
+	MockOptions *MockOptions `json:"mockOptions,omitempty"`
+
+	// OpenAPI Overlays applied in order to the fetched specification, before it is upgraded,
+	// validated and mocked
+	// +optional
+	Overlays []OverlaySource `json:"overlays,omitempty"`
+
+	// Upgrades the specification to the given OpenAPI version before publishing it.
+	// Swagger 2.0 documents are converted to OpenAPI 3.0 first.
+	// +kubebuilder:validation:Enum="3.0";"3.1"
+	// +optional
+	UpgradeTo string `json:"upgradeTo,omitempty"`
+
+	// Removes the operations, parameters, schema properties and tags flagged internal from the published spec,
+	// such as for a public variant of an internal spec
+	// +optional
+	StripInternal *StripInternalOptions `json:"stripInternal,omitempty"`
+
+	// Records the traffic of a running service through the /record/{namespace}/{name}/ proxy
+	// and replays it as the mock of the spec
+	// +optional
+	Recording *RecordingOptions `json:"recording,omitempty"`
+
+	// Lets readers of the documentation call the service through the /proxy/{namespace}/{name}/ proxy.
+	// The servers of the published spec point at the proxy.
+	// +optional
+	TryItOut *TryItOutOptions `json:"tryItOut,omitempty"`
+
+	// Periodically checks that a running service still matches the spec
+	// +optional
+	ContractTest *ContractTestOptions `json:"contractTest,omitempty"`
+
+	// Probes the service in the background to show whether the API is up
+	// +optional
+	HealthCheck *HealthCheckOptions `json:"healthCheck,omitempty"`
+
+	// Overrides the cluster-wide branding on the documentation page of the spec
+	// +optional
+	Branding *Branding `json:"branding,omitempty"`
+
+	// Audience of the spec. Portals only list the visibility levels they are configured for.
+	// Defaults to internal.
+	// +kubebuilder:validation:Enum=public;internal;private
+	// +optional
+	Visibility string `json:"visibility,omitempty"`
+
 	// Theme customization options for Redoc
 	Theme map[string]string `json:"theme,omitempty"`
 }
 
+// MockOptions tunes the generation of fake examples
+type MockOptions struct {
+	// Maximum nesting depth of generated examples, recursive schemas are cut at this depth
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxDepth int `json:"maxDepth,omitempty"`
+
+	// Maximum number of items generated for arrays and maps
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxArrayLength int `json:"maxArrayLength,omitempty"`
+
+	// Seed of the fake data generator. When unset, a stable seed is derived from
+	// the namespace and name so the published examples never change between reconciles.
+	// +optional
+	Seed *int64 `json:"seed,omitempty"`
+
+	// How generated examples are merged with the examples written in the spec:
+	// fillMissing keeps existing examples and only fills the gaps, append adds a generated
+	// example next to them and replace discards them. Defaults to fillMissing.
+	// +kubebuilder:validation:Enum=fillMissing;append;replace
+	// +optional
+	ExamplePolicy string `json:"examplePolicy,omitempty"`
+
+	// Language of generated names, addresses, phone numbers and text, such as "fr" or "de".
+	// Live mock requests can override it with their Accept-Language header.
+	// +optional
+	Locale string `json:"locale,omitempty"`
+
+	// Generate related examples from a shared pool of instances per schema: references reuse
+	// the same objects, fields such as customerId point at existing instances and successful
+	// responses echo the values sent in the request.
+	// +optional
+	Consistent bool `json:"consistent,omitempty"`
+
+	// Delivery of the callbacks and webhooks declared in the spec by the live mock server.
+	// Callbacks are only sent when this block is set.
+	// +optional
+	Callbacks *CallbackOptions `json:"callbacks,omitempty"`
+}
+
+// CallbackOptions tunes how the mock server delivers callbacks and webhooks
+type CallbackOptions struct {
+	// Delay before the first delivery attempt, such as "2s"
+	// +optional
+	Delay *metav1.Duration `json:"delay,omitempty"`
+
+	// Number of retries after a failed delivery, with an exponential backoff capped at a minute. Defaults to 3.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+
+	// Header carrying the HMAC-SHA256 signature of the payload. Defaults to X-Redokube-Signature.
+	// +optional
+	SignatureHeader string `json:"signatureHeader,omitempty"`
+
+	// Key of a Secret in the namespace of the resource used to sign the payloads.
+	// Payloads are not signed when unset.
+	// +optional
+	SecretRef *corev1.SecretKeySelector `json:"secretRef,omitempty"`
+}
+
+// StripInternalOptions configures the removal of internal elements
+type StripInternalOptions struct {
+	// Extension flagging the internal elements with a true value. Defaults to x-internal.
+	// +optional
+	Extension string `json:"extension,omitempty"`
+}
+
+// OverlaySource holds an OpenAPI Overlay 1.0 document in JSON or YAML, either inline or in a ConfigMap
+type OverlaySource struct {
+	// Overlay document written inline
+	// +optional
+	Content string `json:"content,omitempty"`
+
+	// Key of a ConfigMap in the namespace of the resource holding the overlay document
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+}
+
+// DeepCopyInto copies all properties of this source into another source
+func (in *OverlaySource) DeepCopyInto(out *OverlaySource) {
+	*out = *in
+
+	if in.ConfigMapKeyRef != nil {
+		out.ConfigMapKeyRef = new(corev1.ConfigMapKeySelector)
+		in.ConfigMapKeyRef.DeepCopyInto(out.ConfigMapKeyRef)
+	}
+}
+
+// ServiceTarget designates a Service in the namespace of the resource
+type ServiceTarget struct {
+	// Name of the Service
+	ServiceName string `json:"serviceName"`
+
+	// Port of the Service
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// Scheme used to reach the Service. Defaults to http.
+	// +kubebuilder:validation:Enum=http;https
+	// +optional
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// RecordingOptions configures the recording proxy of a spec
+type RecordingOptions struct {
+	// Service whose traffic is recorded
+	Target ServiceTarget `json:"target"`
+
+	// Serve the recorded responses from the mock server, generated responses are
+	// only used for operations without recordings
+	// +optional
+	Replay bool `json:"replay,omitempty"`
+
+	// Number of request/response pairs kept per operation. Defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxPerOperation int `json:"maxPerOperation,omitempty"`
+
+	// Add the recorded responses to the examples of the published spec
+	// +optional
+	PromoteToExamples bool `json:"promoteToExamples,omitempty"`
+}
+
+// TryItOutOptions configures the proxy used to call a service from its documentation
+type TryItOutOptions struct {
+	// Service receiving the calls
+	Target ServiceTarget `json:"target"`
+
+	// Path prepended to the forwarded requests, such as "/v1"
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+
+	// Headers added to the forwarded requests
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Headers added to the forwarded requests with a value read from a Secret, such as an API key
+	// +optional
+	SecretHeaders []SecretHeader `json:"secretHeaders,omitempty"`
+
+	// Number of requests a user may send per minute. Defaults to 60.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RateLimit int32 `json:"rateLimit,omitempty"`
+}
+
+// SecretHeader is a header whose value is read from a Secret
+type SecretHeader struct {
+	// Name of the header
+	Name string `json:"name"`
+
+	// Key of a Secret in the namespace of the resource holding the value
+	SecretKeyRef corev1.SecretKeySelector `json:"secretKeyRef"`
+}
+
+// DeepCopyInto copies all properties of these options into other options
+func (in *TryItOutOptions) DeepCopyInto(out *TryItOutOptions) {
+	*out = *in
+
+	if in.Headers != nil {
+		out.Headers = make(map[string]string)
+		for k, v := range in.Headers {
+			out.Headers[k] = v
+		}
+	}
+
+	if in.SecretHeaders != nil {
+		out.SecretHeaders = make([]SecretHeader, len(in.SecretHeaders))
+		for i := range in.SecretHeaders {
+			out.SecretHeaders[i] = in.SecretHeaders[i]
+			in.SecretHeaders[i].SecretKeyRef.DeepCopyInto(&out.SecretHeaders[i].SecretKeyRef)
+		}
+	}
+}
+
+// ContractTestOptions configures the contract tests of a service. GET and HEAD operations are
+// tested, as well as operations flagged with x-redokube-safe: true. Operations flagged with
+// x-redokube-safe: false are never called.
+type ContractTestOptions struct {
+	// Service under test
+	Target ServiceTarget `json:"target"`
+
+	// Path prepended to the test requests, such as "/v1"
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+
+	// Time between two test runs, such as "30m". Defaults to 1h.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Headers added to the test requests
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// HealthCheckOptions configures the probes of a service
+type HealthCheckOptions struct {
+	// Service to probe
+	Target ServiceTarget `json:"target"`
+
+	// Path requested with GET, such as "/healthz". Defaults to "/".
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Status code of a healthy service. Defaults to 200.
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	// +optional
+	ExpectedStatus int32 `json:"expectedStatus,omitempty"`
+
+	// Time between two probes, such as "1m". Defaults to 30s.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// Visibility levels of a spec
+const (
+	// VisibilityPublic specs are meant for external partners
+	VisibilityPublic = "public"
+
+	// VisibilityInternal specs are meant for the developers of the organization
+	VisibilityInternal = "internal"
+
+	// VisibilityPrivate specs are meant for the team owning the API
+	VisibilityPrivate = "private"
+)
+
+// Branding customizes the look of the documentation pages
+type Branding struct {
+	// URL of the logo shown in the header, or an embedded "data:image/..." URI
+	// +optional
+	LogoURL string `json:"logoURL,omitempty"`
+
+	// URL of the favicon, or an embedded "data:image/..." URI
+	// +optional
+	FaviconURL string `json:"faviconURL,omitempty"`
+
+	// Style sheet added to the pages
+	// +optional
+	CSS string `json:"css,omitempty"`
+
+	// HTML shown above the documentation. Scripts, event handlers and other active content are removed.
+	// +optional
+	Header string `json:"header,omitempty"`
+
+	// HTML shown below the documentation, sanitized like the header
+	// +optional
+	Footer string `json:"footer,omitempty"`
+}
+
+// DeepCopyInto copies all properties of these options into other options
+func (in *HealthCheckOptions) DeepCopyInto(out *HealthCheckOptions) {
+	*out = *in
+
+	if in.Interval != nil {
+		out.Interval = new(metav1.Duration)
+		*out.Interval = *in.Interval
+	}
+}
+
+// DeepCopyInto copies all properties of these options into other options
+func (in *ContractTestOptions) DeepCopyInto(out *ContractTestOptions) {
+	*out = *in
+
+	if in.Interval != nil {
+		out.Interval = new(metav1.Duration)
+		*out.Interval = *in.Interval
+	}
+
+	if in.Headers != nil {
+		out.Headers = make(map[string]string)
+		for k, v := range in.Headers {
+			out.Headers[k] = v
+		}
+	}
+}
+
+// DeepCopyInto copies all properties of these options into other options
+func (in *MockOptions) DeepCopyInto(out *MockOptions) {
+	*out = *in
+
+	if in.Seed != nil {
+		out.Seed = new(int64)
+		*out.Seed = *in.Seed
+	}
+
+	if in.Callbacks != nil {
+		out.Callbacks = new(CallbackOptions)
+		in.Callbacks.DeepCopyInto(out.Callbacks)
+	}
+}
+
+// DeepCopyInto copies all properties of these options into other options
+func (in *CallbackOptions) DeepCopyInto(out *CallbackOptions) {
+	*out = *in
+
+	if in.Delay != nil {
+		out.Delay = new(metav1.Duration)
+		*out.Delay = *in.Delay
+	}
+
+	if in.MaxRetries != nil {
+		out.MaxRetries = new(int32)
+		*out.MaxRetries = *in.MaxRetries
+	}
+
+	if in.SecretRef != nil {
+		out.SecretRef = in.SecretRef.DeepCopy()
+	}
+}
+
+// Condition types reported on OpenAPISpec resources
+const (
+	// ConditionMockWarnings is True when fake examples had to be truncated or simplified
+	ConditionMockWarnings = "MockWarnings"
+
+	// ConditionInvalidExamples is True when generated examples do not satisfy their schema
+	ConditionInvalidExamples = "InvalidExamples"
+
+	// ConditionContractViolations is True when the service answered a contract test outside of the spec
+	ConditionContractViolations = "ContractViolations"
+)
+
 // OpenAPISpecStatus defines the observed state of OpenAPISpec
 type OpenAPISpecStatus struct {
 	// Represents the current state of the OpenAPISpec
@@ -46,6 +422,79 @@ type OpenAPISpecStatus struct {
 
 	// Error message in case of failure
 	ErrorMessage string `json:"errorMessage,omitempty"`
+
+	// Latest observations of the documentation state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Outcome of the latest contract test run
+	// +optional
+	ContractTest *ContractTestStatus `json:"contractTest,omitempty"`
+
+	// Overlays applied to the published spec, in order
+	// +optional
+	AppliedOverlays []AppliedOverlay `json:"appliedOverlays,omitempty"`
+}
+
+// AppliedOverlay describes an overlay applied to the spec
+type AppliedOverlay struct {
+	// Origin of the overlay, such as "inline" or "configmap/<name>/<key>"
+	Source string `json:"source"`
+
+	// Title and version of the overlay, from its info object
+	Title   string `json:"title,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	// Number of actions of the overlay
+	Actions int32 `json:"actions"`
+
+	// Targets of the actions that selected nothing in the spec
+	// +optional
+	UnmatchedTargets []string `json:"unmatchedTargets,omitempty"`
+}
+
+// ContractTestStatus is the outcome of a contract test run
+type ContractTestStatus struct {
+	// Time of the run
+	LastRun metav1.Time `json:"lastRun"`
+
+	// Number of operations that matched the spec
+	Passed int32 `json:"passed"`
+
+	// Number of operations that did not match the spec
+	Failed int32 `json:"failed"`
+
+	// Outcome of each tested operation
+	// +optional
+	Operations []OperationResult `json:"operations,omitempty"`
+}
+
+// OperationResult is the outcome of the contract test of an operation
+type OperationResult struct {
+	// Method and path template of the operation, such as "GET /pets/{id}"
+	Operation string `json:"operation"`
+
+	// Whether the response matched the spec
+	Passed bool `json:"passed"`
+
+	// Status code of the response, 0 when the service could not be reached
+	// +optional
+	StatusCode int32 `json:"statusCode,omitempty"`
+
+	// Violations of the spec or transport error
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// DeepCopyInto copies all properties of this status into another status
+func (in *ContractTestStatus) DeepCopyInto(out *ContractTestStatus) {
+	*out = *in
+	in.LastRun.DeepCopyInto(&out.LastRun)
+
+	if in.Operations != nil {
+		out.Operations = make([]OperationResult, len(in.Operations))
+		copy(out.Operations, in.Operations)
+	}
 }
 
 //+kubebuilder:object:root=true
@@ -70,31 +519,91 @@ func (in *OpenAPISpec) DeepCopyInto(out *OpenAPISpec) {
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 
 	// Copy spec
-	out.Spec = OpenAPISpecSpec{
-		Title:       in.Spec.Title,
-		SpecPath:    in.Spec.SpecPath,
-		SpecContent: in.Spec.SpecContent,
-		Description: in.Spec.Description,
-		Version:     in.Spec.Version,
-		Mock:        in.Spec.Mock,
-	}
-
-	if in.Spec.Theme != nil {
-		out.Spec.Theme = make(map[string]string)
-		for k, v := range in.Spec.Theme {
-			out.Spec.Theme[k] = v
+	in.Spec.DeepCopyInto(&out.Spec)
+
+	// Copy status
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopyInto copies all properties of this spec into another spec
+func (in *OpenAPISpecSpec) DeepCopyInto(out *OpenAPISpecSpec) {
+	*out = *in
+
+	if in.MockOptions != nil {
+		out.MockOptions = new(MockOptions)
+		in.MockOptions.DeepCopyInto(out.MockOptions)
+	}
+
+	if in.Overlays != nil {
+		out.Overlays = make([]OverlaySource, len(in.Overlays))
+		for i := range in.Overlays {
+			in.Overlays[i].DeepCopyInto(&out.Overlays[i])
 		}
 	}
 
-	// Copy status
-	out.Status = OpenAPISpecStatus{
-		Status:       in.Status.Status,
-		URL:          in.Status.URL,
-		ErrorMessage: in.Status.ErrorMessage,
+	if in.StripInternal != nil {
+		out.StripInternal = new(StripInternalOptions)
+		*out.StripInternal = *in.StripInternal
+	}
+
+	if in.Recording != nil {
+		out.Recording = new(RecordingOptions)
+		*out.Recording = *in.Recording
 	}
 
-	if !in.Status.LastUpdated.IsZero() {
-		out.Status.LastUpdated = *in.Status.LastUpdated.DeepCopy()
+	if in.TryItOut != nil {
+		out.TryItOut = new(TryItOutOptions)
+		in.TryItOut.DeepCopyInto(out.TryItOut)
+	}
+
+	if in.ContractTest != nil {
+		out.ContractTest = new(ContractTestOptions)
+		in.ContractTest.DeepCopyInto(out.ContractTest)
+	}
+
+	if in.HealthCheck != nil {
+		out.HealthCheck = new(HealthCheckOptions)
+		in.HealthCheck.DeepCopyInto(out.HealthCheck)
+	}
+
+	if in.Branding != nil {
+		out.Branding = new(Branding)
+		*out.Branding = *in.Branding
+	}
+
+	if in.Theme != nil {
+		out.Theme = make(map[string]string)
+		for k, v := range in.Theme {
+			out.Theme[k] = v
+		}
+	}
+}
+
+// DeepCopyInto copies all properties of this status into another status
+func (in *OpenAPISpecStatus) DeepCopyInto(out *OpenAPISpecStatus) {
+	*out = *in
+	in.LastUpdated.DeepCopyInto(&out.LastUpdated)
+
+	if in.Conditions != nil {
+		out.Conditions = make([]metav1.Condition, len(in.Conditions))
+		for i := range in.Conditions {
+			in.Conditions[i].DeepCopyInto(&out.Conditions[i])
+		}
+	}
+
+	if in.ContractTest != nil {
+		out.ContractTest = new(ContractTestStatus)
+		in.ContractTest.DeepCopyInto(out.ContractTest)
+	}
+
+	if in.AppliedOverlays != nil {
+		out.AppliedOverlays = make([]AppliedOverlay, len(in.AppliedOverlays))
+		for i := range in.AppliedOverlays {
+			out.AppliedOverlays[i] = in.AppliedOverlays[i]
+			if in.AppliedOverlays[i].UnmatchedTargets != nil {
+				out.AppliedOverlays[i].UnmatchedTargets = append([]string(nil), in.AppliedOverlays[i].UnmatchedTargets...)
+			}
+		}
 	}
 }
 
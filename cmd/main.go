@@ -2,8 +2,10 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -41,6 +43,23 @@ func main() {
 	var port int
 	var externalURL string
 	var specDirectory string
+	var recordingDirectory string
+	var indexGroupBy string
+	var indexTemplate string
+	var brandingConfig string
+	var authMode string
+	var basicAuthFile string
+	var proxyUserHeader string
+	var proxyGroupsHeader string
+	var proxyTrustedNetworks string
+	var oidcIssuerURL string
+	var oidcClientID string
+	var oidcUsernameClaim string
+	var oidcGroupsClaim string
+	var oidcScopes string
+	var authorizeRBAC bool
+	var callbackAllowedHosts string
+	var portals []redoc.Portal
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -52,6 +71,29 @@ func main() {
 	flag.IntVar(&port, "port", 8080, "The port for the documentation server.")
 	flag.StringVar(&externalURL, "external-url", "", "The external URL for the documentation server.")
 	flag.StringVar(&specDirectory, "spec-directory", "/tmp/redokube-specs", "The directory to store OpenAPI specs.")
+	flag.StringVar(&recordingDirectory, "recording-directory", "", "The directory to persist recorded traffic, kept in memory when empty.")
+	flag.StringVar(&indexGroupBy, "index-group-by", "namespace", "How the index page groups the APIs: namespace or category.")
+	flag.StringVar(&indexTemplate, "index-template", "", "The html/template file rendering the index page, such as one mounted from a ConfigMap.")
+	flag.StringVar(&authMode, "auth-mode", "none", "How the documentation server authenticates its users: none, basic, proxy or oidc.")
+	flag.StringVar(&basicAuthFile, "basic-auth-file", "", "The file of the basic auth users, one name:password[:group,...] per line.")
+	flag.StringVar(&proxyUserHeader, "auth-proxy-user-header", "X-Forwarded-User", "The header holding the user set by the authenticating proxy.")
+	flag.StringVar(&proxyGroupsHeader, "auth-proxy-groups-header", "X-Forwarded-Groups", "The header holding the groups set by the authenticating proxy.")
+	flag.StringVar(&proxyTrustedNetworks, "auth-proxy-trusted-networks", "", "Comma separated networks of the authenticating proxy, such as 10.0.0.0/8. Required with --auth-mode=proxy.")
+	flag.StringVar(&oidcIssuerURL, "oidc-issuer-url", "", "The URL of the OpenID Connect provider.")
+	flag.StringVar(&oidcClientID, "oidc-client-id", "", "The OpenID Connect client ID. The secret is read from the OIDC_CLIENT_SECRET variable.")
+	flag.StringVar(&oidcUsernameClaim, "oidc-username-claim", "sub", "The ID token claim holding the user name.")
+	flag.StringVar(&oidcGroupsClaim, "oidc-groups-claim", "groups", "The ID token claim holding the groups of the user.")
+	flag.StringVar(&oidcScopes, "oidc-scopes", "profile,email", "Comma separated scopes requested in addition to openid.")
+	flag.BoolVar(&authorizeRBAC, "authorize-rbac", false, "Only show the specs of the namespaces where the user may get OpenAPISpecs, only let the users who may create them trigger webhooks and the users who may delete them drop their recordings, checked with SubjectAccessReviews.")
+	flag.Func("portal", "A portal listing only some visibility levels, such as public@:8090 or public+internal@docs.example.com. Can be repeated.", func(value string) error {
+		portal, err := redoc.ParsePortal(value)
+		if err == nil {
+			portals = append(portals, portal)
+		}
+		return err
+	})
+	flag.StringVar(&callbackAllowedHosts, "callback-allowed-hosts", "", "Comma separated hosts and networks allowed to receive mock callbacks and webhooks, such as hooks.example.com or 10.0.0.0/8. Any public address when empty.")
+	flag.StringVar(&brandingConfig, "branding-config", "", "The YAML file of the cluster-wide branding, such as one mounted from a ConfigMap.")
 
 	opts := zap.Options{
 		Development: true,
@@ -87,12 +129,70 @@ func main() {
 		os.Exit(1)
 	}
 
+	var branding *redoc.BrandingConfig
+	if brandingConfig != "" {
+		if branding, err = redoc.LoadBranding(brandingConfig); err != nil {
+			setupLog.Error(err, "Failed to load the branding config")
+			os.Exit(1)
+		}
+	}
+
+	var authenticator redoc.Authenticator
+	switch authMode {
+	case "none":
+	case "basic":
+		authenticator, err = redoc.LoadBasicAuthenticator(basicAuthFile)
+	case "proxy":
+		authenticator, err = redoc.NewProxyAuthenticator(proxyUserHeader, proxyGroupsHeader, splitFlag(proxyTrustedNetworks))
+	case "oidc":
+		// The provider redirects the browsers to the callback, which needs the public address of the server
+		if externalURL == "" {
+			err = fmt.Errorf("--external-url is required with --auth-mode=oidc")
+			break
+		}
+		authenticator, err = redoc.NewOIDCAuthenticator(redoc.OIDCConfig{
+			IssuerURL:     oidcIssuerURL,
+			ClientID:      oidcClientID,
+			ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:   strings.TrimSuffix(externalURL, "/") + "/auth/callback",
+			Scopes:        splitFlag(oidcScopes),
+			UsernameClaim: oidcUsernameClaim,
+			GroupsClaim:   oidcGroupsClaim,
+			// Shared by the replicas so that sessions survive restarts
+			SessionKey: []byte(os.Getenv("SESSION_KEY")),
+		})
+	default:
+		err = fmt.Errorf("unknown auth mode %q", authMode)
+	}
+	if err != nil {
+		setupLog.Error(err, "Failed to set up authentication")
+		os.Exit(1)
+	}
+
+	callbackAllowlist, err := redoc.ParseCallbackAllowlist(splitFlag(callbackAllowedHosts))
+	if err != nil {
+		setupLog.Error(err, "Failed to parse the callback allowlist")
+		os.Exit(1)
+	}
+
 	// Create and configure the Redoc server
-	server := redoc.NewServer(
+	serverOptions := []redoc.ServerOption{
 		redoc.WithPort(port),
 		redoc.WithExternalURL(externalURL),
 		redoc.WithSpecDirectory(specDirectory),
-	)
+		redoc.WithSecretReader(mgr.GetAPIReader()),
+		redoc.WithRecordingDirectory(recordingDirectory),
+		redoc.WithIndexGrouping(indexGroupBy),
+		redoc.WithIndexTemplate(indexTemplate),
+		redoc.WithBranding(branding),
+		redoc.WithAuthenticator(authenticator),
+		redoc.WithPortals(portals...),
+		redoc.WithCallbackAllowlist(callbackAllowlist),
+	}
+	if authorizeRBAC {
+		serverOptions = append(serverOptions, redoc.WithAuthorizer(redoc.NewSubjectAccessReviewAuthorizer(mgr.GetClient())))
+	}
+	server := redoc.NewServer(serverOptions...)
 
 	// Start the server in a separate goroutine
 	go func() {
@@ -141,3 +241,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitFlag splits a comma separated flag value, dropping the empty items
+func splitFlag(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
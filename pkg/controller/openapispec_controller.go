@@ -2,10 +2,16 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -19,11 +25,17 @@ type OpenAPISpecReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Server *redoc.Server
+
+	// Specs whose contract test is running in the background
+	contractTests sync.Map
 }
 
 // +kubebuilder:rbac:groups=docs.redokube.io,resources=openapispecs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=docs.redokube.io,resources=openapispecs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=docs.redokube.io,resources=openapispecs/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
 
 // Reconcile is part of the main kubernetes reconciliation loop
 func (r *OpenAPISpecReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -35,8 +47,9 @@ func (r *OpenAPISpecReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	err := r.Get(ctx, req.NamespacedName, openAPISpec)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			// Request object not found, could have been deleted after reconcile request
-			logger.Info("OpenAPISpec resource not found. Ignoring since object must be deleted")
+			// Request object not found, it was deleted after the reconcile request
+			logger.Info("OpenAPISpec resource not found, unregistering it since it must be deleted")
+			r.Server.UnregisterSpec(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request
@@ -54,10 +67,11 @@ func (r *OpenAPISpecReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 
 	// Process the OpenAPISpec
-	specURL, err := r.Server.RegisterSpec(openAPISpec)
+	registration, err := r.Server.RegisterSpec(openAPISpec)
 	if err != nil {
 		openAPISpec.Status.Status = "Failed"
 		openAPISpec.Status.ErrorMessage = err.Error()
+		openAPISpec.Status.AppliedOverlays = nil
 		if updateErr := r.Status().Update(ctx, openAPISpec); updateErr != nil {
 			logger.Error(updateErr, "Failed to update OpenAPISpec status after error")
 			return ctrl.Result{}, updateErr
@@ -67,16 +81,172 @@ func (r *OpenAPISpecReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	// Update status on success
 	openAPISpec.Status.Status = "Available"
-	openAPISpec.Status.URL = specURL
+	openAPISpec.Status.URL = registration.URL
 	openAPISpec.Status.LastUpdated.Time = time.Now()
 	openAPISpec.Status.ErrorMessage = ""
+	openAPISpec.Status.AppliedOverlays = registration.Overlays
+	setMockWarningsCondition(openAPISpec, registration.MockWarnings)
+	setInvalidExamplesCondition(openAPISpec, registration.InvalidExamples)
+	requeueAfter := r.runContractTest(ctx, openAPISpec, time.Hour)
 
 	if err := r.Status().Update(ctx, openAPISpec); err != nil {
 		logger.Error(err, "Failed to update OpenAPISpec status")
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{RequeueAfter: time.Hour}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// runContractTest starts a contract test in the background when the previous run is older than the interval,
+// and returns when the resource must be reconciled again for the next run. Tests call the service and may
+// take minutes, so they report their result with a status update of their own instead of blocking the reconcile.
+func (r *OpenAPISpecReconciler) runContractTest(ctx context.Context, openAPISpec *docsv1.OpenAPISpec, requeueAfter time.Duration) time.Duration {
+	options := openAPISpec.Spec.ContractTest
+	if options == nil {
+		openAPISpec.Status.ContractTest = nil
+		meta.RemoveStatusCondition(&openAPISpec.Status.Conditions, docsv1.ConditionContractViolations)
+		return requeueAfter
+	}
+
+	interval := redoc.ContractTestInterval(options)
+	next := interval
+	if last := openAPISpec.Status.ContractTest; last == nil || time.Since(last.LastRun.Time) >= interval {
+		r.startContractTest(ctx, openAPISpec.DeepCopy())
+	} else {
+		next = time.Until(last.LastRun.Add(interval))
+	}
+	if openAPISpec.Status.ContractTest != nil {
+		setContractViolationsCondition(openAPISpec)
+	}
+
+	if next < requeueAfter {
+		requeueAfter = next
+	}
+	if requeueAfter <= 0 {
+		requeueAfter = time.Second
+	}
+	return requeueAfter
+}
+
+// startContractTest runs the contract test of a spec in the background, unless one is already running
+func (r *OpenAPISpecReconciler) startContractTest(ctx context.Context, openAPISpec *docsv1.OpenAPISpec) {
+	key := client.ObjectKeyFromObject(openAPISpec)
+	if _, running := r.contractTests.LoadOrStore(key, true); running {
+		return
+	}
+	logger := log.FromContext(ctx)
+	// The test outlives the reconcile that started it
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		defer r.contractTests.Delete(key)
+
+		result, err := r.Server.RunContractTest(ctx, openAPISpec)
+		if err != nil {
+			logger.Error(err, "Failed to run contract test")
+			return
+		}
+		if err := r.reportContractTest(ctx, key, result); err != nil {
+			logger.Error(err, "Failed to report contract test result")
+		}
+	}()
+}
+
+// reportContractTest stores the result of a contract test in the status of the latest version of a spec
+func (r *OpenAPISpecReconciler) reportContractTest(ctx context.Context, key client.ObjectKey, result *docsv1.ContractTestStatus) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		openAPISpec := &docsv1.OpenAPISpec{}
+		if err := r.Get(ctx, key, openAPISpec); err != nil {
+			// Deleted while it was tested
+			return client.IgnoreNotFound(err)
+		}
+		if openAPISpec.Spec.ContractTest == nil {
+			return nil
+		}
+		openAPISpec.Status.ContractTest = result
+		setContractViolationsCondition(openAPISpec)
+		return r.Status().Update(ctx, openAPISpec)
+	})
+}
+
+// setContractViolationsCondition reports the operations that failed the latest contract test
+func setContractViolationsCondition(openAPISpec *docsv1.OpenAPISpec) {
+	result := openAPISpec.Status.ContractTest
+	condition := metav1.Condition{
+		Type:               docsv1.ConditionContractViolations,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: openAPISpec.Generation,
+		Reason:             "ContractRespected",
+		Message:            fmt.Sprintf("All %d tested operations match the spec", result.Passed),
+	}
+
+	var failures []string
+	for _, operation := range result.Operations {
+		if !operation.Passed {
+			failures = append(failures, fmt.Sprintf("%s: %s", operation.Operation, operation.Message))
+		}
+	}
+	if len(failures) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "ContractViolated"
+		condition.Message = summarize(failures)
+	}
+	meta.SetStatusCondition(&openAPISpec.Status.Conditions, condition)
+}
+
+// setMockWarningsCondition reports the warnings raised while generating fake examples
+func setMockWarningsCondition(openAPISpec *docsv1.OpenAPISpec, warnings []string) {
+	if !openAPISpec.Spec.Mock {
+		meta.RemoveStatusCondition(&openAPISpec.Status.Conditions, docsv1.ConditionMockWarnings)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               docsv1.ConditionMockWarnings,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: openAPISpec.Generation,
+		Reason:             "ExamplesGenerated",
+		Message:            "All examples were generated without warnings",
+	}
+	if len(warnings) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "ExamplesTruncated"
+		condition.Message = summarize(warnings)
+	}
+	meta.SetStatusCondition(&openAPISpec.Status.Conditions, condition)
+}
+
+// setInvalidExamplesCondition reports the generated examples that could not be made valid
+func setInvalidExamplesCondition(openAPISpec *docsv1.OpenAPISpec, invalid []string) {
+	if !openAPISpec.Spec.Mock {
+		meta.RemoveStatusCondition(&openAPISpec.Status.Conditions, docsv1.ConditionInvalidExamples)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               docsv1.ConditionInvalidExamples,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: openAPISpec.Generation,
+		Reason:             "ExamplesValid",
+		Message:            "All examples satisfy their schema",
+	}
+	if len(invalid) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "SchemaViolations"
+		condition.Message = summarize(invalid)
+	}
+	meta.SetStatusCondition(&openAPISpec.Status.Conditions, condition)
+}
+
+// maxReportedWarnings bounds the number of warnings copied into a condition message
+const maxReportedWarnings = 10
+
+// summarize joins warnings into a condition message of bounded size
+func summarize(warnings []string) string {
+	if len(warnings) <= maxReportedWarnings {
+		return strings.Join(warnings, "; ")
+	}
+	return fmt.Sprintf("%s; and %d more", strings.Join(warnings[:maxReportedWarnings], "; "), len(warnings)-maxReportedWarnings)
 }
 
 // SetupWithManager sets up the controller with the Manager
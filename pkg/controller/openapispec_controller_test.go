@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+	"github.com/BombartSimon/redokube/pkg/redoc"
+)
+
+// contractSpec has no operation that contract tests may call, so its tests end without any request
+const contractSpec = `
+openapi: 3.0.3
+info: {title: Items, version: "1"}
+paths:
+  /items:
+    post:
+      responses:
+        201:
+          description: created
+`
+
+func newContractTestSpec() *docsv1.OpenAPISpec {
+	spec := &docsv1.OpenAPISpec{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "items", Generation: 2}}
+	spec.Spec.Title = "Items"
+	spec.Spec.SpecContent = contractSpec
+	spec.Spec.ContractTest = &docsv1.ContractTestOptions{
+		Target:   docsv1.ServiceTarget{ServiceName: "items", Port: 8080},
+		Interval: &metav1.Duration{Duration: 30 * time.Minute},
+	}
+	return spec
+}
+
+func newTestReconciler(t *testing.T, objects ...client.Object) *OpenAPISpecReconciler {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := docsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(&docsv1.OpenAPISpec{}).
+		Build()
+	return &OpenAPISpecReconciler{
+		Client: c,
+		Scheme: scheme,
+		Server: redoc.NewServer(redoc.WithSpecDirectory(t.TempDir())),
+	}
+}
+
+func TestSetContractViolationsCondition(t *testing.T) {
+	tests := []struct {
+		name        string
+		result      docsv1.ContractTestStatus
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name: "passed",
+			result: docsv1.ContractTestStatus{Passed: 2, Operations: []docsv1.OperationResult{
+				{Operation: "GET /items", Passed: true},
+				{Operation: "GET /items/{id}", Passed: true},
+			}},
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  "ContractRespected",
+			wantMessage: "All 2 tested operations match the spec",
+		},
+		{
+			name: "violated",
+			result: docsv1.ContractTestStatus{Passed: 1, Failed: 1, Operations: []docsv1.OperationResult{
+				{Operation: "GET /items", Passed: true},
+				{Operation: "GET /items/{id}", Message: "status code 418 is not declared"},
+			}},
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  "ContractViolated",
+			wantMessage: "GET /items/{id}: status code 418 is not declared",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newContractTestSpec()
+			spec.Status.ContractTest = &tt.result
+			setContractViolationsCondition(spec)
+
+			condition := meta.FindStatusCondition(spec.Status.Conditions, docsv1.ConditionContractViolations)
+			if condition == nil {
+				t.Fatal("no contract violations condition")
+			}
+			if condition.Status != tt.wantStatus || condition.Reason != tt.wantReason || !strings.Contains(condition.Message, tt.wantMessage) {
+				t.Errorf("condition = %+v, want %s %s %q", condition, tt.wantStatus, tt.wantReason, tt.wantMessage)
+			}
+			if condition.ObservedGeneration != 2 {
+				t.Errorf("observed generation = %d, want 2", condition.ObservedGeneration)
+			}
+		})
+	}
+}
+
+func TestRunContractTestInBackground(t *testing.T) {
+	spec := newContractTestSpec()
+	r := newTestReconciler(t, spec)
+	if _, err := r.Server.RegisterSpec(spec); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stale result starts a test and the next run is due after the interval
+	if requeueAfter := r.runContractTest(context.Background(), spec, time.Hour); requeueAfter != 30*time.Minute {
+		t.Errorf("requeue after %v, want 30m", requeueAfter)
+	}
+
+	key := client.ObjectKeyFromObject(spec)
+	deadline := time.Now().Add(5 * time.Second)
+	var updated docsv1.OpenAPISpec
+	for {
+		if err := r.Get(context.Background(), key, &updated); err != nil {
+			t.Fatal(err)
+		}
+		if updated.Status.ContractTest != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("contract test result never reported")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if meta.FindStatusCondition(updated.Status.Conditions, docsv1.ConditionContractViolations) == nil {
+		t.Errorf("conditions = %+v, want the contract violations condition", updated.Status.Conditions)
+	}
+
+	for _, running := r.contractTests.Load(key); running; _, running = r.contractTests.Load(key) {
+		if time.Now().After(deadline) {
+			t.Fatal("contract test never ended")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A recent result starts no test and waits for the rest of the interval
+	spec.Status.ContractTest = &docsv1.ContractTestStatus{LastRun: metav1.NewTime(time.Now().Add(-20 * time.Minute))}
+	requeueAfter := r.runContractTest(context.Background(), spec, time.Hour)
+	if requeueAfter <= 9*time.Minute || requeueAfter > 10*time.Minute {
+		t.Errorf("requeue after %v, want about 10m", requeueAfter)
+	}
+	if _, running := r.contractTests.Load(key); running {
+		t.Error("contract test started before the end of the interval")
+	}
+}
+
+func TestReportContractTestOfDeletedSpec(t *testing.T) {
+	r := newTestReconciler(t)
+	key := client.ObjectKey{Namespace: "ns", Name: "deleted"}
+	if err := r.reportContractTest(context.Background(), key, &docsv1.ContractTestStatus{}); err != nil {
+		t.Errorf("error = %v, want the result of a deleted spec dropped", err)
+	}
+}
@@ -0,0 +1,735 @@
+// Package converter upgrades Swagger 2.0 documents to OpenAPI 3.0 and
+// OpenAPI 3.0 documents to OpenAPI 3.1.
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// Supported upgrade targets
+const (
+	Target30 = "3.0"
+	Target31 = "3.1"
+)
+
+const (
+	openAPI30Version = "3.0.3"
+	openAPI31Version = "3.1.0"
+	defaultMediaType = "application/json"
+)
+
+// Upgrade converts a document to the requested OpenAPI version.
+// Documents already at or above the target version are returned unchanged.
+func Upgrade(doc openapi.Document, target string) (openapi.Document, error) {
+	switch target {
+	case Target30, Target31:
+	default:
+		return nil, fmt.Errorf("unsupported upgrade target %q (expected %q or %q)", target, Target30, Target31)
+	}
+
+	if openapi.IsSwagger2(doc) {
+		converted, err := ConvertSwagger2(doc)
+		if err != nil {
+			return nil, err
+		}
+		doc = converted
+	}
+
+	version := openapi.Version(doc)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", version)
+	}
+
+	if target == Target31 && strings.HasPrefix(version, "3.0") {
+		return UpgradeTo31(doc), nil
+	}
+	return doc, nil
+}
+
+// ConvertSwagger2 converts a Swagger 2.0 document to OpenAPI 3.0
+func ConvertSwagger2(swagger openapi.Document) (openapi.Document, error) {
+	if !openapi.IsSwagger2(swagger) {
+		return nil, fmt.Errorf("document is not a Swagger 2.0 specification (version %q)", openapi.Version(swagger))
+	}
+
+	c := &swagger2Converter{
+		source:         swagger,
+		bodyParameters: make(map[string]bool),
+		formParameters: make(map[string]map[string]interface{}),
+		consumes:       stringList(swagger["consumes"]),
+		produces:       stringList(swagger["produces"]),
+	}
+	return c.convert(), nil
+}
+
+type swagger2Converter struct {
+	source openapi.Document
+
+	// Global parameters that cannot stay in components/parameters
+	bodyParameters map[string]bool
+	formParameters map[string]map[string]interface{}
+
+	consumes []string
+	produces []string
+}
+
+func (c *swagger2Converter) convert() openapi.Document {
+	out := openapi.Document{"openapi": openAPI30Version}
+
+	// Keep top-level fields that have the same meaning in both versions
+	for _, key := range []string{"info", "tags", "externalDocs", "security"} {
+		if v, ok := c.source[key]; ok {
+			out[key] = v
+		}
+	}
+	copyExtensions(c.source, out)
+
+	if servers := c.convertServers(); len(servers) > 0 {
+		out["servers"] = servers
+	}
+
+	components := make(map[string]interface{})
+
+	if defs, ok := c.source["definitions"].(map[string]interface{}); ok {
+		schemas := make(map[string]interface{}, len(defs))
+		for name, def := range defs {
+			schemas[name] = convertSchema(def)
+		}
+		components["schemas"] = schemas
+	}
+
+	if params, ok := c.source["parameters"].(map[string]interface{}); ok {
+		parameters := make(map[string]interface{})
+		requestBodies := make(map[string]interface{})
+		for name, raw := range params {
+			param, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			switch param["in"] {
+			case "body":
+				c.bodyParameters[name] = true
+				requestBodies[name] = c.bodyToRequestBody(param, c.consumes)
+			case "formData":
+				c.formParameters[name] = param
+			default:
+				parameters[name] = convertParameter(param)
+			}
+		}
+		if len(parameters) > 0 {
+			components["parameters"] = parameters
+		}
+		if len(requestBodies) > 0 {
+			components["requestBodies"] = requestBodies
+		}
+	}
+
+	if resps, ok := c.source["responses"].(map[string]interface{}); ok {
+		responses := make(map[string]interface{}, len(resps))
+		for name, raw := range resps {
+			if resp, ok := raw.(map[string]interface{}); ok {
+				responses[name] = c.convertResponse(resp, c.produces)
+			}
+		}
+		components["responses"] = responses
+	}
+
+	if defs, ok := c.source["securityDefinitions"].(map[string]interface{}); ok {
+		schemes := make(map[string]interface{}, len(defs))
+		for name, raw := range defs {
+			if def, ok := raw.(map[string]interface{}); ok {
+				schemes[name] = convertSecurityScheme(def)
+			}
+		}
+		components["securitySchemes"] = schemes
+	}
+
+	if len(components) > 0 {
+		out["components"] = components
+	}
+
+	paths := make(map[string]interface{})
+	if rawPaths, ok := c.source["paths"].(map[string]interface{}); ok {
+		for pathKey, raw := range rawPaths {
+			if strings.HasPrefix(pathKey, "x-") {
+				paths[pathKey] = raw
+				continue
+			}
+			if item, ok := raw.(map[string]interface{}); ok {
+				paths[pathKey] = c.convertPathItem(item)
+			}
+		}
+	}
+	out["paths"] = paths
+
+	return rewriteRefs(out, c.bodyParameters).(map[string]interface{})
+}
+
+// convertServers builds the server list from host, basePath and schemes
+func (c *swagger2Converter) convertServers() []interface{} {
+	host, _ := c.source["host"].(string)
+	basePath, _ := c.source["basePath"].(string)
+	if host == "" && basePath == "" {
+		return nil
+	}
+	if basePath == "" {
+		basePath = "/"
+	}
+	if host == "" {
+		return []interface{}{map[string]interface{}{"url": basePath}}
+	}
+
+	schemes := stringList(c.source["schemes"])
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+
+	servers := make([]interface{}, 0, len(schemes))
+	for _, scheme := range schemes {
+		servers = append(servers, map[string]interface{}{
+			"url": fmt.Sprintf("%s://%s%s", scheme, host, basePath),
+		})
+	}
+	return servers
+}
+
+func (c *swagger2Converter) convertPathItem(item map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+
+	// Path-level parameters apply to every operation of the item
+	var shared []interface{}
+	if params, ok := item["parameters"].([]interface{}); ok {
+		shared = params
+	}
+
+	for key, raw := range item {
+		switch {
+		case key == "parameters":
+			// Handled per operation so that body and form parameters become request bodies
+		case key == "$ref":
+			out[key] = raw
+		case openapi.IsMethod(key):
+			if op, ok := raw.(map[string]interface{}); ok {
+				out[key] = c.convertOperation(op, shared)
+			}
+		default:
+			out[key] = raw
+		}
+	}
+
+	// Keep non-body path-level parameters on the path item itself
+	var pathParams []interface{}
+	for _, raw := range shared {
+		param := c.lookupParameter(raw)
+		if param == nil {
+			pathParams = append(pathParams, raw)
+			continue
+		}
+		if in := param["in"]; in != "body" && in != "formData" {
+			pathParams = append(pathParams, c.parameterOrRef(raw, param))
+		}
+	}
+	if len(pathParams) > 0 {
+		out["parameters"] = pathParams
+	}
+
+	return out
+}
+
+func (c *swagger2Converter) convertOperation(op map[string]interface{}, shared []interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+
+	consumes := c.consumes
+	if v, ok := op["consumes"]; ok {
+		consumes = stringList(v)
+	}
+	produces := c.produces
+	if v, ok := op["produces"]; ok {
+		produces = stringList(v)
+	}
+
+	for key, v := range op {
+		switch key {
+		case "consumes", "produces", "parameters", "responses", "schemes":
+		default:
+			out[key] = v
+		}
+	}
+
+	var parameters []interface{}
+	var formParams []map[string]interface{}
+	var requestBody map[string]interface{}
+
+	opParams, _ := op["parameters"].([]interface{})
+	opHasBody := false
+	for _, raw := range opParams {
+		if param := c.lookupParameter(raw); param != nil && (param["in"] == "body" || param["in"] == "formData") {
+			opHasBody = true
+			break
+		}
+	}
+
+	// Operation parameters come first, path-level ones are inherited when not overridden
+	all := append([]interface{}{}, opParams...)
+	for _, raw := range shared {
+		param := c.lookupParameter(raw)
+		if param == nil {
+			continue
+		}
+		if param["in"] == "body" || param["in"] == "formData" {
+			if !opHasBody {
+				all = append(all, raw)
+			}
+		}
+	}
+
+	for _, raw := range all {
+		param := c.lookupParameter(raw)
+		if param == nil {
+			parameters = append(parameters, raw)
+			continue
+		}
+
+		switch param["in"] {
+		case "body":
+			if ref, ok := raw.(map[string]interface{})["$ref"].(string); ok && c.bodyParameters[openapi.RefName(ref)] {
+				requestBody = map[string]interface{}{"$ref": ref}
+			} else {
+				requestBody = c.bodyToRequestBody(param, consumes)
+			}
+		case "formData":
+			formParams = append(formParams, param)
+		default:
+			parameters = append(parameters, c.parameterOrRef(raw, param))
+		}
+	}
+
+	if len(formParams) > 0 {
+		requestBody = formToRequestBody(formParams, consumes)
+	}
+	if len(parameters) > 0 {
+		out["parameters"] = parameters
+	}
+	if requestBody != nil {
+		out["requestBody"] = requestBody
+	}
+
+	responses := make(map[string]interface{})
+	if resps, ok := op["responses"].(map[string]interface{}); ok {
+		for code, raw := range resps {
+			resp, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, isRef := resp["$ref"]; isRef || strings.HasPrefix(code, "x-") {
+				responses[code] = resp
+				continue
+			}
+			responses[code] = c.convertResponse(resp, produces)
+		}
+	}
+	out["responses"] = responses
+
+	return out
+}
+
+// lookupParameter returns the parameter definition, following global references
+func (c *swagger2Converter) lookupParameter(raw interface{}) map[string]interface{} {
+	param, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	ref, ok := param["$ref"].(string)
+	if !ok {
+		return param
+	}
+	if !strings.HasPrefix(ref, "#/parameters/") {
+		return nil
+	}
+	name := openapi.RefName(ref)
+	if form, ok := c.formParameters[name]; ok {
+		return form
+	}
+	if global, ok := c.source["parameters"].(map[string]interface{}); ok {
+		if def, ok := global[name].(map[string]interface{}); ok {
+			return def
+		}
+	}
+	return nil
+}
+
+// parameterOrRef keeps references to global parameters and converts inline ones
+func (c *swagger2Converter) parameterOrRef(raw interface{}, param map[string]interface{}) interface{} {
+	if m, ok := raw.(map[string]interface{}); ok {
+		if _, isRef := m["$ref"]; isRef {
+			return m
+		}
+	}
+	return convertParameter(param)
+}
+
+func (c *swagger2Converter) bodyToRequestBody(param map[string]interface{}, consumes []string) map[string]interface{} {
+	if len(consumes) == 0 {
+		consumes = []string{defaultMediaType}
+	}
+
+	schema := convertSchema(param["schema"])
+	content := make(map[string]interface{}, len(consumes))
+	for _, mediaType := range consumes {
+		mt := map[string]interface{}{"schema": schema}
+		if example, ok := param["x-example"]; ok {
+			mt["example"] = example
+		}
+		content[mediaType] = mt
+	}
+
+	body := map[string]interface{}{"content": content}
+	if desc, ok := param["description"]; ok {
+		body["description"] = desc
+	}
+	if required, ok := param["required"].(bool); ok && required {
+		body["required"] = true
+	}
+	if name, ok := param["name"].(string); ok && name != "" {
+		body["x-codegen-request-body-name"] = name
+	}
+	copyExtensions(param, body)
+	return body
+}
+
+func formToRequestBody(params []map[string]interface{}, consumes []string) map[string]interface{} {
+	properties := make(map[string]interface{}, len(params))
+	var required []interface{}
+	hasFile := false
+
+	for _, param := range params {
+		name, _ := param["name"].(string)
+		if param["type"] == "file" {
+			hasFile = true
+		}
+		prop := parameterSchema(param)
+		if desc, ok := param["description"]; ok {
+			prop["description"] = desc
+		}
+		properties[name] = prop
+		if req, ok := param["required"].(bool); ok && req {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	var mediaTypes []string
+	for _, mediaType := range consumes {
+		if mediaType == "multipart/form-data" || mediaType == "application/x-www-form-urlencoded" {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+	}
+	if len(mediaTypes) == 0 {
+		if hasFile {
+			mediaTypes = []string{"multipart/form-data"}
+		} else {
+			mediaTypes = []string{"application/x-www-form-urlencoded"}
+		}
+	}
+
+	content := make(map[string]interface{}, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		content[mediaType] = map[string]interface{}{"schema": schema}
+	}
+	return map[string]interface{}{"content": content}
+}
+
+func (c *swagger2Converter) convertResponse(resp map[string]interface{}, produces []string) map[string]interface{} {
+	out := make(map[string]interface{})
+
+	desc, ok := resp["description"]
+	if !ok {
+		desc = ""
+	}
+	out["description"] = desc
+
+	examples, _ := resp["examples"].(map[string]interface{})
+
+	if schema, ok := resp["schema"]; ok {
+		mediaTypes := produces
+		if len(mediaTypes) == 0 {
+			mediaTypes = []string{defaultMediaType}
+		}
+		converted := convertSchema(schema)
+		content := make(map[string]interface{}, len(mediaTypes))
+		for _, mediaType := range mediaTypes {
+			mt := map[string]interface{}{"schema": converted}
+			if example, ok := examples[mediaType]; ok {
+				mt["example"] = example
+			}
+			content[mediaType] = mt
+		}
+		out["content"] = content
+	} else if len(examples) > 0 {
+		content := make(map[string]interface{}, len(examples))
+		for mediaType, example := range examples {
+			content[mediaType] = map[string]interface{}{"example": example}
+		}
+		out["content"] = content
+	}
+
+	if headers, ok := resp["headers"].(map[string]interface{}); ok {
+		converted := make(map[string]interface{}, len(headers))
+		for name, raw := range headers {
+			header, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			h := map[string]interface{}{"schema": parameterSchema(header)}
+			if desc, ok := header["description"]; ok {
+				h["description"] = desc
+			}
+			copyExtensions(header, h)
+			converted[name] = h
+		}
+		out["headers"] = converted
+	}
+
+	copyExtensions(resp, out)
+	return out
+}
+
+func convertParameter(param map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, key := range []string{"name", "in", "description", "required", "allowEmptyValue"} {
+		if v, ok := param[key]; ok {
+			out[key] = v
+		}
+	}
+	copyExtensions(param, out)
+
+	if example, ok := param["x-example"]; ok {
+		out["example"] = example
+		delete(out, "x-example")
+	}
+
+	out["schema"] = parameterSchema(param)
+
+	// Translate Swagger collection formats into style and explode
+	if param["type"] == "array" {
+		switch param["collectionFormat"] {
+		case "multi":
+			out["style"] = "form"
+			out["explode"] = true
+		case "ssv":
+			out["style"] = "spaceDelimited"
+			out["explode"] = false
+		case "pipes":
+			out["style"] = "pipeDelimited"
+			out["explode"] = false
+		case "csv", nil:
+			if param["in"] == "query" || param["in"] == "cookie" {
+				out["style"] = "form"
+				out["explode"] = false
+			} else {
+				out["style"] = "simple"
+			}
+		}
+	}
+
+	return out
+}
+
+// schemaKeywords lists the keywords shared by Swagger parameters, headers, items and schemas
+var schemaKeywords = []string{
+	"type", "format", "items", "default", "maximum", "exclusiveMaximum", "minimum",
+	"exclusiveMinimum", "maxLength", "minLength", "pattern", "maxItems", "minItems",
+	"uniqueItems", "enum", "multipleOf",
+}
+
+// parameterSchema builds a schema from the inline type keywords of a non-body parameter
+func parameterSchema(param map[string]interface{}) map[string]interface{} {
+	schema := make(map[string]interface{})
+	for _, key := range schemaKeywords {
+		v, ok := param[key]
+		if !ok {
+			continue
+		}
+		if key == "items" {
+			if items, ok := v.(map[string]interface{}); ok {
+				v = parameterSchema(items)
+			}
+		}
+		schema[key] = v
+	}
+
+	if schema["type"] == "file" {
+		schema["type"] = "string"
+		schema["format"] = "binary"
+	}
+	return schema
+}
+
+// convertSchema rewrites the Swagger-specific keywords of a schema and its subschemas
+func convertSchema(raw interface{}) interface{} {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			switch key {
+			case "x-nullable":
+				out["nullable"] = value
+			case "discriminator":
+				if name, ok := value.(string); ok {
+					out["discriminator"] = map[string]interface{}{"propertyName": name}
+				} else {
+					out[key] = value
+				}
+			case "properties", "definitions":
+				if props, ok := value.(map[string]interface{}); ok {
+					converted := make(map[string]interface{}, len(props))
+					for name, prop := range props {
+						converted[name] = convertSchema(prop)
+					}
+					out[key] = converted
+				} else {
+					out[key] = value
+				}
+			case "items", "additionalProperties", "not":
+				out[key] = convertSchema(value)
+			case "allOf", "anyOf", "oneOf":
+				if list, ok := value.([]interface{}); ok {
+					converted := make([]interface{}, len(list))
+					for i, item := range list {
+						converted[i] = convertSchema(item)
+					}
+					out[key] = converted
+				} else {
+					out[key] = value
+				}
+			default:
+				out[key] = value
+			}
+		}
+		if out["type"] == "file" {
+			out["type"] = "string"
+			out["format"] = "binary"
+		}
+		return out
+	default:
+		return raw
+	}
+}
+
+func convertSecurityScheme(def map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	if desc, ok := def["description"]; ok {
+		out["description"] = desc
+	}
+	copyExtensions(def, out)
+
+	switch def["type"] {
+	case "basic":
+		out["type"] = "http"
+		out["scheme"] = "basic"
+	case "apiKey":
+		out["type"] = "apiKey"
+		out["name"] = def["name"]
+		out["in"] = def["in"]
+	case "oauth2":
+		out["type"] = "oauth2"
+		flow := make(map[string]interface{})
+		if url, ok := def["authorizationUrl"]; ok {
+			flow["authorizationUrl"] = url
+		}
+		if url, ok := def["tokenUrl"]; ok {
+			flow["tokenUrl"] = url
+		}
+		scopes, ok := def["scopes"].(map[string]interface{})
+		if !ok {
+			scopes = map[string]interface{}{}
+		}
+		flow["scopes"] = scopes
+
+		flowName := "implicit"
+		switch def["flow"] {
+		case "password":
+			flowName = "password"
+		case "application":
+			flowName = "clientCredentials"
+		case "accessCode":
+			flowName = "authorizationCode"
+		}
+		out["flows"] = map[string]interface{}{flowName: flow}
+	default:
+		for k, v := range def {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// rewriteRefs points every Swagger 2.0 reference at its OpenAPI 3.0 location
+func rewriteRefs(node interface{}, bodyParameters map[string]bool) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				v[key] = convertRef(ref, bodyParameters)
+				continue
+			}
+			v[key] = rewriteRefs(value, bodyParameters)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = rewriteRefs(item, bodyParameters)
+		}
+		return v
+	default:
+		return node
+	}
+}
+
+func convertRef(ref string, bodyParameters map[string]bool) string {
+	switch {
+	case strings.HasPrefix(ref, "#/definitions/"):
+		return "#/components/schemas/" + strings.TrimPrefix(ref, "#/definitions/")
+	case strings.HasPrefix(ref, "#/parameters/"):
+		name := strings.TrimPrefix(ref, "#/parameters/")
+		if bodyParameters[openapi.RefName(ref)] {
+			return "#/components/requestBodies/" + name
+		}
+		return "#/components/parameters/" + name
+	case strings.HasPrefix(ref, "#/responses/"):
+		return "#/components/responses/" + strings.TrimPrefix(ref, "#/responses/")
+	}
+	return ref
+}
+
+func copyExtensions(from, to map[string]interface{}) {
+	for k, v := range from {
+		if strings.HasPrefix(k, "x-") {
+			to[k] = v
+		}
+	}
+}
+
+func stringList(raw interface{}) []string {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package converter
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// at returns the value of a document at a JSON pointer such as "/paths/~1pets/get", nil when it is missing
+func at(doc interface{}, pointer string) interface{} {
+	current := doc
+	for _, part := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[part]
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i >= len(node) {
+				return nil
+			}
+			current = node[i]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+func loadFixture(t *testing.T, name string) openapi.Document {
+	t.Helper()
+	content, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi.Parse(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestConvertSwagger2(t *testing.T) {
+	doc, err := ConvertSwagger2(loadFixture(t, "petstore-swagger2.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		pointer string
+		want    interface{}
+	}{
+		// Top level
+		{"version", "/openapi", "3.0.3"},
+		{"servers", "/servers/0/url", "https://petstore.example.com/v1"},
+		{"swagger fields dropped", "/definitions", nil},
+
+		// definitions → components/schemas
+		{"schema moved", "/components/schemas/Owner/properties/name/type", "string"},
+		{"x-nullable", "/components/schemas/Pet/properties/tag/nullable", true},
+		{"nested ref", "/components/schemas/Pet/properties/owner/$ref", "#/components/schemas/Owner"},
+
+		// Unquoted YAML status codes
+		{"numeric response kept", "/paths/~1pets/get/responses/200/description", "A list of pets"},
+		{"default response kept", "/paths/~1pets/get/responses/default/description", "Error"},
+		{"created response kept", "/paths/~1pets/post/responses/201/description", "Created"},
+		{"response ref", "/paths/~1pets~1{petId}/get/responses/404/$ref", "#/components/responses/NotFound"},
+
+		// produces → content
+		{"global produces json", "/paths/~1pets/get/responses/200/content/application~1json/schema/items/$ref", "#/components/schemas/Pet"},
+		{"global produces xml", "/paths/~1pets/get/responses/200/content/application~1xml/schema/type", "array"},
+		{"operation produces", "/paths/~1pets~1{petId}/get/responses/200/content/application~1xml", nil},
+		{"response header", "/paths/~1pets/get/responses/200/headers/X-Total/schema/type", "integer"},
+		{"component response", "/components/responses/NotFound/content/application~1json/schema/$ref", "#/components/schemas/Error"},
+
+		// body → requestBody, consumes → content
+		{"body parameter ref", "/paths/~1pets/post/requestBody/$ref", "#/components/requestBodies/petBody"},
+		{"body parameter removed", "/paths/~1pets/post/parameters", nil},
+		{"request body schema", "/components/requestBodies/petBody/content/application~1json/schema/$ref", "#/components/schemas/Pet"},
+		{"request body required", "/components/requestBodies/petBody/required", true},
+
+		// formData → requestBody
+		{"form file", "/paths/~1pets~1{petId}~1photo/post/requestBody/content/multipart~1form-data/schema/properties/file/format", "binary"},
+		{"form required", "/paths/~1pets~1{petId}~1photo/post/requestBody/content/multipart~1form-data/schema/required", []interface{}{"file"}},
+
+		// Parameters
+		{"query parameter schema", "/paths/~1pets/get/parameters/0/schema/maximum", 100},
+		{"path parameter ref", "/paths/~1pets~1{petId}/parameters/0/$ref", "#/components/parameters/petId"},
+		{"component parameter schema", "/components/parameters/petId/schema/format", "int64"},
+
+		// securityDefinitions → components/securitySchemes
+		{"basic", "/components/securitySchemes/basicAuth/scheme", "basic"},
+		{"api key", "/components/securitySchemes/apiKey/in", "header"},
+		{"oauth2 flow", "/components/securitySchemes/oauth/flows/authorizationCode/tokenUrl", "https://auth.example.com/token"},
+		{"oauth2 scopes", "/components/securitySchemes/oauth/flows/authorizationCode/scopes/read:pets", "Read pets"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := at(doc, tt.pointer); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.pointer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertSwagger2RewritesRefs(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"#/definitions/Pet", "#/components/schemas/Pet"},
+		{"#/parameters/petId", "#/components/parameters/petId"},
+		{"#/parameters/petBody", "#/components/requestBodies/petBody"},
+		{"#/responses/NotFound", "#/components/responses/NotFound"},
+		{"other.yaml#/definitions/Pet", "other.yaml#/definitions/Pet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			if got := convertRef(tt.ref, map[string]bool{"petBody": true}); got != tt.want {
+				t.Errorf("convertRef(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertSwagger2RejectsOpenAPI3(t *testing.T) {
+	if _, err := ConvertSwagger2(openapi.Document{"openapi": "3.0.3"}); err == nil {
+		t.Error("expected an error for an OpenAPI 3 document")
+	}
+}
@@ -0,0 +1,185 @@
+package converter
+
+import (
+	"slices"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// UpgradeTo31 converts an OpenAPI 3.0 document to OpenAPI 3.1.
+// Schemas are rewritten in place to follow JSON Schema 2020-12:
+// nullable becomes a "null" type and boolean exclusive bounds become numeric.
+// Only schema positions are rewritten, so examples and extensions are left alone.
+func UpgradeTo31(doc openapi.Document) openapi.Document {
+	doc["openapi"] = openAPI31Version
+
+	if paths, ok := doc["paths"].(map[string]interface{}); ok {
+		for _, item := range paths {
+			upgradePathItem(item)
+		}
+	}
+
+	components, _ := doc["components"].(map[string]interface{})
+	for section, walk := range map[string]func(interface{}){
+		"schemas":       upgradeSchema,
+		"parameters":    upgradeParameter,
+		"headers":       upgradeParameter,
+		"requestBodies": upgradeContainer,
+		"responses":     upgradeResponse,
+		"callbacks":     upgradeCallback,
+	} {
+		if named, ok := components[section].(map[string]interface{}); ok {
+			for _, component := range named {
+				walk(component)
+			}
+		}
+	}
+	return doc
+}
+
+// upgradePathItem upgrades the schemas of the parameters and operations of a path item
+func upgradePathItem(raw interface{}) {
+	item, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+	upgradeParameters(item["parameters"])
+	for method, raw := range item {
+		operation, ok := raw.(map[string]interface{})
+		if !ok || !openapi.IsMethod(method) {
+			continue
+		}
+		upgradeParameters(operation["parameters"])
+		upgradeContainer(operation["requestBody"])
+		if responses, ok := operation["responses"].(map[string]interface{}); ok {
+			for _, response := range responses {
+				upgradeResponse(response)
+			}
+		}
+		if callbacks, ok := operation["callbacks"].(map[string]interface{}); ok {
+			for _, callback := range callbacks {
+				upgradeCallback(callback)
+			}
+		}
+	}
+}
+
+// upgradeCallback upgrades the path items of a callback, keyed by runtime expressions
+func upgradeCallback(raw interface{}) {
+	if callback, ok := raw.(map[string]interface{}); ok {
+		for _, item := range callback {
+			upgradePathItem(item)
+		}
+	}
+}
+
+func upgradeParameters(raw interface{}) {
+	if parameters, ok := raw.([]interface{}); ok {
+		for _, parameter := range parameters {
+			upgradeParameter(parameter)
+		}
+	}
+}
+
+// upgradeParameter upgrades the schema of a parameter or header
+func upgradeParameter(raw interface{}) {
+	if parameter, ok := raw.(map[string]interface{}); ok {
+		upgradeSchema(parameter["schema"])
+		upgradeContainer(parameter)
+	}
+}
+
+// upgradeResponse upgrades the schemas of the content and headers of a response
+func upgradeResponse(raw interface{}) {
+	response, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+	upgradeContainer(response)
+	if headers, ok := response["headers"].(map[string]interface{}); ok {
+		for _, header := range headers {
+			upgradeParameter(header)
+		}
+	}
+}
+
+// upgradeContainer upgrades the schemas of the media types of an object with content
+func upgradeContainer(raw interface{}) {
+	container, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if content, ok := container["content"].(map[string]interface{}); ok {
+		for _, raw := range content {
+			if mediaType, ok := raw.(map[string]interface{}); ok {
+				upgradeSchema(mediaType["schema"])
+			}
+		}
+	}
+}
+
+// upgradeSchema rewrites a schema and its subschemas
+func upgradeSchema(raw interface{}) {
+	schema, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+	upgradeSchemaKeywords(schema)
+
+	for _, keyword := range []string{"properties", "patternProperties"} {
+		if properties, ok := schema[keyword].(map[string]interface{}); ok {
+			for _, property := range properties {
+				upgradeSchema(property)
+			}
+		}
+	}
+	for _, keyword := range []string{"items", "additionalProperties", "not"} {
+		upgradeSchema(schema[keyword])
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		if members, ok := schema[keyword].([]interface{}); ok {
+			for _, member := range members {
+				upgradeSchema(member)
+			}
+		}
+	}
+}
+
+// upgradeSchemaKeywords only touches keywords whose value has the 3.0 shape,
+// so properties or parameters that happen to share their names are left alone.
+func upgradeSchemaKeywords(schema map[string]interface{}) {
+	if nullable, ok := schema["nullable"].(bool); ok {
+		delete(schema, "nullable")
+		if nullable {
+			if t, ok := schema["type"].(string); ok {
+				schema["type"] = []interface{}{t, "null"}
+				if enum, ok := schema["enum"].([]interface{}); ok && !slices.Contains(enum, nil) {
+					schema["enum"] = append(enum, nil)
+				}
+			} else {
+				// References, compositions and untyped schemas carry their type elsewhere
+				inner := make(map[string]interface{}, len(schema))
+				for k, v := range schema {
+					inner[k] = v
+					delete(schema, k)
+				}
+				schema["anyOf"] = []interface{}{inner, map[string]interface{}{"type": "null"}}
+			}
+		}
+	}
+
+	for exclusive, bound := range map[string]string{
+		"exclusiveMinimum": "minimum",
+		"exclusiveMaximum": "maximum",
+	} {
+		flag, ok := schema[exclusive].(bool)
+		if !ok {
+			continue
+		}
+		delete(schema, exclusive)
+		if value, ok := schema[bound]; ok && flag {
+			schema[exclusive] = value
+			delete(schema, bound)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+const upgradeFixture = `
+openapi: 3.0.3
+info: {title: Pets, version: "1"}
+x-settings:
+  nullable: true
+  exclusiveMinimum: true
+  minimum: 1
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          schema: {type: integer, minimum: 0, exclusiveMinimum: true}
+      responses:
+        200:
+          description: ok
+          headers:
+            X-Rate:
+              schema: {type: number, nullable: true}
+          content:
+            application/json:
+              schema:
+                type: array
+                items: {$ref: "#/components/schemas/Pet"}
+              example:
+                - nullable: true
+                  exclusiveMaximum: true
+components:
+  schemas:
+    Pet:
+      type: object
+      example: {nullable: true, exclusiveMinimum: true, minimum: 2}
+      properties:
+        nullable:
+          type: boolean
+        tag:
+          type: string
+          nullable: true
+          enum: [a, b]
+        owner:
+          $ref: "#/components/schemas/Owner"
+          nullable: true
+        anything:
+          description: Any value
+          nullable: true
+        score:
+          type: number
+          maximum: 10
+          exclusiveMaximum: true
+          x-meta: {nullable: true}
+    Owner:
+      type: object
+      nullable: false
+`
+
+func TestUpgradeTo31(t *testing.T) {
+	doc, err := openapi.Parse([]byte(upgradeFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = UpgradeTo31(doc)
+
+	null := map[string]interface{}{"type": "null"}
+	tests := []struct {
+		name    string
+		pointer string
+		want    interface{}
+	}{
+		{"version", "/openapi", "3.1.0"},
+
+		// Schemas
+		{"typed nullable", "/components/schemas/Pet/properties/tag/type", []interface{}{"string", "null"}},
+		{"nullable enum", "/components/schemas/Pet/properties/tag/enum", []interface{}{"a", "b", nil}},
+		{"nullable ref", "/components/schemas/Pet/properties/owner/anyOf", []interface{}{map[string]interface{}{"$ref": "#/components/schemas/Owner"}, null}},
+		{"untyped nullable", "/components/schemas/Pet/properties/anything/anyOf", []interface{}{map[string]interface{}{"description": "Any value"}, null}},
+		{"not nullable", "/components/schemas/Owner", map[string]interface{}{"type": "object"}},
+		{"property named nullable", "/components/schemas/Pet/properties/nullable/type", "boolean"},
+		{"exclusive maximum", "/components/schemas/Pet/properties/score/exclusiveMaximum", 10},
+		{"bound moved", "/components/schemas/Pet/properties/score/maximum", nil},
+		{"parameter schema", "/paths/~1pets/get/parameters/0/schema/exclusiveMinimum", 0},
+		{"header schema", "/paths/~1pets/get/responses/200/headers/X-Rate/schema/type", []interface{}{"number", "null"}},
+
+		// User data is left alone
+		{"schema example", "/components/schemas/Pet/example", map[string]interface{}{"nullable": true, "exclusiveMinimum": true, "minimum": 2}},
+		{"media type example", "/paths/~1pets/get/responses/200/content/application~1json/example/0", map[string]interface{}{"nullable": true, "exclusiveMaximum": true}},
+		{"schema extension", "/components/schemas/Pet/properties/score/x-meta", map[string]interface{}{"nullable": true}},
+		{"document extension", "/x-settings", map[string]interface{}{"nullable": true, "exclusiveMinimum": true, "minimum": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := at(doc, tt.pointer); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.pointer, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpgradeConvertsSwagger2To31(t *testing.T) {
+	doc, err := Upgrade(loadFixture(t, "petstore-swagger2.yaml"), Target31)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := at(doc, "/components/schemas/Pet/properties/tag/type"); !reflect.DeepEqual(got, []interface{}{"string", "null"}) {
+		t.Errorf("x-nullable property type = %#v", got)
+	}
+	if got := at(doc, "/paths/~1pets/get/responses/200/description"); got != "A list of pets" {
+		t.Errorf("response description = %#v", got)
+	}
+}
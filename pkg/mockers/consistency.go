@@ -0,0 +1,187 @@
+package mockers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v6"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// DefaultFixturePoolSize is the number of shared instances generated per component schema in consistent mode
+const DefaultFixturePoolSize = 5
+
+// idReference matches property names pointing at another entity, such as customerId, customer_id or customerIds
+var idReference = regexp.MustCompile(`^(.+?)[_-]?(?:[Ii][Dd]|ID)s?$`)
+
+// fixture returns one of the shared instances of a component schema in consistent mode.
+// Instances are generated from their own seed and a fresh state, so every example
+// and every live response sees the same pool whatever the traversal order.
+// The schema is the reference being generated.
+func (g *generator) fixture(refName string, schema map[string]interface{}) (interface{}, bool) {
+	if !g.opts.Consistent || refName == "" || g.building[refName] {
+		return nil, false
+	}
+
+	pool := g.fixturePool(refName, schema)
+	return deepCopy(pool[g.faker.Number(0, len(pool)-1)]), true
+}
+
+// fixturePool generates the shared instances of a component schema on first use
+func (g *generator) fixturePool(refName string, schema map[string]interface{}) []interface{} {
+	key := fmt.Sprintf("%s %d", refName, g.direction)
+	if pool, ok := g.fixtures[key]; ok {
+		return pool
+	}
+
+	// Save the state of the example being generated
+	faker, context, depth, path, activeRefs := g.faker, g.context, g.depth, g.path, g.activeRefs
+	g.building[refName] = true
+	defer func() {
+		g.faker, g.context, g.depth, g.path, g.activeRefs = faker, context, depth, path, activeRefs
+		delete(g.building, refName)
+	}()
+
+	pool := make([]interface{}, g.opts.FixturePoolSize)
+	for i := range pool {
+		g.faker = gofakeit.New(deriveSeed(g.seed, fmt.Sprintf("fixture %s #%d", refName, i)))
+		g.context = "fixture " + refName
+		g.depth, g.path = 0, nil
+		g.activeRefs = make(map[string]bool)
+		pool[i] = g.generate(schema, "")
+	}
+	g.fixtures[key] = pool
+	return pool
+}
+
+// relatedID returns the id of a shared instance for properties such as customerId,
+// when a component schema named after the property prefix has an id property
+func (g *generator) relatedID(schema map[string]interface{}, name string) (interface{}, bool) {
+	if !g.opts.Consistent || name == "" {
+		return nil, false
+	}
+	match := idReference.FindStringSubmatch(name)
+	if match == nil {
+		return nil, false
+	}
+
+	refName, ref := g.entitySchema(match[1])
+	if ref == nil || g.building[refName] {
+		return nil, false
+	}
+
+	// Ids are always taken from the instances returned by the API
+	direction := g.direction
+	g.direction = directionResponse
+	pool := g.fixturePool(refName, ref)
+	g.direction = direction
+
+	instance, ok := pool[g.faker.Number(0, len(pool)-1)].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	id, ok := instance["id"]
+	if !ok || len(g.validate(schema, id, "")) > 0 {
+		return nil, false
+	}
+	return id, true
+}
+
+// entitySchema finds the component schema named after an entity, such as Customer for "customer",
+// and returns a reference to it
+func (g *generator) entitySchema(entity string) (string, map[string]interface{}) {
+	entity = strings.ReplaceAll(strings.ReplaceAll(entity, "_", ""), "-", "")
+	schemas := openapi.Schemas(g.doc)
+	for _, name := range sortedKeys(schemas) {
+		if !strings.EqualFold(name, entity) && !strings.EqualFold(name, singular(entity)) {
+			continue
+		}
+		schema, ok := schemas[name].(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+		resolved, _ := g.resolve(schema)
+		if _, ok := resolved["allOf"]; ok {
+			resolved, _ = g.mergeAllOf(resolved)
+		}
+		if props, ok := resolved["properties"].(map[string]interface{}); ok && props["id"] != nil {
+			return name, map[string]interface{}{"$ref": "#/components/schemas/" + name}
+		}
+		return "", nil
+	}
+	return "", nil
+}
+
+// pathEntityParameter names the id parameter of a path after its collection,
+// so the id of /customers/{id} is generated like a customerId
+func pathEntityParameter(pathKey, name string) string {
+	if !strings.EqualFold(name, "id") {
+		return name
+	}
+	segments := strings.Split(strings.Trim(pathKey, "/"), "/")
+	for i, segment := range segments {
+		if segment == "{"+name+"}" && i > 0 && !strings.HasPrefix(segments[i-1], "{") {
+			return singular(segments[i-1]) + "Id"
+		}
+	}
+	return name
+}
+
+// echo copies the values sent in the request into a generated response object, for the properties
+// they share. The response is validated again after each copy and the values making it invalid
+// are dropped, so echoing never turns a valid example into an invalid one.
+func (g *generator) echo(schema map[string]interface{}, value interface{}, request map[string]interface{}) interface{} {
+	obj, ok := value.(map[string]interface{})
+	if !ok || len(request) == 0 {
+		return value
+	}
+
+	resolved, _ := g.resolve(schema)
+	if _, ok := resolved["allOf"]; ok {
+		resolved, _ = g.mergeAllOf(resolved)
+	}
+	props, _ := resolved["properties"].(map[string]interface{})
+
+	// A response already invalid was reported by generateValid, echoing must not make it worse
+	violations := len(g.validate(schema, obj, ""))
+	for _, key := range sortedKeys(request) {
+		if _, ok := props[key].(map[string]interface{}); !ok {
+			continue
+		}
+		previous, exists := obj[key]
+		if !exists && !requiredSet(resolved)[key] {
+			continue
+		}
+		obj[key] = deepCopy(request[key])
+		if len(g.validate(schema, obj, "")) <= violations {
+			continue
+		}
+		if exists {
+			obj[key] = previous
+		} else {
+			delete(obj, key)
+		}
+	}
+	return obj
+}
+
+// deepCopy copies generated values so shared fixtures are never modified through an example
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out[key] = deepCopy(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = deepCopy(item)
+		}
+		return out
+	}
+	return value
+}
@@ -0,0 +1,242 @@
+package mockers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+const consistentSpec = `
+openapi: 3.0.3
+info: {title: Shop, version: "1"}
+paths:
+  /customers:
+    get:
+      responses:
+        "200":
+          description: Customers
+          content:
+            application/json:
+              schema:
+                type: array
+                minItems: 3
+                maxItems: 5
+                items: {$ref: '#/components/schemas/Customer'}
+  /customers/{id}:
+    get:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer}, example: 7}
+      responses:
+        "200":
+          description: Customer
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Customer'}
+  /orders:
+    get:
+      responses:
+        "200":
+          description: Order
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Order'}
+components:
+  schemas:
+    Customer:
+      type: object
+      required: [id, name]
+      properties:
+        id: {type: integer, minimum: 1000}
+        name: {type: string}
+    Order:
+      type: object
+      required: [id, customerId]
+      properties:
+        id: {type: integer}
+        customerId: {type: integer}
+`
+
+func TestConsistentFixturesAcrossOperations(t *testing.T) {
+	opts := Options{Seed: 1, Consistent: true, FixturePoolSize: 3}
+	result, err := MockOpenAPISpec(consistentSpec, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.InvalidExamples) > 0 {
+		t.Errorf("invalid examples = %v", result.InvalidExamples)
+	}
+	doc, err := openapi.Parse([]byte(result.Content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, _ := openapi.Parse([]byte(consistentSpec))
+	g := newGenerator(parsed, opts)
+	g.direction = directionResponse
+	pool := g.fixturePool("Customer", map[string]interface{}{"$ref": "#/components/schemas/Customer"})
+	// inPool tells whether a customer is one of the shared instances, ignoring the properties echoed from the request
+	inPool := func(customer map[string]interface{}, ignored ...string) bool {
+		for _, fixture := range pool {
+			expected := deepCopy(fixture).(map[string]interface{})
+			actual := deepCopy(customer).(map[string]interface{})
+			for _, key := range ignored {
+				delete(expected, key)
+				delete(actual, key)
+			}
+			if toKey(expected) == toKey(actual) {
+				return true
+			}
+		}
+		return false
+	}
+	example := func(path string) interface{} {
+		return dig(doc, "paths", path, "get", "responses", "200", "content", "application/json", "examples", autoExampleName, "value")
+	}
+
+	list, _ := example("/customers").([]interface{})
+	if len(list) < 3 {
+		t.Fatalf("list = %v, want at least 3 customers", example("/customers"))
+	}
+	for i, item := range list {
+		if customer, _ := item.(map[string]interface{}); !inPool(customer) {
+			t.Errorf("customer %d of the list = %v, want a shared instance", i, item)
+		}
+	}
+
+	detail, _ := example("/customers/{id}").(map[string]interface{})
+	if !inPool(detail, "id") {
+		t.Errorf("customer detail = %v, want a shared instance", detail)
+	}
+
+	ids := make(map[string]bool)
+	for _, fixture := range pool {
+		ids[toKey(fixture.(map[string]interface{})["id"])] = true
+	}
+	order, _ := example("/orders").(map[string]interface{})
+	if !ids[toKey(order["customerId"])] {
+		t.Errorf("customerId = %v, want the id of a shared customer", order["customerId"])
+	}
+}
+
+func TestConsistentPathIDEcho(t *testing.T) {
+	// The path id 7 is below the minimum of Customer.id, so the detail only echoes it when the schema allows it
+	tests := []struct {
+		name    string
+		minimum string
+		wantID  interface{}
+	}{
+		{"echoed", "minimum: 1", 7},
+		{"dropped when invalid", "minimum: 1000", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := strings.Replace(consistentSpec, "minimum: 1000", tt.minimum, 1)
+			result, err := MockOpenAPISpec(spec, Options{Seed: 1, Consistent: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.InvalidExamples) > 0 {
+				t.Errorf("invalid examples = %v", result.InvalidExamples)
+			}
+			doc, err := openapi.Parse([]byte(result.Content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			id := dig(doc, "paths", "/customers/{id}", "get", "responses", "200", "content", "application/json", "examples", autoExampleName, "value", "id")
+			if tt.wantID != nil && id != tt.wantID {
+				t.Errorf("id = %v, want the path id %v", id, tt.wantID)
+			}
+			if tt.wantID == nil && id == 7 {
+				t.Error("the path id was echoed although it violates the schema")
+			}
+		})
+	}
+}
+
+const echoSchemas = `
+Customer:
+  type: object
+  required: [id, name]
+  properties:
+    id: {type: integer}
+    name: {type: string}
+    email: {type: string}
+Account:
+  type: object
+  required: [id, plan]
+  properties:
+    id: {type: integer}
+    plan: {type: string}
+  anyOf:
+    - properties:
+        plan: {const: free}
+        id: {maximum: 100}
+    - properties:
+        plan: {const: paid}
+`
+
+func TestEcho(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		value   map[string]interface{}
+		request map[string]interface{}
+		want    map[string]interface{}
+	}{
+		{
+			name:    "shared properties",
+			schema:  "Customer",
+			value:   map[string]interface{}{"id": 1, "name": "Ann"},
+			request: map[string]interface{}{"id": 7, "name": "Bob", "unknown": true},
+			want:    map[string]interface{}{"id": 7, "name": "Bob"},
+		},
+		{
+			name:    "optional property left out",
+			schema:  "Customer",
+			value:   map[string]interface{}{"id": 1, "name": "Ann"},
+			request: map[string]interface{}{"email": "bob@example.com"},
+			want:    map[string]interface{}{"id": 1, "name": "Ann"},
+		},
+		{
+			name:    "property of another type",
+			schema:  "Customer",
+			value:   map[string]interface{}{"id": 1, "name": "Ann"},
+			request: map[string]interface{}{"id": "seven"},
+			want:    map[string]interface{}{"id": 1, "name": "Ann"},
+		},
+		{
+			name:    "value breaking the whole schema",
+			schema:  "Account",
+			value:   map[string]interface{}{"id": 5, "plan": "free"},
+			request: map[string]interface{}{"id": 5000},
+			want:    map[string]interface{}{"id": 5, "plan": "free"},
+		},
+		{
+			name:    "values checked one at a time",
+			schema:  "Account",
+			value:   map[string]interface{}{"id": 5, "plan": "free"},
+			request: map[string]interface{}{"id": 5000, "plan": "paid"},
+			want:    map[string]interface{}{"id": 5, "plan": "paid"},
+		},
+	}
+	parsed, err := openapi.Parse([]byte(echoSchemas))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := openapi.Document{
+		"openapi":    "3.0.3",
+		"components": map[string]interface{}{"schemas": map[string]interface{}(parsed)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newGenerator(doc, Options{Consistent: true})
+			g.direction = directionResponse
+			got := g.echo(map[string]interface{}{"$ref": "#/components/schemas/" + tt.schema}, tt.value, tt.request)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("echo = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
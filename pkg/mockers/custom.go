@@ -0,0 +1,158 @@
+package mockers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/brianvoe/gofakeit/v6"
+)
+
+// Generator produces a fake value for a schema.
+// Implementations are registered by format or by property name to extend the mocker
+// with domain-specific values such as IBANs, SKUs or internal identifiers.
+type Generator interface {
+	Generate(faker *gofakeit.Faker, schema map[string]interface{}) (interface{}, error)
+}
+
+// GeneratorFunc adapts an ordinary function to the Generator interface
+type GeneratorFunc func(faker *gofakeit.Faker, schema map[string]interface{}) (interface{}, error)
+
+// Generate calls f(faker, schema)
+func (f GeneratorFunc) Generate(faker *gofakeit.Faker, schema map[string]interface{}) (interface{}, error) {
+	return f(faker, schema)
+}
+
+var (
+	registryMutex      sync.RWMutex
+	formatGenerators   = make(map[string]Generator)
+	propertyGenerators = make(map[string]Generator)
+)
+
+// RegisterFormat registers a generator used for every schema with the given format.
+// Generators only needed by one generation are better given in Options.FormatGenerators.
+func RegisterFormat(format string, generator Generator) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	formatGenerators[format] = generator
+}
+
+// RegisterProperty registers a generator used for every property with the given name.
+// Names are matched case-insensitively. Generators only needed by one generation are
+// better given in Options.PropertyGenerators.
+func RegisterProperty(name string, generator Generator) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	propertyGenerators[strings.ToLower(name)] = generator
+}
+
+// registeredGenerator returns the custom generator for a property name or schema format,
+// looking at the options before the generators registered for the whole process
+func (g *generator) registeredGenerator(name string, schema map[string]interface{}) Generator {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	if name != "" {
+		for _, generators := range []map[string]Generator{g.opts.PropertyGenerators, propertyGenerators} {
+			for property, generator := range generators {
+				if strings.EqualFold(property, name) {
+					return generator
+				}
+			}
+		}
+	}
+	if format, ok := schema["format"].(string); ok {
+		for _, generators := range []map[string]Generator{g.opts.FormatGenerators, formatGenerators} {
+			if generator, ok := generators[format]; ok {
+				return generator
+			}
+		}
+	}
+	return nil
+}
+
+// fakerExtension is the schema extension naming how a value must be generated
+const fakerExtension = "x-faker"
+
+// generateCustom produces a value from the x-faker extension or a registered generator.
+// It reports false when neither applies or when they fail.
+func (g *generator) generateCustom(schema map[string]interface{}, name string) (interface{}, bool) {
+	if spec, ok := schema[fakerExtension]; ok {
+		value, err := g.generateFromExtension(spec)
+		if err == nil {
+			err = g.conforms(schema, value)
+		}
+		if err == nil {
+			return value, true
+		}
+		g.warn("invalid %s at %s: %v", fakerExtension, g.location(), err)
+	}
+
+	if generator := g.registeredGenerator(name, schema); generator != nil {
+		value, err := generator.Generate(g.faker, schema)
+		if err == nil {
+			err = g.conforms(schema, value)
+		}
+		if err == nil {
+			return value, true
+		}
+		g.warn("custom generator failed at %s: %v", g.location(), err)
+	}
+	return nil, false
+}
+
+// conforms rejects custom values that do not satisfy the schema, such as a word for an integer
+func (g *generator) conforms(schema map[string]interface{}, value interface{}) error {
+	if errs := g.validate(schema, value, ""); len(errs) > 0 {
+		return fmt.Errorf("generated %s does not satisfy the schema", valueType(value))
+	}
+	return nil
+}
+
+// generateFromExtension interprets an x-faker value, either a gofakeit function name
+// or an object such as {func: number, args: {min: 1, max: 9}} or {regex: "SKU-[0-9]{6}"}
+func (g *generator) generateFromExtension(spec interface{}) (interface{}, error) {
+	switch v := spec.(type) {
+	case string:
+		return g.callFaker(v, nil)
+	case map[string]interface{}:
+		if regex, ok := v["regex"].(string); ok {
+			return g.faker.Regex(regex), nil
+		}
+		function, ok := v["func"].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a func or regex key")
+		}
+		args, _ := v["args"].(map[string]interface{})
+		return g.callFaker(function, args)
+	}
+	return nil, fmt.Errorf("expected a function name or an object, got %T", spec)
+}
+
+// callFaker runs a gofakeit function by its lookup name, for example "ach" or "creditcardnumber"
+func (g *generator) callFaker(function string, args map[string]interface{}) (interface{}, error) {
+	info := gofakeit.GetFuncLookup(strings.ToLower(function))
+	if info == nil {
+		return nil, fmt.Errorf("unknown gofakeit function %q", function)
+	}
+
+	params := gofakeit.NewMapParams()
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		switch arg := args[key].(type) {
+		case []interface{}:
+			for _, item := range arg {
+				params.Add(key, fmt.Sprint(item))
+			}
+		default:
+			params.Add(key, fmt.Sprint(arg))
+		}
+	}
+
+	return info.Generate(g.faker.Rand, params, info)
+}
@@ -0,0 +1,200 @@
+package mockers
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+)
+
+// constant is a generator always returning the same value
+func constant(value interface{}) Generator {
+	return GeneratorFunc(func(*gofakeit.Faker, map[string]interface{}) (interface{}, error) {
+		return value, nil
+	})
+}
+
+// restoreRegistry puts back the generators registered for the process when a test ends
+func restoreRegistry(t *testing.T) {
+	t.Helper()
+	registryMutex.Lock()
+	formats, properties := formatGenerators, propertyGenerators
+	formatGenerators, propertyGenerators = make(map[string]Generator), make(map[string]Generator)
+	registryMutex.Unlock()
+	t.Cleanup(func() {
+		registryMutex.Lock()
+		formatGenerators, propertyGenerators = formats, properties
+		registryMutex.Unlock()
+	})
+}
+
+func TestFakerExtension(t *testing.T) {
+	tests := []struct {
+		name        string
+		schema      string
+		check       func(interface{}) bool
+		wantWarning string
+	}{
+		{
+			name:   "function name",
+			schema: `{type: string, x-faker: uuid}`,
+			check: func(v interface{}) bool {
+				s, ok := v.(string)
+				return ok && regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`).MatchString(s)
+			},
+		},
+		{
+			name:   "function with arguments",
+			schema: `{type: integer, x-faker: {func: number, args: {min: 3, max: 5}}}`,
+			check:  func(v interface{}) bool { return isIntegerBetween(v, 3, 5) },
+		},
+		{
+			name:   "regular expression",
+			schema: `{type: string, x-faker: {regex: "SKU-[0-9]{6}"}}`,
+			check: func(v interface{}) bool {
+				s, ok := v.(string)
+				return ok && regexp.MustCompile(`^SKU-[0-9]{6}$`).MatchString(s)
+			},
+		},
+		{
+			name:        "value not satisfying the schema",
+			schema:      `{type: integer, minimum: 10, maximum: 20, x-faker: word}`,
+			check:       func(v interface{}) bool { return isIntegerBetween(v, 10, 20) },
+			wantWarning: "invalid x-faker",
+		},
+		{
+			name:        "unknown function",
+			schema:      `{type: integer, minimum: 10, maximum: 20, x-faker: notAFunction}`,
+			check:       func(v interface{}) bool { return isIntegerBetween(v, 10, 20) },
+			wantWarning: "unknown gofakeit function",
+		},
+		{
+			name:        "object without func or regex",
+			schema:      `{type: string, x-faker: {args: {min: 1}}}`,
+			check:       func(v interface{}) bool { _, ok := v.(string); return ok },
+			wantWarning: "expected a func or regex key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, warnings := generateSchema(t, "Value: "+tt.schema, "Value", Options{Seed: 1})
+			if !tt.check(value) {
+				t.Errorf("value = %#v", value)
+			}
+			if tt.wantWarning == "" && len(warnings) > 0 {
+				t.Errorf("warnings = %v, want none", warnings)
+			}
+			if tt.wantWarning != "" && !hasWarning(warnings, tt.wantWarning) {
+				t.Errorf("warnings = %v, want %q", warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+// isIntegerBetween tells whether a generated value is an integer within bounds
+func isIntegerBetween(v interface{}, min, max int64) bool {
+	var n int64
+	switch i := v.(type) {
+	case int:
+		n = int64(i)
+	case int64:
+		n = i
+	default:
+		return false
+	}
+	return n >= min && n <= max
+}
+
+const registeredSchemas = `
+Account:
+  type: object
+  required: [iban, sku, Reference, count]
+  properties:
+    iban: {type: string, format: iban}
+    sku: {type: string}
+    Reference: {type: string, format: iban}
+    count: {type: integer, format: counter}
+`
+
+func TestRegisteredGenerators(t *testing.T) {
+	tests := []struct {
+		name        string
+		register    func()
+		opts        Options
+		want        map[string]interface{}
+		wantWarning string
+	}{
+		{
+			name: "format",
+			register: func() {
+				RegisterFormat("iban", constant("FR7630006000011234567890189"))
+			},
+			want: map[string]interface{}{"iban": "FR7630006000011234567890189", "Reference": "FR7630006000011234567890189"},
+		},
+		{
+			name: "property name, case-insensitively and before the format",
+			register: func() {
+				RegisterFormat("iban", constant("FR7630006000011234567890189"))
+				RegisterProperty("SKU", constant("SKU-000001"))
+				RegisterProperty("reference", constant("REF-1"))
+			},
+			want: map[string]interface{}{"iban": "FR7630006000011234567890189", "sku": "SKU-000001", "Reference": "REF-1"},
+		},
+		{
+			name: "options before the registry",
+			register: func() {
+				RegisterFormat("iban", constant("FR7630006000011234567890189"))
+				RegisterProperty("sku", constant("SKU-000001"))
+			},
+			opts: Options{
+				FormatGenerators:   map[string]Generator{"iban": constant("DE89370400440532013000")},
+				PropertyGenerators: map[string]Generator{"Sku": constant("SKU-999999")},
+			},
+			want: map[string]interface{}{"iban": "DE89370400440532013000", "sku": "SKU-999999"},
+		},
+		{
+			name: "value not satisfying the schema",
+			register: func() {
+				RegisterFormat("counter", constant("many"))
+			},
+			wantWarning: "custom generator failed",
+		},
+		{
+			name: "generator error",
+			register: func() {
+				RegisterFormat("counter", GeneratorFunc(func(*gofakeit.Faker, map[string]interface{}) (interface{}, error) {
+					return nil, fmt.Errorf("counter unavailable")
+				}))
+			},
+			wantWarning: "counter unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restoreRegistry(t)
+			tt.register()
+			tt.opts.Seed = 1
+
+			value, warnings := generateSchema(t, registeredSchemas, "Account", tt.opts)
+			account, ok := value.(map[string]interface{})
+			if !ok {
+				t.Fatalf("value = %#v, want an object", value)
+			}
+			for property, want := range tt.want {
+				if account[property] != want {
+					t.Errorf("%s = %#v, want %#v", property, account[property], want)
+				}
+			}
+			if _, ok := account["count"].(string); ok {
+				t.Errorf("count = %#v, want an integer", account["count"])
+			}
+			if tt.wantWarning == "" && len(warnings) > 0 {
+				t.Errorf("warnings = %v, want none", warnings)
+			}
+			if tt.wantWarning != "" && !hasWarning(warnings, tt.wantWarning) {
+				t.Errorf("warnings = %v, want %q", warnings, tt.wantWarning)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package mockers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// Formats of exported fixtures
+const (
+	FixtureFormatJSON   = "json"
+	FixtureFormatNDJSON = "ndjson"
+	FixtureFormatCSV    = "csv"
+)
+
+// FixtureContentTypes maps the fixture formats to their media type
+var FixtureContentTypes = map[string]string{
+	FixtureFormatJSON:   "application/json",
+	FixtureFormatNDJSON: "application/x-ndjson",
+	FixtureFormatCSV:    "text/csv",
+}
+
+// GenerateFixtures generates instances of a component schema with the same engine as the examples.
+// Instance i always gets the same value for a given seed, so exports are reproducible and
+// their first instances are the shared ones used by the consistent mode. Generation stops with
+// the error of the context when it is done.
+func GenerateFixtures(ctx context.Context, doc openapi.Document, schemaName string, count int, opts Options) ([]interface{}, error) {
+	if _, ok := openapi.Schemas(doc)[schemaName].(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("schema %s is not declared in the components", schemaName)
+	}
+
+	gen := newGenerator(doc, opts)
+	gen.direction = directionResponse
+	// The exported instances are generated fresh, only nested references come from the pool
+	gen.building[schemaName] = true
+
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + schemaName}
+	fixtures := make([]interface{}, count)
+	for i := range fixtures {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fixtures[i] = gen.generateValid(fmt.Sprintf("fixture %s #%d", schemaName, i), ref, "")
+	}
+	return fixtures, nil
+}
+
+// WriteFixtures writes fixtures as a JSON array, newline-delimited JSON or a CSV table
+func WriteFixtures(w io.Writer, fixtures []interface{}, format string) error {
+	switch format {
+	case FixtureFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(fixtures)
+	case FixtureFormatNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, fixture := range fixtures {
+			if err := encoder.Encode(fixture); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FixtureFormatCSV:
+		body, err := encodeCSV(fixtures)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(body)
+		return err
+	}
+	return fmt.Errorf("unsupported fixture format %q", format)
+}
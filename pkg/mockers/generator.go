@@ -0,0 +1,906 @@
+package mockers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+const (
+	defaultMinNumber = 1
+	defaultMaxNumber = 1000
+	defaultArraySize = 2
+)
+
+// direction tells whether an example is sent by the client or returned by the server
+type direction int
+
+const (
+	directionResponse direction = iota
+	directionRequest
+)
+
+// generator builds example values that satisfy the constraints of a JSON schema
+type generator struct {
+	faker *gofakeit.Faker
+	doc   openapi.Document
+	opts  Options
+	seed  int64
+
+	// locale holds the language data for names, addresses and text, nil for English
+	locale *localeData
+
+	// Recursion tracking: references being expanded, current depth and property path
+	activeRefs map[string]bool
+	merging    map[string]bool
+	validating map[string]bool
+	depth      int
+	path       []string
+
+	// Shared instances of component schemas in consistent mode, and the schemas being pooled
+	fixtures map[string][]interface{}
+	building map[string]bool
+
+	// direction decides whether readOnly or writeOnly properties are left out
+	direction direction
+
+	// context names the example being generated in warnings, such as "GET /pets 200"
+	context  string
+	warnings []string
+	warned   map[string]bool
+
+	// invalidExamples lists the examples that still violate their schema after every attempt
+	invalidExamples []string
+}
+
+func newGenerator(doc openapi.Document, opts Options) *generator {
+	opts = opts.withDefaults()
+	g := &generator{
+		faker:      gofakeit.New(deriveSeed(opts.Seed, "")),
+		doc:        doc,
+		opts:       opts,
+		seed:       opts.Seed,
+		activeRefs: make(map[string]bool),
+		merging:    make(map[string]bool),
+		validating: make(map[string]bool),
+		fixtures:   make(map[string][]interface{}),
+		building:   make(map[string]bool),
+		warned:     make(map[string]bool),
+	}
+
+	if locale, ok := NormalizeLocale(opts.Locale); ok {
+		g.locale = locales[locale]
+	} else {
+		g.warn("unsupported locale %q, using %q", opts.Locale, DefaultLocale)
+	}
+	return g
+}
+
+// reseed restarts the random sequence from a seed derived from the example key,
+// so each example only depends on its own schema and location in the spec
+func (g *generator) reseed(key string) {
+	g.context = key
+	g.faker = gofakeit.New(deriveSeed(g.seed, key))
+}
+
+// warn records a generation warning once
+func (g *generator) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if g.context != "" {
+		msg = g.context + ": " + msg
+	}
+	if !g.warned[msg] {
+		g.warned[msg] = true
+		g.warnings = append(g.warnings, msg)
+	}
+}
+
+// location describes the property being generated
+func (g *generator) location() string {
+	if len(g.path) == 0 {
+		return "the root"
+	}
+	return strings.Join(g.path, ".")
+}
+
+func (g *generator) pushPath(name string) {
+	g.path = append(g.path, name)
+}
+
+func (g *generator) popPath() {
+	g.path = g.path[:len(g.path)-1]
+}
+
+// generate returns an example value for the schema.
+// name is the property name holding the value, used to pick a realistic value
+// when the schema itself does not constrain it.
+func (g *generator) generate(schema map[string]interface{}, name string) interface{} {
+	reference := schema
+	if ref, ok := schema["$ref"].(string); ok {
+		if g.activeRefs[ref] {
+			g.warn("recursive schema %s truncated at %s", openapi.RefName(ref), g.location())
+			return g.terminal(schema)
+		}
+		g.activeRefs[ref] = true
+		defer delete(g.activeRefs, ref)
+	}
+
+	if g.depth >= g.opts.MaxDepth {
+		g.warn("maximum depth %d reached at %s", g.opts.MaxDepth, g.location())
+		return g.terminal(schema)
+	}
+	g.depth++
+	defer func() { g.depth-- }()
+
+	schema, refName := g.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	// Explicit values always win over generated ones
+	if v, ok := g.explicitValue(schema); ok {
+		return v
+	}
+
+	// In consistent mode references and ids come from the shared fixture pool
+	if v, ok := g.fixture(refName, reference); ok {
+		return v
+	}
+	if v, ok := g.relatedID(schema, name); ok {
+		return v
+	}
+
+	var inherited map[string]interface{}
+	if _, ok := schema["allOf"]; ok {
+		schema, inherited = g.mergeAllOf(schema)
+		if v, ok := g.explicitValue(schema); ok {
+			return v
+		}
+	}
+
+	if variants := schemaVariants(schema); len(variants) > 0 {
+		return g.generateVariant(schema, variants, name)
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[g.faker.Number(0, len(enum)-1)]
+	}
+
+	// x-faker and registered generators take over from the built-in ones
+	if v, ok := g.generateCustom(schema, name); ok {
+		return v
+	}
+
+	switch schemaType(schema) {
+	case "null":
+		return nil
+	case "string":
+		return g.generateString(schema, name)
+	case "integer":
+		return g.generateInteger(schema)
+	case "number":
+		return g.generateNumber(schema)
+	case "boolean":
+		return g.faker.Bool()
+	case "array":
+		return g.generateArray(schema, name)
+	case "object":
+		obj := g.generateObject(schema)
+		// A schema extending a polymorphic parent names itself in the discriminator
+		if inherited != nil && refName != "" {
+			setDiscriminator(obj, inherited, refName)
+		}
+		return obj
+	default:
+		return g.fakeValue(name)
+	}
+}
+
+// explicitValue returns the value set by const, example, examples or default.
+// Author examples are skipped unless the example policy keeps them.
+func (g *generator) explicitValue(schema map[string]interface{}) (interface{}, bool) {
+	if v, ok := schema["const"]; ok {
+		return v, true
+	}
+	if g.opts.ExamplePolicy == ExamplePolicyFillMissing {
+		if v, ok := schema["example"]; ok {
+			return v, true
+		}
+		if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+			return examples[0], true
+		}
+	}
+	if v, ok := schema["default"]; ok {
+		return v, true
+	}
+	return nil, false
+}
+
+// resolve follows $ref chains to the referenced schema and returns the name of the last reference
+func (g *generator) resolve(schema map[string]interface{}) (map[string]interface{}, string) {
+	refName := ""
+	for i := 0; schema != nil && i < 32; i++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema, refName
+		}
+		refName = openapi.RefName(ref)
+		target, ok := openapi.Resolve(g.doc, ref)
+		if !ok {
+			// Fall back to the schema name for references outside components
+			target, ok = openapi.Schemas(g.doc)[refName].(map[string]interface{})
+			if !ok {
+				return nil, refName
+			}
+		}
+		schema = target
+	}
+	return schema, refName
+}
+
+// mergeAllOf flattens an allOf composition into a single schema.
+// Keywords of later subschemas override earlier ones, properties and required lists are combined.
+// The discriminator declared by a parent schema is returned separately.
+func (g *generator) mergeAllOf(schema map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	merged := make(map[string]interface{})
+	properties := make(map[string]interface{})
+	var required []interface{}
+	var discriminator map[string]interface{}
+
+	parts := make([]map[string]interface{}, 0)
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, raw := range allOf {
+			part, ok := raw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			part, refName := g.resolve(part)
+			if part == nil {
+				continue
+			}
+			if _, nested := part["allOf"]; nested {
+				if g.merging[refName] {
+					g.warn("recursive allOf through %s ignored at %s", refName, g.location())
+					continue
+				}
+				if refName != "" {
+					g.merging[refName] = true
+				}
+				var parentDiscriminator map[string]interface{}
+				part, parentDiscriminator = g.mergeAllOf(part)
+				delete(g.merging, refName)
+				if parentDiscriminator != nil {
+					discriminator = parentDiscriminator
+				}
+			}
+			if d, ok := part["discriminator"].(map[string]interface{}); ok {
+				discriminator = d
+			}
+			parts = append(parts, part)
+		}
+	}
+	parts = append(parts, schema)
+
+	for _, part := range parts {
+		for key, value := range part {
+			switch key {
+			case "allOf", "discriminator":
+			case "properties":
+				if props, ok := value.(map[string]interface{}); ok {
+					for name, prop := range props {
+						properties[name] = prop
+					}
+				}
+			case "required":
+				if list, ok := value.([]interface{}); ok {
+					required = append(required, list...)
+				}
+			default:
+				merged[key] = value
+			}
+		}
+	}
+
+	if len(properties) > 0 {
+		merged["properties"] = properties
+		if _, ok := merged["type"]; !ok {
+			merged["type"] = "object"
+		}
+	}
+	if len(required) > 0 {
+		merged["required"] = required
+	}
+	if d, ok := schema["discriminator"]; ok {
+		merged["discriminator"] = d
+	}
+	return merged, discriminator
+}
+
+// schemaVariants returns the oneOf or anyOf alternatives of a schema
+func schemaVariants(schema map[string]interface{}) []interface{} {
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok && len(oneOf) > 0 {
+		return oneOf
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && len(anyOf) > 0 {
+		return anyOf
+	}
+	return nil
+}
+
+// generateVariant picks one alternative of a oneOf or anyOf and sets the discriminator accordingly
+func (g *generator) generateVariant(schema map[string]interface{}, variants []interface{}, name string) interface{} {
+	variant, ok := variants[g.faker.Number(0, len(variants)-1)].(map[string]interface{})
+	if !ok {
+		return g.fakeValue(name)
+	}
+
+	variantName := ""
+	if ref, ok := variant["$ref"].(string); ok {
+		variantName = openapi.RefName(ref)
+	}
+
+	// Properties declared next to oneOf/anyOf apply to every alternative
+	base := make(map[string]interface{})
+	for key, value := range schema {
+		switch key {
+		case "oneOf", "anyOf", "discriminator":
+		default:
+			base[key] = value
+		}
+	}
+
+	target := variant
+	if _, ok := base["properties"]; ok {
+		target = map[string]interface{}{"allOf": []interface{}{base, variant}}
+	}
+
+	value := g.generate(target, name)
+	if discriminator, ok := schema["discriminator"].(map[string]interface{}); ok && variantName != "" {
+		setDiscriminator(value, discriminator, variantName)
+	}
+	return value
+}
+
+// setDiscriminator writes the discriminator property of a generated object
+// using the mapping key pointing at the schema, or the schema name itself.
+func setDiscriminator(value interface{}, discriminator map[string]interface{}, schemaName string) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	property, ok := discriminator["propertyName"].(string)
+	if !ok || property == "" {
+		return
+	}
+
+	obj[property] = schemaName
+	if mapping, ok := discriminator["mapping"].(map[string]interface{}); ok {
+		for _, key := range sortedKeys(mapping) {
+			target, _ := mapping[key].(string)
+			if target == schemaName || openapi.RefName(target) == schemaName {
+				obj[property] = key
+				return
+			}
+		}
+	}
+}
+
+// schemaType returns the declared type of a schema, inferring it from its keywords when missing
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		// Prefer a non-null type so the example shows the value's shape
+		for _, item := range t {
+			if s, ok := item.(string); ok && s != "null" {
+				return s
+			}
+		}
+		if len(t) > 0 {
+			return "null"
+		}
+	}
+
+	switch {
+	case schema["properties"] != nil || schema["additionalProperties"] != nil:
+		return "object"
+	case schema["items"] != nil:
+		return "array"
+	case schema["format"] == "int32" || schema["format"] == "int64":
+		return "integer"
+	case schema["format"] == "float" || schema["format"] == "double":
+		return "number"
+	case schema["minLength"] != nil || schema["maxLength"] != nil || schema["pattern"] != nil || schema["format"] != nil:
+		return "string"
+	case schema["minimum"] != nil || schema["maximum"] != nil || schema["multipleOf"] != nil:
+		return "number"
+	}
+	return ""
+}
+
+func (g *generator) generateString(schema map[string]interface{}, name string) interface{} {
+	if pattern, ok := schema["pattern"].(string); ok && pattern != "" {
+		return g.faker.Regex(pattern)
+	}
+
+	format, _ := schema["format"].(string)
+	value, ok := g.formatValue(format, schema)
+	if !ok {
+		value, ok = g.fakeValue(name).(string)
+		if !ok {
+			value = g.word()
+		}
+	}
+
+	return fitLength(g.faker, value, schema)
+}
+
+// formatValue generates a string for the well-known formats
+func (g *generator) formatValue(format string, schema map[string]interface{}) (string, bool) {
+	switch format {
+	case "date-time":
+		return g.date().Format(time.RFC3339), true
+	case "date":
+		return g.date().Format("2006-01-02"), true
+	case "time":
+		return g.date().Format("15:04:05"), true
+	case "uuid":
+		return g.faker.UUID(), true
+	case "email", "idn-email":
+		return g.faker.Email(), true
+	case "uri", "url", "iri", "uri-reference", "iri-reference":
+		return g.faker.URL(), true
+	case "hostname", "idn-hostname":
+		return g.faker.DomainName(), true
+	case "ipv4":
+		return g.faker.IPv4Address(), true
+	case "ipv6":
+		return g.faker.IPv6Address(), true
+	case "byte":
+		size := 12
+		if maxLength, ok := intKeyword(schema, "maxLength"); ok {
+			// Base64 output is 4/3 of the input size
+			size = int(math.Max(1, math.Min(float64(size), float64(maxLength/4*3))))
+		}
+		return base64.StdEncoding.EncodeToString([]byte(g.faker.LetterN(uint(size)))), true
+	case "binary":
+		return g.faker.LetterN(16), true
+	case "password":
+		return g.faker.Password(true, true, true, false, false, 12), true
+	}
+	return "", false
+}
+
+// Generated dates stay within a fixed window so examples do not depend on the current date
+var (
+	dateRangeStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	dateRangeEnd   = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func (g *generator) date() time.Time {
+	return g.faker.DateRange(dateRangeStart, dateRangeEnd)
+}
+
+// fitLength pads or truncates a string so it satisfies minLength and maxLength
+func fitLength(faker *gofakeit.Faker, value string, schema map[string]interface{}) string {
+	minLength, hasMin := intKeyword(schema, "minLength")
+	maxLength, hasMax := intKeyword(schema, "maxLength")
+
+	runes := []rune(value)
+	if hasMin {
+		for len(runes) < minLength {
+			runes = append(runes, []rune(faker.Letter())...)
+		}
+	}
+	if hasMax && len(runes) > maxLength {
+		runes = runes[:maxLength]
+	}
+	return string(runes)
+}
+
+func (g *generator) generateInteger(schema map[string]interface{}) interface{} {
+	minimum, maximum := numberBounds(schema, true)
+	lo, hi := int64(math.Ceil(minimum)), int64(math.Floor(maximum))
+	if lo > hi {
+		return lo
+	}
+
+	value := int64(math.Floor(g.faker.Float64Range(float64(lo), float64(hi)+1)))
+	if value > hi {
+		value = hi
+	}
+
+	if multipleOf, ok := floatKeyword(schema, "multipleOf"); ok && multipleOf >= 1 {
+		step := int64(multipleOf)
+		if rounded := (value / step) * step; rounded >= lo {
+			value = rounded
+		} else if rounded+step <= hi {
+			value = rounded + step
+		}
+	}
+	return value
+}
+
+func (g *generator) generateNumber(schema map[string]interface{}) interface{} {
+	minimum, maximum := numberBounds(schema, false)
+	if minimum > maximum {
+		return minimum
+	}
+
+	value := g.faker.Float64Range(minimum, maximum)
+	if multipleOf, ok := floatKeyword(schema, "multipleOf"); ok && multipleOf > 0 {
+		rounded := math.Floor(value/multipleOf) * multipleOf
+		if rounded < minimum {
+			rounded += multipleOf
+		}
+		return rounded
+	}
+	// Keep examples readable when rounding stays within bounds
+	if rounded := math.Round(value*100) / 100; rounded >= minimum && rounded <= maximum {
+		return rounded
+	}
+	return value
+}
+
+// numberBounds computes the inclusive range allowed by the numeric keywords,
+// supporting both the boolean (OpenAPI 3.0) and numeric (3.1) exclusive bounds.
+func numberBounds(schema map[string]interface{}, integer bool) (float64, float64) {
+	epsilon := 0.01
+	if integer {
+		epsilon = 1
+	}
+
+	minimum, hasMin := floatKeyword(schema, "minimum")
+	maximum, hasMax := floatKeyword(schema, "maximum")
+
+	if exclusive, ok := schema["exclusiveMinimum"].(bool); ok && exclusive && hasMin {
+		minimum += epsilon
+	} else if v, ok := floatKeyword(schema, "exclusiveMinimum"); ok && (!hasMin || v >= minimum) {
+		minimum, hasMin = v+epsilon, true
+	}
+	if exclusive, ok := schema["exclusiveMaximum"].(bool); ok && exclusive && hasMax {
+		maximum -= epsilon
+	} else if v, ok := floatKeyword(schema, "exclusiveMaximum"); ok && (!hasMax || v <= maximum) {
+		maximum, hasMax = v-epsilon, true
+	}
+
+	switch {
+	case !hasMin && !hasMax:
+		minimum, maximum = defaultMinNumber, defaultMaxNumber
+	case !hasMin:
+		minimum = math.Min(0, maximum-defaultMaxNumber)
+		if maximum > 0 {
+			minimum = math.Max(minimum, math.Min(defaultMinNumber, maximum))
+		}
+	case !hasMax:
+		maximum = minimum + defaultMaxNumber
+	}
+	return minimum, maximum
+}
+
+func (g *generator) generateArray(schema map[string]interface{}, name string) interface{} {
+	size := g.collectionSize(schema, "minItems", "maxItems")
+
+	items, _ := schema["items"].(map[string]interface{})
+	unique, _ := schema["uniqueItems"].(bool)
+
+	// An array of the schema being expanded ends the recursion with no items
+	if items != nil && g.isRecursive(items) {
+		if minItems, _ := intKeyword(schema, "minItems"); minItems == 0 {
+			g.warn("recursive schema %s truncated at %s", recursiveRefName(items), g.location())
+			return []interface{}{}
+		}
+	}
+
+	g.pushPath("[]")
+	defer g.popPath()
+
+	result := make([]interface{}, 0, size)
+	seen := make(map[string]bool)
+	for attempts := 0; len(result) < size && attempts < size*10; attempts++ {
+		var item interface{}
+		if items != nil {
+			item = g.generate(items, singular(name))
+		} else {
+			item = g.fakeValue(singular(name))
+		}
+		if unique {
+			key := toKey(item)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func (g *generator) generateObject(schema map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+
+	required := requiredSet(schema)
+
+	props, _ := schema["properties"].(map[string]interface{})
+	for _, key := range sortedKeys(props) {
+		prop, ok := props[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// readOnly properties are never sent and writeOnly ones never returned
+		if g.skipProperty(prop) {
+			continue
+		}
+		// Optional properties pointing back at a schema being expanded are left out
+		if ref, ok := prop["$ref"].(string); ok && !required[key] && g.activeRefs[ref] {
+			g.pushPath(key)
+			g.warn("recursive schema %s truncated at %s", openapi.RefName(ref), g.location())
+			g.popPath()
+			continue
+		}
+		g.pushPath(key)
+		result[key] = g.generate(prop, key)
+		g.popPath()
+	}
+
+	// Generate map entries for schemas that only describe their values
+	if len(props) == 0 {
+		if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok && !g.isRecursive(additional) {
+			size := g.collectionSize(schema, "minProperties", "maxProperties")
+			for i := 0; len(result) < size && i < size*10; i++ {
+				key := g.faker.Word()
+				if _, exists := result[key]; !exists {
+					g.pushPath(key)
+					result[key] = g.generate(additional, key)
+					g.popPath()
+				}
+			}
+		}
+	}
+
+	// Make sure required properties without a schema still appear
+	for _, key := range sortedBoolKeys(required) {
+		if _, exists := result[key]; !exists && props[key] == nil {
+			result[key] = g.fakeValue(key)
+		}
+	}
+
+	// Respect minProperties by adding extra entries when allowed,
+	// using the additionalProperties schema for their values
+	if minProps, ok := intKeyword(schema, "minProperties"); ok && schema["additionalProperties"] != false {
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		minProps = min(minProps, g.opts.MaxArrayLength)
+		for i := 0; len(result) < minProps && i < minProps*2; i++ {
+			key := g.faker.Word()
+			if _, exists := result[key]; exists {
+				continue
+			}
+			if additional != nil && !g.isRecursive(additional) {
+				g.pushPath(key)
+				result[key] = g.generate(additional, key)
+				g.popPath()
+			} else {
+				result[key] = g.faker.Word()
+			}
+		}
+	}
+
+	return result
+}
+
+// toKey returns a comparable representation of a generated value
+func toKey(value interface{}) string {
+	return fmt.Sprintf("%v", value)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func floatKeyword(schema map[string]interface{}, key string) (float64, bool) {
+	switch v := schema[key].(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func intKeyword(schema map[string]interface{}, key string) (int, bool) {
+	v, ok := floatKeyword(schema, key)
+	return int(v), ok
+}
+
+// singular turns a plural property name into the name of one of its items
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "ses"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
+
+// skipProperty reports whether a property does not belong in the current direction
+func (g *generator) skipProperty(prop map[string]interface{}) bool {
+	resolved, _ := g.resolve(prop)
+	if resolved == nil {
+		return false
+	}
+	if g.direction == directionRequest {
+		readOnly, _ := resolved["readOnly"].(bool)
+		return readOnly
+	}
+	writeOnly, _ := resolved["writeOnly"].(bool)
+	return writeOnly
+}
+
+// collectionSize picks how many items or entries to generate within the
+// schema bounds and the configured maximum length
+func (g *generator) collectionSize(schema map[string]interface{}, minKey, maxKey string) int {
+	size := defaultArraySize
+	if minimum, ok := intKeyword(schema, minKey); ok && size < minimum {
+		size = minimum
+	}
+	if maximum, ok := intKeyword(schema, maxKey); ok && size > maximum {
+		size = maximum
+	}
+	if size > g.opts.MaxArrayLength {
+		g.warn("%s of %d capped to %d items at %s", minKey, size, g.opts.MaxArrayLength, g.location())
+		size = g.opts.MaxArrayLength
+	}
+	return size
+}
+
+// isRecursive reports whether the schema, or the items of an array schema,
+// references a schema that is currently being expanded
+func (g *generator) isRecursive(schema map[string]interface{}) bool {
+	return recursiveRefName(schema) != "" && g.activeRefs[recursiveRef(schema)]
+}
+
+func recursiveRef(schema map[string]interface{}) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return ref
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		if ref, ok := items["$ref"].(string); ok {
+			return ref
+		}
+	}
+	return ""
+}
+
+func recursiveRefName(schema map[string]interface{}) string {
+	if ref := recursiveRef(schema); ref != "" {
+		return openapi.RefName(ref)
+	}
+	return ""
+}
+
+// terminal returns the smallest valid value for a schema, without following
+// any further reference. It ends recursive and overly deep structures.
+func (g *generator) terminal(schema map[string]interface{}) interface{} {
+	schema, _ = g.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+	if v, ok := g.explicitValue(schema); ok {
+		return v
+	}
+	if nullable(schema) {
+		return nil
+	}
+	if variants := schemaVariants(schema); len(variants) > 0 {
+		if variant, ok := variants[0].(map[string]interface{}); ok && variant["$ref"] == nil {
+			return g.terminal(variant)
+		}
+		return map[string]interface{}{}
+	}
+
+	switch schemaType(schema) {
+	case "array":
+		return []interface{}{}
+	case "object", "":
+		// Keep required primitive properties so the object stays valid
+		result := make(map[string]interface{})
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, key := range sortedBoolKeys(requiredSet(schema)) {
+			prop, ok := props[key].(map[string]interface{})
+			if !ok || prop["$ref"] != nil || g.skipProperty(prop) {
+				continue
+			}
+			g.pushPath(key)
+			switch schemaType(prop) {
+			case "object", "array", "":
+				result[key] = g.terminal(prop)
+			default:
+				result[key] = g.generateLeaf(prop, key)
+			}
+			g.popPath()
+		}
+		return result
+	default:
+		return g.generateLeaf(schema, "")
+	}
+}
+
+// generateLeaf generates a primitive value without any nesting
+func (g *generator) generateLeaf(schema map[string]interface{}, name string) interface{} {
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[g.faker.Number(0, len(enum)-1)]
+	}
+	if v, ok := g.generateCustom(schema, name); ok {
+		return v
+	}
+	switch schemaType(schema) {
+	case "string":
+		return g.generateString(schema, name)
+	case "integer":
+		return g.generateInteger(schema)
+	case "number":
+		return g.generateNumber(schema)
+	case "boolean":
+		return g.faker.Bool()
+	}
+	return nil
+}
+
+// nullable reports whether null is an accepted value for the schema
+func nullable(schema map[string]interface{}) bool {
+	if n, ok := schema["nullable"].(bool); ok && n {
+		return true
+	}
+	switch t := schema["type"].(type) {
+	case string:
+		return t == "null"
+	case []interface{}:
+		for _, item := range t {
+			if item == "null" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func requiredSet(schema map[string]interface{}) map[string]bool {
+	set := make(map[string]bool)
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, raw := range required {
+			if key, ok := raw.(string); ok {
+				set[key] = true
+			}
+		}
+	}
+	return set
+}
+
+func sortedBoolKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
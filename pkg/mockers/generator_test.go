@@ -0,0 +1,377 @@
+package mockers
+
+import (
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// generateSchema generates an example of a component schema, the schemas being given as YAML
+func generateSchema(t *testing.T, schemas, name string, opts Options) (interface{}, []string) {
+	t.Helper()
+	parsed, err := openapi.Parse([]byte(schemas))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := openapi.Document{
+		"openapi":    "3.0.3",
+		"components": map[string]interface{}{"schemas": map[string]interface{}(parsed)},
+	}
+	g := newGenerator(doc, opts)
+	g.reseed(name)
+	value := g.generate(map[string]interface{}{"$ref": "#/components/schemas/" + name}, "")
+	return value, g.warnings
+}
+
+// depth returns how deeply objects and arrays are nested in a value
+func depth(value interface{}) int {
+	deepest := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, item := range v {
+			deepest = max(deepest, depth(item))
+		}
+	case []interface{}:
+		for _, item := range v {
+			deepest = max(deepest, depth(item))
+		}
+	default:
+		return 0
+	}
+	return deepest + 1
+}
+
+// hasWarning tells whether a warning contains a message
+func hasWarning(warnings []string, message string) bool {
+	for _, warning := range warnings {
+		if strings.Contains(warning, message) {
+			return true
+		}
+	}
+	return false
+}
+
+const recursiveSchemas = `
+Category:
+  type: object
+  required: [name, parent]
+  properties:
+    name: {type: string}
+    parent: {$ref: '#/components/schemas/Category'}
+Folder:
+  type: object
+  required: [name]
+  properties:
+    name: {type: string}
+    parent: {$ref: '#/components/schemas/Folder'}
+    children:
+      type: array
+      items: {$ref: '#/components/schemas/Folder'}
+A:
+  type: object
+  required: [b]
+  properties:
+    b: {$ref: '#/components/schemas/B'}
+B:
+  type: object
+  required: [a]
+  properties:
+    a: {$ref: '#/components/schemas/A'}
+Level1:
+  type: object
+  required: [next]
+  properties:
+    next: {$ref: '#/components/schemas/Level2'}
+Level2:
+  type: object
+  required: [next]
+  properties:
+    next: {$ref: '#/components/schemas/Level3'}
+Level3:
+  type: object
+  required: [next]
+  properties:
+    next: {$ref: '#/components/schemas/Level4'}
+Level4:
+  type: object
+  required: [next]
+  properties:
+    next: {$ref: '#/components/schemas/Level5'}
+Level5:
+  type: object
+  required: [name]
+  properties:
+    name: {type: string}
+`
+
+func TestGenerateRecursiveSchemas(t *testing.T) {
+	tests := []struct {
+		schema      string
+		maxDepth    int
+		wantWarning string
+	}{
+		{"Category", 0, "recursive schema Category truncated at parent"},
+		{"Folder", 0, "recursive schema Folder truncated at parent"},
+		{"A", 0, "recursive schema A truncated at b.a"},
+		{"Level1", 3, "maximum depth 3 reached at next.next.next"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			value, warnings := generateSchema(t, recursiveSchemas, tt.schema, Options{Seed: 1, MaxDepth: tt.maxDepth})
+			if _, ok := value.(map[string]interface{}); !ok {
+				t.Fatalf("value = %v, want an object", value)
+			}
+			if !hasWarning(warnings, tt.wantWarning) {
+				t.Errorf("warnings = %v, want %q", warnings, tt.wantWarning)
+			}
+			maxDepth := tt.maxDepth
+			if maxDepth == 0 {
+				maxDepth = DefaultMaxDepth
+			}
+			// The last expanded schema holds the minimal value of the truncated one
+			if got := depth(value); got > maxDepth+1 {
+				t.Errorf("depth = %d, want at most %d: %v", got, maxDepth+1, value)
+			}
+		})
+	}
+}
+
+func TestGenerateRecursiveSchemaKeepsRequiredFields(t *testing.T) {
+	value, _ := generateSchema(t, recursiveSchemas, "Category", Options{Seed: 1})
+	category := value.(map[string]interface{})
+	parent, ok := category["parent"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("parent = %v, want the truncated parent object", category["parent"])
+	}
+	if _, ok := parent["name"].(string); !ok {
+		t.Errorf("parent = %v, want its required name", parent)
+	}
+
+	value, _ = generateSchema(t, recursiveSchemas, "Folder", Options{Seed: 1})
+	folder := value.(map[string]interface{})
+	if _, ok := folder["parent"]; ok {
+		t.Errorf("folder = %v, want the optional recursive parent left out", folder)
+	}
+	if children, ok := folder["children"].([]interface{}); !ok || len(children) != 0 {
+		t.Errorf("children = %v, want an empty array", folder["children"])
+	}
+}
+
+const compositionSchemas = `
+Pet:
+  type: object
+  required: [petType]
+  properties:
+    petType: {type: string}
+    name: {type: string}
+  discriminator:
+    propertyName: petType
+    mapping:
+      dog: '#/components/schemas/Dog'
+      cat: '#/components/schemas/Cat'
+Dog:
+  allOf:
+    - $ref: '#/components/schemas/Pet'
+    - type: object
+      required: [bark]
+      properties:
+        bark: {type: boolean}
+Cat:
+  allOf:
+    - $ref: '#/components/schemas/Pet'
+    - required: [lives]
+      properties:
+        lives: {type: integer, minimum: 1, maximum: 9}
+AnyPet:
+  oneOf:
+    - $ref: '#/components/schemas/Dog'
+    - $ref: '#/components/schemas/Cat'
+  discriminator:
+    propertyName: petType
+    mapping:
+      dog: '#/components/schemas/Dog'
+      cat: '#/components/schemas/Cat'
+Scores:
+  type: object
+  maxProperties: 3
+  additionalProperties: {type: integer, minimum: 1, maximum: 5}
+Nickname:
+  type: [string, "null"]
+Node:
+  type: object
+  nullable: true
+  required: [next]
+  properties:
+    next: {$ref: '#/components/schemas/Node'}
+`
+
+func TestMergeAllOf(t *testing.T) {
+	parsed, err := openapi.Parse([]byte(compositionSchemas))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newGenerator(openapi.Document{"components": map[string]interface{}{"schemas": map[string]interface{}(parsed)}}, Options{})
+	merged, discriminator := g.mergeAllOf(parsed["Dog"].(map[string]interface{}))
+
+	properties, _ := merged["properties"].(map[string]interface{})
+	for _, name := range []string{"petType", "name", "bark"} {
+		if properties[name] == nil {
+			t.Errorf("properties = %v, want %s", properties, name)
+		}
+	}
+	if required := requiredSet(merged); !required["petType"] || !required["bark"] || len(required) != 2 {
+		t.Errorf("required = %v, want petType and bark", merged["required"])
+	}
+	if merged["type"] != "object" {
+		t.Errorf("type = %v, want object", merged["type"])
+	}
+	if discriminator["propertyName"] != "petType" {
+		t.Errorf("discriminator = %v, want the one of Pet", discriminator)
+	}
+}
+
+func TestGenerateComposition(t *testing.T) {
+	value, _ := generateSchema(t, compositionSchemas, "Dog", Options{Seed: 1})
+	dog := value.(map[string]interface{})
+	if _, ok := dog["bark"].(bool); !ok || dog["petType"] != "dog" {
+		t.Errorf("dog = %v, want bark and the petType mapped to dog", dog)
+	}
+
+	// Every oneOf alternative sets the discriminator to its mapping key
+	seen := make(map[interface{}]bool)
+	for seed := int64(1); seed <= 20; seed++ {
+		value, _ := generateSchema(t, compositionSchemas, "AnyPet", Options{Seed: seed})
+		pet := value.(map[string]interface{})
+		seen[pet["petType"]] = true
+		switch pet["petType"] {
+		case "dog":
+			if _, ok := pet["bark"].(bool); !ok {
+				t.Errorf("dog = %v, want bark", pet)
+			}
+		case "cat":
+			if lives, ok := pet["lives"].(int64); !ok || lives < 1 || lives > 9 {
+				t.Errorf("cat = %v, want lives between 1 and 9", pet)
+			}
+		default:
+			t.Errorf("petType = %v, want dog or cat", pet["petType"])
+		}
+	}
+	if !seen["dog"] || !seen["cat"] {
+		t.Errorf("alternatives generated = %v, want both", seen)
+	}
+}
+
+func TestGenerateMapsAndNullable(t *testing.T) {
+	value, _ := generateSchema(t, compositionSchemas, "Scores", Options{Seed: 1})
+	scores, ok := value.(map[string]interface{})
+	if !ok || len(scores) == 0 || len(scores) > 3 {
+		t.Fatalf("scores = %v, want 1 to 3 entries", value)
+	}
+	for key, score := range scores {
+		if n, ok := score.(int64); !ok || n < 1 || n > 5 {
+			t.Errorf("score %s = %v, want an integer between 1 and 5", key, score)
+		}
+	}
+
+	// Nullable types show the shape of the value rather than null
+	if value, _ := generateSchema(t, compositionSchemas, "Nickname", Options{Seed: 1}); value == nil {
+		t.Error("nickname = nil, want a string")
+	}
+
+	// A nullable schema ends its own recursion with null
+	value, _ = generateSchema(t, compositionSchemas, "Node", Options{Seed: 1})
+	node, ok := value.(map[string]interface{})
+	if !ok || node["next"] != nil {
+		t.Errorf("node = %v, want next set to null", value)
+	}
+	if _, ok := node["next"]; !ok {
+		t.Errorf("node = %v, want the required next kept", value)
+	}
+}
+
+func TestGenerateRespectsConstraints(t *testing.T) {
+	integer := func(check func(int64) bool) func(interface{}) bool {
+		return func(value interface{}) bool {
+			n, ok := value.(int64)
+			return ok && check(n)
+		}
+	}
+	number := func(check func(float64) bool) func(interface{}) bool {
+		return func(value interface{}) bool {
+			n, ok := value.(float64)
+			return ok && check(n)
+		}
+	}
+	str := func(check func(string) bool) func(interface{}) bool {
+		return func(value interface{}) bool {
+			s, ok := value.(string)
+			return ok && check(s)
+		}
+	}
+	length := func(s string) int { return len([]rune(s)) }
+
+	tests := []struct {
+		name   string
+		schema string
+		valid  func(interface{}) bool
+	}{
+		{"integer bounds", "{type: integer, minimum: 10, maximum: 20}", integer(func(n int64) bool { return n >= 10 && n <= 20 })},
+		{"integer exclusive bounds 3.0", "{type: integer, minimum: 10, maximum: 12, exclusiveMinimum: true, exclusiveMaximum: true}", integer(func(n int64) bool { return n == 11 })},
+		{"integer exclusive bounds 3.1", "{type: integer, exclusiveMinimum: 5, exclusiveMaximum: 8}", integer(func(n int64) bool { return n > 5 && n < 8 })},
+		{"integer maximum only", "{type: integer, maximum: -5}", integer(func(n int64) bool { return n <= -5 })},
+		{"integer multipleOf", "{type: integer, minimum: 1, maximum: 100, multipleOf: 5}", integer(func(n int64) bool { return n >= 1 && n <= 100 && n%5 == 0 })},
+		{"number bounds", "{type: number, minimum: 0.5, maximum: 1.5}", number(func(n float64) bool { return n >= 0.5 && n <= 1.5 })},
+		{"number exclusive bounds", "{type: number, exclusiveMinimum: 0, maximum: 1}", number(func(n float64) bool { return n > 0 && n <= 1 })},
+		{"number multipleOf", "{type: number, minimum: 1, maximum: 2, multipleOf: 0.25}", number(func(n float64) bool {
+			return n >= 1 && n <= 2 && math.Abs(n/0.25-math.Round(n/0.25)) < 1e-9
+		})},
+		{"minLength", "{type: string, minLength: 30}", str(func(s string) bool { return length(s) >= 30 })},
+		{"maxLength", "{type: string, maxLength: 3}", str(func(s string) bool { return length(s) <= 3 })},
+		{"exact length", "{type: string, minLength: 5, maxLength: 5}", str(func(s string) bool { return length(s) == 5 })},
+		{"pattern", `{type: string, pattern: '^[A-Z]{3}-[0-9]{4}$'}`, str(regexp.MustCompile(`^[A-Z]{3}-[0-9]{4}$`).MatchString)},
+		{"uuid", "{type: string, format: uuid}", str(regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`).MatchString)},
+		{"email", "{type: string, format: email}", str(func(s string) bool {
+			address, err := mail.ParseAddress(s)
+			return err == nil && address.Address == s
+		})},
+		{"date-time", "{type: string, format: date-time}", str(func(s string) bool {
+			_, err := time.Parse(time.RFC3339, s)
+			return err == nil
+		})},
+		{"date", "{type: string, format: date}", str(func(s string) bool {
+			_, err := time.Parse("2006-01-02", s)
+			return err == nil
+		})},
+		{"ipv4", "{type: string, format: ipv4}", str(func(s string) bool {
+			ip := net.ParseIP(s)
+			return ip != nil && ip.To4() != nil
+		})},
+		{"uri", "{type: string, format: uri}", str(func(s string) bool {
+			u, err := url.Parse(s)
+			return err == nil && u.IsAbs()
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := openapi.Parse([]byte(tt.schema))
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := newGenerator(openapi.Document{"openapi": "3.1.0"}, Options{})
+			for seed := int64(1); seed <= 50; seed++ {
+				g.seed = seed
+				g.reseed(tt.name)
+				if value := g.generate(schema, ""); !tt.valid(value) {
+					t.Fatalf("seed %d generated %#v for %s", seed, value, tt.schema)
+				}
+			}
+		})
+	}
+}
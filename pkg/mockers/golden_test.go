@@ -0,0 +1,64 @@
+package mockers
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update rewrites the golden files with the current output: go test ./pkg/mockers -update
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestMockOpenAPISpecGolden(t *testing.T) {
+	seed := SeedFor("golden")
+	tests := []struct {
+		name   string
+		input  string
+		golden string
+		opts   Options
+	}{
+		{"openapi3", "store.yaml", "store.golden.yaml", Options{Seed: seed}},
+		{"swagger2", "petstore-swagger2.yaml", "petstore-swagger2.golden.yaml", Options{Seed: seed}},
+		{"locale", "store.yaml", "store-fr.golden.yaml", Options{Seed: seed, Locale: "fr"}},
+		{"consistent", "store.yaml", "store-consistent.golden.yaml", Options{Seed: seed, Consistent: true}},
+		{"replace", "store.yaml", "store-replace.golden.yaml", Options{Seed: seed, ExamplePolicy: ExamplePolicyReplace, MaxArrayLength: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := MockOpenAPISpec(string(input), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []byte(result.Content)
+
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("missing golden file, run go test with -update: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s, run go test with -update if the change is expected", golden)
+			}
+
+			// The same seed must give the same bytes within a run too
+			again, err := MockOpenAPISpec(string(input), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.Content != result.Content {
+				t.Error("two runs with the same seed differ")
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package mockers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// Errors of GenerateResponse for requests that match no declared operation
+var (
+	ErrPathNotDeclared   = errors.New("path is not declared")
+	ErrMethodNotDeclared = errors.New("method is not declared")
+)
+
+// ResponseExample is a response generated for a live mock request
+type ResponseExample struct {
+	StatusCode int
+	// MediaType is the Content-Type of the body, including parameters such as the multipart boundary
+	MediaType string
+	// Value is the example before serialization
+	Value interface{}
+	// Body is nil when the response has no content
+	Body    []byte
+	Headers map[string]interface{}
+}
+
+// MockRequest describes a request received by the live mock server
+type MockRequest struct {
+	// PathKey is the path template matching the request, such as /pets/{id}
+	PathKey string
+	Method  string
+	// Accept is the Accept header of the request
+	Accept string
+	// PathParams holds the raw values of the path template parameters
+	PathParams map[string]string
+	// Body is the decoded JSON body, nil when there is none
+	Body interface{}
+}
+
+// GenerateResponse builds the response of an operation for a live mock request.
+// The lowest declared 2xx response is used, falling back to the default response,
+// and its content is serialized in the media type that best matches the Accept header.
+// Author examples are returned as written unless the example policy replaces them.
+// In consistent mode, generated responses echo the body and path parameters of the request.
+func GenerateResponse(doc openapi.Document, req MockRequest, opts Options) (example *ResponseExample, err error) {
+	// A malformed schema must fail the request, not the server
+	defer func() {
+		if r := recover(); r != nil {
+			example, err = nil, fmt.Errorf("response generation failed: %v", r)
+		}
+	}()
+
+	pathKey, method := req.PathKey, req.Method
+	operation, err := lookupOperation(doc, pathKey, method)
+	if err != nil {
+		return nil, err
+	}
+
+	responses, _ := operation["responses"].(map[string]interface{})
+	statusKey, statusCode := selectResponse(responses)
+	if statusKey == "" {
+		return &ResponseExample{StatusCode: 204}, nil
+	}
+
+	gen := newGenerator(doc, opts)
+	response, _ := responses[statusKey].(map[string]interface{})
+	if ref, ok := response["$ref"].(string); ok {
+		response, _ = openapi.Resolve(doc, ref)
+	}
+
+	location := fmt.Sprintf("live %s %s %s", strings.ToUpper(method), pathKey, statusKey)
+	example = &ResponseExample{StatusCode: statusCode, Headers: make(map[string]interface{})}
+
+	if content, ok := response["content"].(map[string]interface{}); ok && len(content) > 0 {
+		mediaTypeName := negotiateMediaType(content, req.Accept)
+		mediaType, _ := content[mediaTypeName].(map[string]interface{})
+		var request map[string]interface{}
+		if gen.opts.Consistent && statusCode >= 200 && statusCode < 300 {
+			request = gen.liveRequestValues(doc, req, operation)
+		}
+		gen.direction = directionResponse
+		example.Value, example.Body, example.MediaType, err = gen.encodeBody(location+" "+mediaTypeName, mediaTypeName, mediaType, request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	headers, _ := response["headers"].(map[string]interface{})
+	for _, name := range sortedKeys(headers) {
+		header, ok := headers[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if ref, ok := header["$ref"].(string); ok {
+			header, _ = openapi.Resolve(doc, ref)
+		}
+		if value, ok := header["example"]; ok && opts.ExamplePolicy != ExamplePolicyReplace {
+			example.Headers[name] = value
+			continue
+		}
+		if schema, ok := header["schema"].(map[string]interface{}); ok {
+			example.Headers[name] = gen.generateValid(location+" header "+name, schema, name)
+		}
+	}
+
+	return example, nil
+}
+
+// RequestExample is a request body generated for a callback or webhook
+type RequestExample struct {
+	// MediaType is the Content-Type of the body, including parameters such as the multipart boundary
+	MediaType string
+	// Value is the body before serialization
+	Value interface{}
+	// Body is nil when the operation has no request body
+	Body []byte
+}
+
+// GenerateRequest builds the request body of an operation sent by the API, such as a callback or a webhook.
+// The location identifies the operation, for instance "webhook newPet POST", and keeps the values reproducible.
+func GenerateRequest(doc openapi.Document, operation map[string]interface{}, location string, opts Options) (example *RequestExample, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			example, err = nil, fmt.Errorf("request generation failed: %v", r)
+		}
+	}()
+
+	example = &RequestExample{}
+
+	body, _ := operation["requestBody"].(map[string]interface{})
+	if ref, ok := body["$ref"].(string); ok {
+		body, _ = openapi.Resolve(doc, ref)
+	}
+	content, ok := body["content"].(map[string]interface{})
+	if !ok || len(content) == 0 {
+		return example, nil
+	}
+
+	gen := newGenerator(doc, opts)
+	gen.direction = directionRequest
+	mediaTypeName := preferredMediaType(content)
+	mediaType, _ := content[mediaTypeName].(map[string]interface{})
+
+	example.Value, example.Body, example.MediaType, err = gen.encodeBody(location+" "+mediaTypeName, mediaTypeName, mediaType, nil)
+	if err != nil {
+		return nil, err
+	}
+	return example, nil
+}
+
+// GenerateParameters returns values for the required parameters of an operation, keyed by
+// location then name, such as {"path": {"petId": 42}}. Author examples are used when present.
+func GenerateParameters(doc openapi.Document, pathKey string, operation map[string]interface{}, location string, opts Options) map[string]map[string]interface{} {
+	gen := newGenerator(doc, opts)
+	gen.direction = directionRequest
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	pathItem, _ := paths[pathKey].(map[string]interface{})
+
+	values := make(map[string]map[string]interface{})
+	// Operation parameters override the path ones with the same name and location
+	for _, raw := range append(listOf(pathItem["parameters"]), listOf(operation["parameters"])...) {
+		param, ok := raw.(map[string]interface{})
+		if ref, isRef := param["$ref"].(string); isRef {
+			param, ok = openapi.Resolve(doc, ref)
+		}
+		if !ok {
+			continue
+		}
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if required, _ := param["required"].(bool); !required && in != "path" {
+			continue
+		}
+
+		value := exampleOf(param)
+		if value == nil {
+			schema, ok := param["schema"].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			fieldName := name
+			if in == "path" {
+				fieldName = pathEntityParameter(pathKey, name)
+			}
+			value = gen.generateValid(fmt.Sprintf("%s %s parameter %s", location, in, name), schema, fieldName)
+		}
+		if values[in] == nil {
+			values[in] = make(map[string]interface{})
+		}
+		values[in][name] = value
+	}
+	return values
+}
+
+// encodeBody returns the value and serialized body of a media type, with the content type to send.
+// Author examples of non-JSON media types are usually written serialized already, so strings are sent as they are.
+func (g *generator) encodeBody(location, mediaTypeName string, mediaType, request map[string]interface{}) (interface{}, []byte, string, error) {
+	value, authored := g.mediaTypeValue(location, mediaTypeName, mediaType)
+	schema, _ := mediaTypeSchema(mediaTypeName, mediaType)
+	if !authored && request != nil {
+		value = g.echo(schema, value, request)
+	}
+	if value == nil {
+		return nil, nil, mediaTypeName, nil
+	}
+
+	if text, ok := value.(string); ok && authored && kindOf(mediaTypeName) != mediaJSON {
+		return value, []byte(text), mediaTypeName, nil
+	}
+
+	encoding, _ := mediaType["encoding"].(map[string]interface{})
+	body, contentType, err := g.encode(mediaTypeName, schema, value, encoding)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("cannot encode body as %s: %v", mediaTypeName, err)
+	}
+	return value, body, contentType, nil
+}
+
+// mediaTypeValue returns the author example of a media type, or generates one from its schema
+// in the current direction. It reports whether the value was written by the author.
+func (g *generator) mediaTypeValue(location, mediaTypeName string, mediaType map[string]interface{}) (interface{}, bool) {
+	if g.opts.ExamplePolicy != ExamplePolicyReplace {
+		if value, ok := mediaType["example"]; ok {
+			return value, true
+		}
+		if examples, ok := mediaType["examples"].(map[string]interface{}); ok {
+			for _, key := range sortedKeys(examples) {
+				if key == autoExampleName {
+					continue
+				}
+				if entry, ok := examples[key].(map[string]interface{}); ok {
+					if value, ok := entry["value"]; ok {
+						return value, true
+					}
+				}
+			}
+		}
+	}
+
+	schema, ok := mediaTypeSchema(mediaTypeName, mediaType)
+	if !ok {
+		return nil, false
+	}
+	return g.generateValid(location, schema, ""), false
+}
+
+// liveRequestValues collects the body and path parameters of a live request.
+// Path parameters are converted to the type declared by their schema.
+func (g *generator) liveRequestValues(doc openapi.Document, req MockRequest, operation map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{})
+	if body, ok := req.Body.(map[string]interface{}); ok {
+		for key, value := range body {
+			values[key] = value
+		}
+	}
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	pathItem, _ := paths[req.PathKey].(map[string]interface{})
+	for _, raw := range append(listOf(pathItem["parameters"]), listOf(operation["parameters"])...) {
+		param, ok := raw.(map[string]interface{})
+		if ref, isRef := param["$ref"].(string); isRef {
+			param, ok = openapi.Resolve(doc, ref)
+		}
+		if !ok || param["in"] != "path" {
+			continue
+		}
+		name, _ := param["name"].(string)
+		rawValue, ok := req.PathParams[name]
+		if !ok {
+			continue
+		}
+		schema, _ := param["schema"].(map[string]interface{})
+		value := g.parseParameter(schema, rawValue)
+		values[name] = value
+		if strings.HasSuffix(req.PathKey, "{"+name+"}") {
+			if _, ok := values["id"]; !ok {
+				values["id"] = value
+			}
+		}
+	}
+	return values
+}
+
+// parseParameter converts a raw parameter value to the type of its schema
+func (g *generator) parseParameter(schema map[string]interface{}, raw string) interface{} {
+	resolved, _ := g.resolve(schema)
+	switch schemaType(resolved) {
+	case "integer":
+		if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return v
+		}
+	case "number":
+		if v, err := strconv.ParseFloat(raw, 64); err == nil {
+			return v
+		}
+	case "boolean":
+		if v, err := strconv.ParseBool(raw); err == nil {
+			return v
+		}
+	}
+	return raw
+}
+
+// lookupOperation returns the operation declared for a path template and method
+func lookupOperation(doc openapi.Document, pathKey, method string) (map[string]interface{}, error) {
+	paths, _ := doc["paths"].(map[string]interface{})
+	pathItem, ok := paths[pathKey].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotDeclared, pathKey)
+	}
+	operation, ok := pathItem[strings.ToLower(method)].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s", ErrMethodNotDeclared, strings.ToUpper(method), pathKey)
+	}
+	return operation, nil
+}
+
+// selectResponse picks the response to return: the lowest 2xx, then 2XX, then default
+func selectResponse(responses map[string]interface{}) (string, int) {
+	codes := make([]int, 0, len(responses))
+	for key := range responses {
+		if code, err := strconv.Atoi(key); err == nil {
+			codes = append(codes, code)
+		}
+	}
+	sort.Ints(codes)
+
+	for _, code := range codes {
+		if code >= 200 && code < 300 {
+			return strconv.Itoa(code), code
+		}
+	}
+	if _, ok := responses["2XX"]; ok {
+		return "2XX", 200
+	}
+	if _, ok := responses["default"]; ok {
+		return "default", 200
+	}
+	if len(codes) > 0 {
+		return strconv.Itoa(codes[0]), codes[0]
+	}
+	return "", 0
+}
+
+// negotiateMediaType picks the declared media type with the highest quality in the Accept header.
+// It falls back to the preferred media type when nothing matches.
+func negotiateMediaType(content map[string]interface{}, accept string) string {
+	best, bestWeight := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		pattern := strings.ToLower(strings.TrimSpace(fields[0]))
+		if pattern == "" {
+			continue
+		}
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				fmt.Sscanf(strings.TrimPrefix(param, "q="), "%g", &weight)
+			}
+		}
+		if weight <= bestWeight {
+			continue
+		}
+		// Wildcards keep the preferred media type, exact types win over ranges
+		if pattern == "*/*" {
+			best, bestWeight = preferredMediaType(content), weight
+			continue
+		}
+		for _, key := range sortedKeys(content) {
+			mediaType := strings.ToLower(key)
+			if i := strings.Index(mediaType, ";"); i >= 0 {
+				mediaType = strings.TrimSpace(mediaType[:i])
+			}
+			if mediaType == pattern || (strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*"))) {
+				best, bestWeight = key, weight
+				break
+			}
+		}
+	}
+	if best == "" {
+		return preferredMediaType(content)
+	}
+	return best
+}
+
+// preferredMediaType picks the JSON media type when available, the first declared one otherwise
+func preferredMediaType(content map[string]interface{}) string {
+	keys := sortedKeys(content)
+	for _, key := range keys {
+		if key == "application/json" || strings.HasSuffix(key, "+json") {
+			return key
+		}
+	}
+	return keys[0]
+}
@@ -0,0 +1,78 @@
+package mockers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v6"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+const liveSpec = `
+openapi: 3.0.3
+info: {title: Live, version: "1"}
+paths:
+  /pets:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Pet'}
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, chip]
+      properties:
+        id: {type: integer}
+        chip: {type: string, format: chip}
+`
+
+func TestLiveGenerationRecovers(t *testing.T) {
+	doc, err := openapi.Parse([]byte(liveSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	operation, _ := dig(doc, "paths", "/pets", "post").(map[string]interface{})
+	opts := Options{FormatGenerators: map[string]Generator{
+		"chip": GeneratorFunc(func(*gofakeit.Faker, map[string]interface{}) (interface{}, error) {
+			panic("chip reader unplugged")
+		}),
+	}}
+
+	tests := []struct {
+		name     string
+		generate func(Options) (bool, error)
+		wantErr  string
+	}{
+		{"response", func(opts Options) (bool, error) {
+			example, err := GenerateResponse(doc, MockRequest{Method: "post", PathKey: "/pets"}, opts)
+			return example != nil, err
+		}, "response generation failed: chip reader unplugged"},
+		{"request", func(opts Options) (bool, error) {
+			example, err := GenerateRequest(doc, operation, "webhook pets POST", opts)
+			return example != nil, err
+		}, "request generation failed: chip reader unplugged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.generate(Options{}); err != nil {
+				t.Fatalf("generation without the failing generator: %v", err)
+			}
+			generated, err := tt.generate(opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+			if generated {
+				t.Error("got an example along with the error")
+			}
+		})
+	}
+}
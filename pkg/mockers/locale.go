@@ -0,0 +1,216 @@
+package mockers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is used when no locale, or an unsupported one, is requested
+const DefaultLocale = "en"
+
+// localeCity pairs a city with a postal code prefix so addresses stay plausible
+type localeCity struct {
+	name       string
+	postalCode string
+}
+
+// localeData holds the values used to generate names, addresses and text in a language
+type localeData struct {
+	firstNames  []string
+	lastNames   []string
+	streets     []string
+	cities      []localeCity
+	country     string
+	phoneFormat string
+	words       []string
+
+	// formatStreet builds the street line from a number and a street name
+	formatStreet func(number int, street string) string
+}
+
+var locales = map[string]*localeData{
+	"fr": {
+		firstNames: []string{"Camille", "Léa", "Manon", "Chloé", "Inès", "Juliette", "Louise", "Emma",
+			"Lucas", "Hugo", "Louis", "Gabriel", "Arthur", "Jules", "Théo", "Raphaël", "Nathan", "Mathis"},
+		lastNames: []string{"Martin", "Bernard", "Dubois", "Thomas", "Robert", "Richard", "Petit", "Durand",
+			"Leroy", "Moreau", "Simon", "Laurent", "Lefèvre", "Michel", "Garcia", "David", "Bertrand", "Roux"},
+		streets: []string{"rue de la Paix", "avenue Victor Hugo", "boulevard Saint-Michel", "rue du Faubourg Saint-Honoré",
+			"rue de la République", "place de la Mairie", "chemin des Vignes", "allée des Tilleuls", "rue Jean Jaurès"},
+		cities: []localeCity{
+			{"Paris", "750"}, {"Lyon", "690"}, {"Marseille", "130"}, {"Toulouse", "310"}, {"Nantes", "440"},
+			{"Bordeaux", "330"}, {"Lille", "590"}, {"Strasbourg", "670"}, {"Rennes", "350"}, {"Montpellier", "340"},
+		},
+		country:     "France",
+		phoneFormat: "+33 6 ## ## ## ##",
+		words: []string{"le", "la", "un", "une", "projet", "client", "commande", "service", "équipe", "produit",
+			"rapide", "simple", "nouveau", "grand", "petit", "avec", "pour", "dans", "sans", "toujours",
+			"livraison", "facture", "compte", "adresse", "magasin", "prix", "qualité", "offre", "semaine", "jour"},
+		formatStreet: func(number int, street string) string {
+			return fmt.Sprintf("%d %s", number, street)
+		},
+	},
+	"de": {
+		firstNames: []string{"Anna", "Lena", "Lea", "Hannah", "Mia", "Laura", "Sophie", "Marie",
+			"Lukas", "Leon", "Felix", "Jonas", "Maximilian", "Paul", "Finn", "Elias", "Noah", "Tim"},
+		lastNames: []string{"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker",
+			"Schulz", "Hoffmann", "Schäfer", "Koch", "Bauer", "Richter", "Klein", "Wolf", "Schröder", "Neumann"},
+		streets: []string{"Hauptstraße", "Schulstraße", "Bahnhofstraße", "Gartenstraße", "Dorfstraße",
+			"Bergstraße", "Lindenstraße", "Kirchweg", "Goethestraße", "Am Markt"},
+		cities: []localeCity{
+			{"Berlin", "101"}, {"Hamburg", "200"}, {"München", "803"}, {"Köln", "506"}, {"Frankfurt am Main", "603"},
+			{"Stuttgart", "701"}, {"Düsseldorf", "402"}, {"Leipzig", "041"}, {"Dresden", "010"}, {"Hannover", "301"},
+		},
+		country:     "Deutschland",
+		phoneFormat: "+49 30 ########",
+		words: []string{"der", "die", "das", "ein", "eine", "Projekt", "Kunde", "Bestellung", "Dienst", "Team",
+			"Produkt", "schnell", "einfach", "neu", "groß", "klein", "mit", "für", "ohne", "immer",
+			"Lieferung", "Rechnung", "Konto", "Adresse", "Geschäft", "Preis", "Qualität", "Angebot", "Woche", "Tag"},
+		formatStreet: func(number int, street string) string {
+			return fmt.Sprintf("%s %d", street, number)
+		},
+	},
+}
+
+// SupportedLocales lists the languages the mocker can generate data for
+func SupportedLocales() []string {
+	return append([]string{DefaultLocale}, sortedLocaleNames()...)
+}
+
+func sortedLocaleNames() []string {
+	names := make([]string, 0, len(locales))
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NormalizeLocale reduces a language tag such as "fr-FR" to a supported locale.
+// It reports false when the language is not supported.
+func NormalizeLocale(tag string) (string, bool) {
+	language := strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(language, "-_"); i >= 0 {
+		language = language[:i]
+	}
+	if language == DefaultLocale {
+		return DefaultLocale, true
+	}
+	if _, ok := locales[language]; ok {
+		return language, true
+	}
+	return "", false
+}
+
+// MatchAcceptLanguage picks the best supported locale from an Accept-Language header
+func MatchAcceptLanguage(header string) (string, bool) {
+	bestLocale, bestWeight := "", -1.0
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		locale, ok := NormalizeLocale(fields[0])
+		if !ok {
+			continue
+		}
+		weight := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				fmt.Sscanf(strings.TrimPrefix(param, "q="), "%g", &weight)
+			}
+		}
+		if weight > bestWeight {
+			bestLocale, bestWeight = locale, weight
+		}
+	}
+	return bestLocale, bestLocale != "" && bestWeight > 0
+}
+
+// The helpers below fall back to the English gofakeit data when no locale data is set
+
+func (g *generator) firstName() string {
+	if g.locale == nil {
+		return g.faker.FirstName()
+	}
+	return g.pick(g.locale.firstNames)
+}
+
+func (g *generator) lastName() string {
+	if g.locale == nil {
+		return g.faker.LastName()
+	}
+	return g.pick(g.locale.lastNames)
+}
+
+func (g *generator) fullName() string {
+	if g.locale == nil {
+		return g.faker.Name()
+	}
+	return g.firstName() + " " + g.lastName()
+}
+
+func (g *generator) city() string {
+	if g.locale == nil {
+		return g.faker.City()
+	}
+	return g.locale.cities[g.faker.Number(0, len(g.locale.cities)-1)].name
+}
+
+func (g *generator) country() string {
+	if g.locale == nil {
+		return g.faker.Country()
+	}
+	return g.locale.country
+}
+
+func (g *generator) phone() string {
+	if g.locale == nil {
+		return g.faker.Phone()
+	}
+	return g.faker.Numerify(g.locale.phoneFormat)
+}
+
+func (g *generator) postalCode() string {
+	if g.locale == nil {
+		return g.faker.Zip()
+	}
+	city := g.locale.cities[g.faker.Number(0, len(g.locale.cities)-1)]
+	return city.postalCode + g.faker.Numerify("##")
+}
+
+func (g *generator) street() string {
+	if g.locale == nil {
+		return g.faker.Street()
+	}
+	return g.locale.formatStreet(g.faker.Number(1, 120), g.pick(g.locale.streets))
+}
+
+func (g *generator) address() string {
+	if g.locale == nil {
+		return g.faker.Address().Address
+	}
+	city := g.locale.cities[g.faker.Number(0, len(g.locale.cities)-1)]
+	return fmt.Sprintf("%s, %s%s %s", g.street(), city.postalCode, g.faker.Numerify("##"), city.name)
+}
+
+func (g *generator) sentence(words int) string {
+	if g.locale == nil {
+		return g.faker.Sentence(words)
+	}
+	parts := make([]string, words)
+	for i := range parts {
+		parts[i] = g.pick(g.locale.words)
+	}
+	text := []rune(strings.Join(parts, " "))
+	return strings.ToUpper(string(text[:1])) + string(text[1:]) + "."
+}
+
+func (g *generator) word() string {
+	if g.locale == nil {
+		return g.faker.Word()
+	}
+	return g.pick(g.locale.words)
+}
+
+func (g *generator) pick(values []string) string {
+	return values[g.faker.Number(0, len(values)-1)]
+}
@@ -0,0 +1,435 @@
+package mockers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// multipartBoundary is fixed so multipart examples stay reproducible
+const multipartBoundary = "redokube-boundary"
+
+// mediaKind groups media types sharing the same serialization
+type mediaKind int
+
+const (
+	mediaJSON mediaKind = iota
+	mediaXML
+	mediaForm
+	mediaMultipart
+	mediaText
+	mediaCSV
+	mediaBinary
+)
+
+// kindOf classifies a media type, unknown types are treated as JSON
+func kindOf(mediaType string) mediaKind {
+	base, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		base = strings.ToLower(strings.TrimSpace(mediaType))
+	}
+
+	switch {
+	case base == "application/json" || strings.HasSuffix(base, "+json"):
+		return mediaJSON
+	case base == "application/xml" || base == "text/xml" || strings.HasSuffix(base, "+xml"):
+		return mediaXML
+	case base == "application/x-www-form-urlencoded":
+		return mediaForm
+	case strings.HasPrefix(base, "multipart/"):
+		return mediaMultipart
+	case base == "text/csv":
+		return mediaCSV
+	case strings.HasPrefix(base, "text/"):
+		return mediaText
+	case base == "application/octet-stream" || base == "application/pdf" || base == "application/zip" ||
+		strings.HasPrefix(base, "image/") || strings.HasPrefix(base, "audio/") || strings.HasPrefix(base, "video/"):
+		return mediaBinary
+	}
+	return mediaJSON
+}
+
+// defaultMediaSchema describes the content of text and binary media types declared without a schema
+func defaultMediaSchema(mediaType string) (map[string]interface{}, bool) {
+	switch kindOf(mediaType) {
+	case mediaText:
+		return map[string]interface{}{"type": "string"}, true
+	case mediaBinary:
+		return map[string]interface{}{"type": "string", "format": "binary"}, true
+	}
+	return nil, false
+}
+
+// encode serializes a generated value in the wire format of a media type.
+// It returns the body and the content type to send, which carries the multipart boundary.
+// The encoding map is the Encoding Object of the media type, used by form and multipart bodies.
+func (g *generator) encode(mediaType string, schema map[string]interface{}, value interface{}, encoding map[string]interface{}) ([]byte, string, error) {
+	switch kindOf(mediaType) {
+	case mediaXML:
+		return g.encodeXML(schema, value), mediaType, nil
+	case mediaForm:
+		body, err := g.encodeForm(value)
+		return body, mediaType, err
+	case mediaMultipart:
+		return g.encodeMultipart(mediaType, schema, value, encoding)
+	case mediaCSV:
+		body, err := encodeCSV(value)
+		return body, mediaType, err
+	case mediaText, mediaBinary:
+		if s, ok := value.(string); ok {
+			return []byte(s), mediaType, nil
+		}
+		if isScalar(value) {
+			return []byte(fmt.Sprint(value)), mediaType, nil
+		}
+	}
+	body, err := json.MarshalIndent(value, "", "  ")
+	return body, mediaType, err
+}
+
+// exampleValue turns a generated value into the example stored in the spec.
+// JSON values are kept as they are, other media types get their serialized text.
+func (g *generator) exampleValue(mediaType string, schema map[string]interface{}, value interface{}, encoding map[string]interface{}) interface{} {
+	if kindOf(mediaType) == mediaJSON {
+		return value
+	}
+	body, _, err := g.encode(mediaType, schema, value, encoding)
+	if err != nil {
+		g.warn("cannot encode example as %s: %v", mediaType, err)
+		return value
+	}
+	// Multipart line breaks are CRLF on the wire, plain ones read better in the docs
+	if kindOf(mediaType) == mediaMultipart {
+		return strings.ReplaceAll(string(body), "\r\n", "\n")
+	}
+	return string(body)
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+// scalarText formats a primitive value, complex values are written as JSON
+func scalarText(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if isScalar(value) {
+		return fmt.Sprint(value)
+	}
+	body, _ := json.Marshal(value)
+	return string(body)
+}
+
+// encodeXML writes a value as an XML document following the xml hints of its schema
+func (g *generator) encodeXML(schema map[string]interface{}, value interface{}) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	resolved, refName := g.resolve(schema)
+	name := refName
+	if name == "" {
+		name = "root"
+	}
+	g.writeXMLElement(&buf, resolved, value, name, 0)
+	return buf.Bytes()
+}
+
+// xmlHints returns the xml object of a schema
+func xmlHints(schema map[string]interface{}) map[string]interface{} {
+	hints, _ := schema["xml"].(map[string]interface{})
+	return hints
+}
+
+// xmlName computes the qualified element or attribute name and the namespace declaration to add
+func xmlName(schema map[string]interface{}, fallback string) (string, string) {
+	hints := xmlHints(schema)
+	name := fallback
+	if n, ok := hints["name"].(string); ok && n != "" {
+		name = n
+	}
+	prefix, _ := hints["prefix"].(string)
+	namespace, _ := hints["namespace"].(string)
+
+	declaration := ""
+	if namespace != "" {
+		if prefix != "" {
+			declaration = fmt.Sprintf(` xmlns:%s="%s"`, prefix, escapeXML(namespace))
+		} else {
+			declaration = fmt.Sprintf(` xmlns="%s"`, escapeXML(namespace))
+		}
+	}
+	if prefix != "" {
+		name = prefix + ":" + name
+	}
+	return name, declaration
+}
+
+func (g *generator) writeXMLElement(buf *bytes.Buffer, schema map[string]interface{}, value interface{}, fallback string, depth int) {
+	if schema == nil {
+		schema = map[string]interface{}{}
+	}
+	if _, ok := schema["allOf"]; ok {
+		schema, _ = g.mergeAllOf(schema)
+	}
+	name, declaration := xmlName(schema, fallback)
+	indent := strings.Repeat("  ", depth)
+
+	switch v := value.(type) {
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		items, itemRef := g.resolve(items)
+		itemName := singular(fallback)
+		if itemRef != "" {
+			itemName = itemRef
+		}
+		// Unwrapped arrays repeat the item element in place of the array element,
+		// except at the root where a single element is required
+		if wrapped, _ := xmlHints(schema)["wrapped"].(bool); !wrapped && depth > 0 {
+			if _, ok := xmlHints(items)["name"]; !ok {
+				itemName = fallback
+			}
+			for _, item := range v {
+				g.writeXMLElement(buf, items, item, itemName, depth)
+			}
+			return
+		}
+		fmt.Fprintf(buf, "%s<%s%s>\n", indent, name, declaration)
+		for _, item := range v {
+			g.writeXMLElement(buf, items, item, itemName, depth+1)
+		}
+		fmt.Fprintf(buf, "%s</%s>\n", indent, name)
+
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		var attributes strings.Builder
+		var children []string
+		for _, key := range sortedKeys(v) {
+			prop, _ := props[key].(map[string]interface{})
+			prop, _ = g.resolve(prop)
+			if attribute, _ := xmlHints(prop)["attribute"].(bool); attribute && isScalar(v[key]) {
+				attrName, _ := xmlName(prop, key)
+				fmt.Fprintf(&attributes, ` %s="%s"`, attrName, escapeXML(scalarText(v[key])))
+				continue
+			}
+			children = append(children, key)
+		}
+
+		if len(children) == 0 {
+			fmt.Fprintf(buf, "%s<%s%s%s/>\n", indent, name, declaration, attributes.String())
+			return
+		}
+		fmt.Fprintf(buf, "%s<%s%s%s>\n", indent, name, declaration, attributes.String())
+		for _, key := range children {
+			prop, _ := props[key].(map[string]interface{})
+			prop, _ = g.resolve(prop)
+			g.writeXMLElement(buf, prop, v[key], key, depth+1)
+		}
+		fmt.Fprintf(buf, "%s</%s>\n", indent, name)
+
+	default:
+		fmt.Fprintf(buf, "%s<%s%s>%s</%s>\n", indent, name, declaration, escapeXML(scalarText(value)), name)
+	}
+}
+
+func escapeXML(text string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// encodeForm writes an object as application/x-www-form-urlencoded.
+// Arrays of primitives repeat their key, nested structures are sent as JSON.
+func (g *generator) encodeForm(value interface{}) ([]byte, error) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("form bodies must be objects, got %s", valueType(value))
+	}
+
+	values := url.Values{}
+	for _, key := range sortedKeys(obj) {
+		if items, ok := obj[key].([]interface{}); ok && allScalar(items) {
+			for _, item := range items {
+				values.Add(key, scalarText(item))
+			}
+			continue
+		}
+		values.Set(key, scalarText(obj[key]))
+	}
+	return []byte(values.Encode()), nil
+}
+
+func allScalar(items []interface{}) bool {
+	for _, item := range items {
+		if !isScalar(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeMultipart writes an object as multipart/form-data.
+// Binary properties become file parts, other values text or JSON parts.
+func (g *generator) encodeMultipart(mediaType string, schema map[string]interface{}, value interface{}, encoding map[string]interface{}) ([]byte, string, error) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, "", fmt.Errorf("multipart bodies must be objects, got %s", valueType(value))
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.SetBoundary(multipartBoundary); err != nil {
+		return nil, "", err
+	}
+
+	resolved, _ := g.resolve(schema)
+	if _, ok := resolved["allOf"]; ok {
+		resolved, _ = g.mergeAllOf(resolved)
+	}
+	props, _ := resolved["properties"].(map[string]interface{})
+
+	for _, key := range sortedKeys(obj) {
+		prop, _ := props[key].(map[string]interface{})
+		prop, _ = g.resolve(prop)
+		contentType := ""
+		if enc, ok := encoding[key].(map[string]interface{}); ok {
+			contentType, _ = enc["contentType"].(string)
+		}
+
+		parts := []interface{}{obj[key]}
+		itemSchema := prop
+		if items, ok := obj[key].([]interface{}); ok && isBinary(arrayItems(g, prop)) {
+			parts, itemSchema = items, arrayItems(g, prop)
+		}
+
+		for i, part := range parts {
+			header := make(textproto.MIMEHeader)
+			var body []byte
+			switch {
+			case isBinary(itemSchema):
+				if contentType == "" {
+					contentType = "application/octet-stream"
+				}
+				filename := key
+				if len(parts) > 1 {
+					filename = fmt.Sprintf("%s-%d", key, i+1)
+				}
+				header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s%s"`, key, filename, fileExtension(contentType)))
+				body = []byte(scalarText(part))
+			case isScalar(part):
+				header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, key))
+				body = []byte(scalarText(part))
+			default:
+				if contentType == "" {
+					contentType = "application/json"
+				}
+				header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, key))
+				body, _ = json.Marshal(part)
+			}
+			if contentType != "" {
+				header.Set("Content-Type", contentType)
+			}
+
+			w, err := writer.CreatePart(header)
+			if err != nil {
+				return nil, "", err
+			}
+			w.Write(body)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	base, params, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		base, params = "multipart/form-data", map[string]string{}
+	}
+	params["boundary"] = multipartBoundary
+	return buf.Bytes(), mime.FormatMediaType(base, params), nil
+}
+
+func arrayItems(g *generator, schema map[string]interface{}) map[string]interface{} {
+	items, _ := schema["items"].(map[string]interface{})
+	items, _ = g.resolve(items)
+	return items
+}
+
+// isBinary reports whether a schema describes file content
+func isBinary(schema map[string]interface{}) bool {
+	if schema == nil {
+		return false
+	}
+	format, _ := schema["format"].(string)
+	_, hasMediaType := schema["contentMediaType"]
+	return format == "binary" || format == "base64" || hasMediaType
+}
+
+// fileExtension picks the extension of fake file names from their content type
+func fileExtension(contentType string) string {
+	if extensions, err := mime.ExtensionsByType(contentType); err == nil && len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ".bin"
+}
+
+// encodeCSV writes arrays of objects as a table with a header row.
+// Objects give a single row and primitives a single column.
+func encodeCSV(value interface{}) ([]byte, error) {
+	rows, ok := value.([]interface{})
+	if !ok {
+		rows = []interface{}{value}
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		if obj, ok := row.(map[string]interface{}); ok {
+			for _, key := range sortedKeys(obj) {
+				if !seen[key] {
+					seen[key] = true
+					columns = append(columns, key)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if len(columns) > 0 {
+		writer.Write(columns)
+	}
+	for _, row := range rows {
+		obj, ok := row.(map[string]interface{})
+		if !ok {
+			writer.Write([]string{scalarText(row)})
+			continue
+		}
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = scalarText(obj[column])
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// mediaTypeSchema returns the schema of a media type object, or the default one of its media type
+func mediaTypeSchema(mediaTypeName string, mediaType map[string]interface{}) (map[string]interface{}, bool) {
+	if schema, ok := mediaType["schema"].(map[string]interface{}); ok {
+		return schema, true
+	}
+	return defaultMediaSchema(mediaTypeName)
+}
@@ -0,0 +1,289 @@
+package mockers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// encodeSchema generates a value of a component schema and encodes it in a media type
+func encodeSchema(t *testing.T, schemas, name, mediaType string, encoding map[string]interface{}) (interface{}, []byte, string) {
+	t.Helper()
+	parsed, err := openapi.Parse([]byte(schemas))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := openapi.Document{
+		"openapi":    "3.0.3",
+		"components": map[string]interface{}{"schemas": map[string]interface{}(parsed)},
+	}
+	g := newGenerator(doc, Options{Seed: 1})
+	g.reseed(name)
+	schema := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	value := g.generate(schema, "")
+	body, contentType, err := g.encode(mediaType, schema, value, encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value, body, contentType
+}
+
+// xmlNode is a decoded XML element
+type xmlNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []xmlNode  `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+// children returns the child elements with a local name
+func (n xmlNode) children(local string) []xmlNode {
+	var found []xmlNode
+	for _, child := range n.Children {
+		if child.XMLName.Local == local {
+			found = append(found, child)
+		}
+	}
+	return found
+}
+
+const xmlSchemas = `
+Pet:
+  type: object
+  required: [id, name, tags, photos]
+  xml: {name: pet, prefix: p, namespace: "https://example.com/pets"}
+  properties:
+    id:
+      type: integer
+      xml: {attribute: true}
+    name:
+      type: string
+      xml: {name: petName}
+    tags:
+      type: array
+      minItems: 2
+      maxItems: 3
+      xml: {wrapped: true}
+      items:
+        type: string
+        xml: {name: tag}
+    photos:
+      type: array
+      minItems: 2
+      maxItems: 3
+      items: {type: string}
+`
+
+func TestEncodeXML(t *testing.T) {
+	generated, body, _ := encodeSchema(t, xmlSchemas, "Pet", "application/xml", nil)
+	value := generated.(map[string]interface{})
+
+	var root xmlNode
+	if err := xml.Unmarshal(body, &root); err != nil {
+		t.Fatalf("%v in\n%s", err, body)
+	}
+	if root.XMLName.Local != "pet" || root.XMLName.Space != "https://example.com/pets" {
+		t.Errorf("root = %+v, want pet in https://example.com/pets", root.XMLName)
+	}
+	if !strings.Contains(string(body), "<p:pet ") {
+		t.Errorf("root element is not prefixed with p:\n%s", body)
+	}
+
+	var id string
+	for _, attr := range root.Attrs {
+		if attr.Name.Local == "id" {
+			id = attr.Value
+		}
+	}
+	if id != fmt.Sprint(value["id"]) {
+		t.Errorf("id attribute = %q, want %v", id, value["id"])
+	}
+	if len(root.children("id")) != 0 {
+		t.Error("id is written as an element as well")
+	}
+
+	if names := root.children("petName"); len(names) != 1 || names[0].Text != value["name"] {
+		t.Errorf("petName = %+v, want %v", names, value["name"])
+	}
+
+	wrapped := root.children("tags")
+	if len(wrapped) != 1 {
+		t.Fatalf("got %d tags elements, want 1 wrapping element", len(wrapped))
+	}
+	tags := value["tags"].([]interface{})
+	if items := wrapped[0].children("tag"); len(items) != len(tags) || items[0].Text != tags[0] {
+		t.Errorf("tag elements = %+v, want %v", items, tags)
+	}
+
+	photos := value["photos"].([]interface{})
+	if items := root.children("photos"); len(items) != len(photos) || items[0].Text != photos[0] {
+		t.Errorf("unwrapped photos = %+v, want one element per item of %v", items, photos)
+	}
+}
+
+const formSchemas = `
+Order:
+  type: object
+  required: [id, tags, address]
+  properties:
+    id: {type: integer}
+    tags:
+      type: array
+      minItems: 2
+      maxItems: 3
+      items: {type: string}
+    address:
+      type: object
+      required: [city]
+      properties:
+        city: {type: string}
+`
+
+func TestEncodeForm(t *testing.T) {
+	generated, body, _ := encodeSchema(t, formSchemas, "Order", "application/x-www-form-urlencoded", nil)
+	value := generated.(map[string]interface{})
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values.Get("id") != fmt.Sprint(value["id"]) {
+		t.Errorf("id = %q, want %v", values.Get("id"), value["id"])
+	}
+	tags := value["tags"].([]interface{})
+	if len(values["tags"]) != len(tags) || values["tags"][0] != tags[0] {
+		t.Errorf("tags = %v, want the key repeated for %v", values["tags"], tags)
+	}
+	var address map[string]interface{}
+	if err := json.Unmarshal([]byte(values.Get("address")), &address); err != nil {
+		t.Fatalf("address is not JSON: %v", err)
+	}
+	if !reflect.DeepEqual(address, value["address"]) {
+		t.Errorf("address = %v, want %v", address, value["address"])
+	}
+}
+
+const multipartSchemas = `
+Upload:
+  type: object
+  required: [note, avatar, attachments, metadata]
+  properties:
+    note: {type: string}
+    avatar: {type: string, format: binary}
+    attachments:
+      type: array
+      minItems: 2
+      maxItems: 2
+      items: {type: string, format: binary}
+    metadata:
+      type: object
+      required: [owner]
+      properties:
+        owner: {type: string}
+`
+
+func TestEncodeMultipart(t *testing.T) {
+	encoding := map[string]interface{}{"avatar": map[string]interface{}{"contentType": "image/png"}}
+	generated, body, contentType := encodeSchema(t, multipartSchemas, "Upload", "multipart/form-data", encoding)
+	value := generated.(map[string]interface{})
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/form-data" || params["boundary"] != multipartBoundary {
+		t.Fatalf("content type = %q, want multipart/form-data with boundary %s", contentType, multipartBoundary)
+	}
+
+	type part struct {
+		filename    string
+		contentType string
+		body        string
+	}
+	parts := make(map[string][]part)
+	reader := multipart.NewReader(strings.NewReader(string(body)), params["boundary"])
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(p)
+		parts[p.FormName()] = append(parts[p.FormName()], part{p.FileName(), p.Header.Get("Content-Type"), string(content)})
+	}
+
+	if note := parts["note"]; len(note) != 1 || note[0].filename != "" || note[0].body != value["note"] {
+		t.Errorf("note parts = %+v, want a text part with %v", note, value["note"])
+	}
+	if avatar := parts["avatar"]; len(avatar) != 1 || avatar[0].filename != "avatar.png" || avatar[0].contentType != "image/png" {
+		t.Errorf("avatar parts = %+v, want the file avatar.png of type image/png", avatar)
+	}
+	attachments := parts["attachments"]
+	if len(attachments) != 2 {
+		t.Fatalf("got %d attachments parts, want one file part per item", len(attachments))
+	}
+	for i, attachment := range attachments {
+		if !strings.HasPrefix(attachment.filename, fmt.Sprintf("attachments-%d.", i+1)) || attachment.contentType != "application/octet-stream" {
+			t.Errorf("attachment %d = %+v, want a numbered application/octet-stream file", i, attachment)
+		}
+	}
+	metadata := parts["metadata"]
+	if len(metadata) != 1 || metadata[0].contentType != "application/json" {
+		t.Fatalf("metadata parts = %+v, want a JSON part", metadata)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(metadata[0].body), &decoded); err != nil || !reflect.DeepEqual(decoded, value["metadata"]) {
+		t.Errorf("metadata = %s, want %v", metadata[0].body, value["metadata"])
+	}
+}
+
+const csvSchemas = `
+Report:
+  type: array
+  minItems: 2
+  maxItems: 4
+  items:
+    type: object
+    required: [id, name]
+    properties:
+      id: {type: integer}
+      name: {type: string}
+      score: {type: number}
+`
+
+func TestEncodeCSV(t *testing.T) {
+	generated, body, _ := encodeSchema(t, csvSchemas, "Report", "text/csv", nil)
+	rows := generated.([]interface{})
+
+	records, err := csv.NewReader(strings.NewReader(string(body))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(rows)+1 {
+		t.Fatalf("got %d records, want a header and %d rows", len(records), len(rows))
+	}
+	header := records[0]
+	if len(header) < 2 || header[0] != "id" || header[1] != "name" {
+		t.Errorf("header = %v, want the sorted property names", header)
+	}
+	for i, row := range rows {
+		obj := row.(map[string]interface{})
+		for j, column := range header {
+			if want := scalarText(obj[column]); records[i+1][j] != want {
+				t.Errorf("row %d %s = %q, want %q", i, column, records[i+1][j], want)
+			}
+		}
+	}
+}
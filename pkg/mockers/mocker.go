@@ -1,344 +1,370 @@
 package mockers
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
 
-	"github.com/brianvoe/gofakeit/v6"
-	"gopkg.in/yaml.v3"
+	"github.com/BombartSimon/redokube/pkg/converter"
+	"github.com/BombartSimon/redokube/pkg/openapi"
 )
 
-// MockOpenAPISpec adds fake examples to an OpenAPI specification
-func MockOpenAPISpec(specContent string) (string, error) {
-	var swagger map[string]interface{}
-
-	// Try parsing as JSON first
-	err := json.Unmarshal([]byte(specContent), &swagger)
-	if err != nil {
-		// If JSON parsing fails, try YAML
-		err = yaml.Unmarshal([]byte(specContent), &swagger)
-		if err != nil {
-			return "", fmt.Errorf("error parsing OpenAPI spec (neither valid JSON nor YAML): %v", err)
+// MockOpenAPISpec adds fake examples to an OpenAPI specification.
+// Swagger 2.0 documents are converted to OpenAPI 3.0 first.
+func MockOpenAPISpec(specContent string, opts Options) (result *Result, err error) {
+	// Never let a malformed schema take the operator down
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("mock generation failed: %v", r)
 		}
-	}
-
-	log.Printf("Parsed swagger: %+v", swagger)
+	}()
 
-	gofakeit.Seed(0)
-
-	// Create OpenAPI 3.0 skeleton if needed
-	openapi := make(map[string]interface{})
-
-	// Check if already OpenAPI 3.0+
-	if version, ok := swagger["openapi"].(string); ok && strings.HasPrefix(version, "3.") {
-		openapi = swagger
-	} else {
-		// Convert from Swagger 2.0 to OpenAPI 3.0
-		openapi["openapi"] = "3.1.0"
-		openapi["info"] = swagger["info"]
-
-		// Add servers if host exists
-		if host, ok := swagger["host"].(string); ok {
-			basePath := "/"
-			if bp, ok := swagger["basePath"].(string); ok {
-				basePath = bp
-			}
-			openapi["servers"] = []map[string]string{
-				{"url": fmt.Sprintf("https://%s%s", host, basePath)},
-			}
-		}
-
-		// Convert definitions to components/schemas
-		if defsRaw, ok := swagger["definitions"].(map[string]interface{}); ok {
-			openapi["components"] = map[string]interface{}{
-				"schemas": defsRaw,
-			}
-		}
+	openapiDoc, err := openapi.Parse([]byte(specContent))
+	if err != nil {
+		return nil, err
 	}
 
-	// Get definitions/schemas
-	var schemas map[string]interface{}
-	if components, ok := openapi["components"].(map[string]interface{}); ok {
-		if s, ok := components["schemas"].(map[string]interface{}); ok {
-			schemas = s
+	if openapi.IsSwagger2(openapiDoc) {
+		openapiDoc, err = converter.ConvertSwagger2(openapiDoc)
+		if err != nil {
+			return nil, err
 		}
-	} else if defsRaw, ok := swagger["definitions"].(map[string]interface{}); ok {
-		schemas = defsRaw
 	}
 
-	// Process paths
-	paths := make(map[string]interface{})
-	var rawPaths map[string]interface{}
+	m := &mocker{gen: newGenerator(openapiDoc, opts)}
 
-	if p, ok := swagger["paths"].(map[string]interface{}); ok {
-		rawPaths = p
-	} else if p, ok := openapi["paths"].(map[string]interface{}); ok {
-		rawPaths = p
-	}
-
-	if rawPaths == nil {
+	rawPaths, ok := openapiDoc["paths"].(map[string]interface{})
+	if !ok {
 		log.Println("Warning: No paths found in the OpenAPI spec")
 		rawPaths = make(map[string]interface{})
+		openapiDoc["paths"] = rawPaths
 	}
 
-	for pathKey, pathVal := range rawPaths {
+	for _, pathKey := range sortedKeys(rawPaths) {
+		pathVal := rawPaths[pathKey]
 		pathItem, ok := pathVal.(map[string]interface{})
 		if !ok {
 			log.Printf("Warning: Path item is not a map: %v", pathVal)
 			continue
 		}
 
-		newPathItem := make(map[string]interface{})
+		m.mockParameters(pathKey, pathKey, pathItem["parameters"])
 
-		for methodKey, methodVal := range pathItem {
+		for _, methodKey := range openapi.Methods {
+			methodVal, ok := pathItem[methodKey]
+			if !ok {
+				continue
+			}
 			method, ok := methodVal.(map[string]interface{})
 			if !ok {
 				log.Printf("Warning: Method is not a map: %v", methodVal)
 				continue
 			}
 
-			newMethod := make(map[string]interface{})
+			m.mockOperation(pathKey, fmt.Sprintf("%s %s", strings.ToUpper(methodKey), pathKey), method, pathItem)
+		}
+	}
 
-			// Copy description, summary, etc.
-			for k, v := range method {
-				if k != "responses" {
-					newMethod[k] = v
-				}
-			}
+	// Reusable objects referenced from operations get their examples in place
+	m.mockComponents(openapiDoc)
+
+	// Convert back to YAML
+	out, err := openapi.Marshal(openapiDoc)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Successfully generated OpenAPI examples")
+	return &Result{Content: string(out), Warnings: m.gen.warnings, InvalidExamples: m.gen.invalidExamples}, nil
+}
 
-			responses := make(map[string]interface{})
-			if resp, ok := method["responses"].(map[string]interface{}); ok {
-				for statusCode, respVal := range resp {
-					response, ok := respVal.(map[string]interface{})
-					if !ok {
-						log.Printf("Warning: Response is not a map: %v", respVal)
-						continue
-					}
-
-					newResp := make(map[string]interface{})
-					newResp["description"] = response["description"]
-
-					// Add examples for successful responses
-					if statusCode == "200" || statusCode == "201" {
-						if schema, ok := response["schema"].(map[string]interface{}); ok {
-							if ref, ok := schema["$ref"].(string); ok {
-								refName := resolveRef(ref)
-								if def, ok := schemas[refName]; ok {
-									defMap, ok := def.(map[string]interface{})
-									if !ok {
-										log.Printf("Warning: Schema definition is not a map: %v", def)
-										continue
-									}
-
-									example := generateFakeObject(defMap, schemas)
-									newResp["content"] = map[string]interface{}{
-										"application/json": map[string]interface{}{
-											"schema": map[string]interface{}{
-												"$ref": convertRefPath(ref),
-											},
-											"examples": map[string]interface{}{
-												"auto_example": map[string]interface{}{
-													"value": example,
-												},
-											},
-										},
-									}
-								}
-							}
-						}
-					} else {
-						// Generate simple example for errors
-						example := map[string]interface{}{
-							"message":   gofakeit.Sentence(5),
-							"errorCode": gofakeit.Regex(`ERR_[0-9]{3}`),
-						}
-						newResp["content"] = map[string]interface{}{
-							"application/json": map[string]interface{}{
-								"examples": map[string]interface{}{
-									"auto_example": map[string]interface{}{
-										"value": example,
-									},
-								},
-							},
-						}
-					}
-
-					responses[statusCode] = newResp
+// mocker walks a document and fills in the examples of every operation
+type mocker struct {
+	gen *generator
+
+	// request holds the values sent to the operation being mocked, echoed by its
+	// successful responses in consistent mode
+	request map[string]interface{}
+}
+
+func (m *mocker) mockOperation(pathKey, location string, operation map[string]interface{}, pathItem map[string]interface{}) {
+	m.mockParameters(pathKey, location, operation["parameters"])
+
+	if body, ok := operation["requestBody"].(map[string]interface{}); ok {
+		m.mockContent(location+" requestBody", body["content"], directionRequest)
+	}
+
+	responses, ok := operation["responses"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, statusCode := range sortedKeys(responses) {
+		response, ok := responses[statusCode].(map[string]interface{})
+		if !ok {
+			log.Printf("Warning: Response is not a map: %v", responses[statusCode])
+			continue
+		}
+		if m.gen.opts.Consistent && strings.HasPrefix(statusCode, "2") {
+			m.request = m.requestValues(pathKey, pathItem, operation)
+		}
+		m.mockResponse(location+" "+statusCode, response)
+		m.request = nil
+	}
+}
+
+// requestValues collects the examples of the request body and path parameters of an operation.
+// The parameter ending the path also stands for the id of the returned resource.
+func (m *mocker) requestValues(pathKey string, pathItem, operation map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{})
+
+	if body, ok := operation["requestBody"].(map[string]interface{}); ok {
+		if content, ok := body["content"].(map[string]interface{}); ok && len(content) > 0 {
+			mediaType, _ := content[preferredMediaType(content)].(map[string]interface{})
+			if obj, ok := exampleOf(mediaType).(map[string]interface{}); ok {
+				for key, value := range obj {
+					values[key] = value
 				}
 			}
-
-			newMethod["responses"] = responses
-			newPathItem[methodKey] = newMethod
 		}
-		paths[pathKey] = newPathItem
 	}
-	openapi["paths"] = paths
 
-	// Convert back to YAML
-	out, err := yaml.Marshal(openapi)
-	if err != nil {
-		return "", fmt.Errorf("error encoding modified OpenAPI spec: %v", err)
+	for _, raw := range append(listOf(pathItem["parameters"]), listOf(operation["parameters"])...) {
+		param, ok := raw.(map[string]interface{})
+		if ref, isRef := param["$ref"].(string); isRef {
+			param, ok = openapi.Resolve(m.gen.doc, ref)
+		}
+		if !ok || param["in"] != "path" {
+			continue
+		}
+		name, _ := param["name"].(string)
+		value := exampleOf(param)
+		if value == nil {
+			continue
+		}
+		values[name] = value
+		if strings.HasSuffix(pathKey, "{"+name+"}") {
+			if _, ok := values["id"]; !ok {
+				values["id"] = value
+			}
+		}
 	}
+	return values
+}
 
-	log.Println("Successfully generated OpenAPI examples")
-	return string(out), nil
+// exampleOf returns the example of a media type or parameter, preferring the author ones
+func exampleOf(object map[string]interface{}) interface{} {
+	if value, ok := object["example"]; ok {
+		return value
+	}
+	examples, _ := object["examples"].(map[string]interface{})
+	var generated interface{}
+	for _, key := range sortedKeys(examples) {
+		entry, ok := examples[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if key == autoExampleName {
+			generated = entry["value"]
+			continue
+		}
+		if value, ok := entry["value"]; ok {
+			return value
+		}
+	}
+	return generated
 }
 
-// Helper function to extract the reference name
-func resolveRef(ref string) string {
-	parts := strings.Split(ref, "/")
-	return parts[len(parts)-1]
+func listOf(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
 }
 
-// Convert Swagger 2.0 ref path to OpenAPI 3.0
-func convertRefPath(ref string) string {
-	if strings.HasPrefix(ref, "#/definitions/") {
-		return strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+// mockResponse generates examples for every media type and header of a response from their own schemas
+func (m *mocker) mockResponse(location string, response map[string]interface{}) {
+	if _, isRef := response["$ref"]; isRef {
+		return
+	}
+
+	m.mockContent(location, response["content"], directionResponse)
+
+	headers, ok := response["headers"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, name := range sortedKeys(headers) {
+		if header, ok := headers[name].(map[string]interface{}); ok {
+			m.mockParameter(fmt.Sprintf("%s header %s", location, name), name, header)
+		}
 	}
-	return ref
 }
 
-// Generate fake object based on schema definition
-func generateFakeObject(def map[string]interface{}, defs map[string]interface{}) map[string]interface{} {
-	props, ok := def["properties"].(map[string]interface{})
+// mockContent generates an example for every media type of a content map
+func (m *mocker) mockContent(location string, rawContent interface{}, direction direction) {
+	content, ok := rawContent.(map[string]interface{})
 	if !ok {
-		return map[string]interface{}{"example": "No properties found"}
+		return
 	}
+	for _, mediaTypeName := range sortedKeys(content) {
+		mediaType, ok := content[mediaTypeName].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		schema, ok := mediaTypeSchema(mediaTypeName, mediaType)
+		if !ok {
+			continue
+		}
 
-	result := make(map[string]interface{})
+		key := location + " " + mediaTypeName
+		encoding, _ := mediaType["encoding"].(map[string]interface{})
+		setExample(mediaType, m.gen.opts.ExamplePolicy, func() interface{} {
+			m.gen.direction = direction
+			value := m.gen.generateValid(key, schema, "")
+			if direction == directionResponse && m.request != nil {
+				value = m.gen.echo(schema, value, m.request)
+			}
+			return m.gen.exampleValue(mediaTypeName, schema, value, encoding)
+		})
+	}
+}
 
-	for key, val := range props {
-		field, ok := val.(map[string]interface{})
+// mockParameters generates examples for a list of parameters of a path
+func (m *mocker) mockParameters(pathKey, location string, rawParams interface{}) {
+	params, ok := rawParams.([]interface{})
+	if !ok {
+		return
+	}
+	for _, raw := range params {
+		param, ok := raw.(map[string]interface{})
 		if !ok {
 			continue
 		}
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		fieldName := name
+		if in == "path" {
+			fieldName = pathEntityParameter(pathKey, name)
+		}
+		m.mockParameter(fmt.Sprintf("%s %s parameter %s", location, in, name), fieldName, param)
+	}
+}
 
-		fieldType, _ := field["type"].(string)
-
-		switch fieldType {
-		case "string":
-			result[key] = generateFakeValue(key)
-		case "integer", "number":
-			result[key] = gofakeit.Number(1, 1000)
-		case "boolean":
-			result[key] = gofakeit.Bool()
-		case "array":
-			items, ok := field["items"].(map[string]interface{})
-			if !ok {
-				continue
+// mockParameter generates the example of a parameter or header object
+func (m *mocker) mockParameter(location, name string, param map[string]interface{}) {
+	if _, isRef := param["$ref"]; isRef {
+		return
+	}
+
+	// Parameters may describe their value with a content map instead of a schema
+	if _, ok := param["content"]; ok {
+		m.mockContent(location, param["content"], directionRequest)
+		return
+	}
+
+	schema, ok := param["schema"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	setParameterExample(param, m.gen.opts.ExamplePolicy, func() interface{} {
+		m.gen.direction = directionRequest
+		return m.gen.generateValid(location, schema, name)
+	})
+}
+
+// mockComponents generates examples for the reusable parameters, request bodies, responses and headers
+func (m *mocker) mockComponents(doc openapi.Document) {
+	components, ok := doc["components"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if params, ok := components["parameters"].(map[string]interface{}); ok {
+		for _, key := range sortedKeys(params) {
+			if param, ok := params[key].(map[string]interface{}); ok {
+				name, _ := param["name"].(string)
+				m.mockParameter("components parameter "+key, name, param)
 			}
+		}
+	}
 
-			itemType, _ := items["type"].(string)
-			switch itemType {
-			case "string":
-				result[key] = []string{
-					generateFakeValue(key).(string),
-					generateFakeValue(key).(string),
-				}
-			case "number", "integer":
-				result[key] = []int{
-					gofakeit.Number(1, 1000),
-					gofakeit.Number(1, 1000),
-				}
-			case "boolean":
-				result[key] = []bool{
-					gofakeit.Bool(),
-					gofakeit.Bool(),
-				}
-			default:
-				if ref, ok := items["$ref"].(string); ok {
-					refName := resolveRef(ref)
-					if subDefRaw, ok := defs[refName]; ok {
-						subDef, ok := subDefRaw.(map[string]interface{})
-						if ok {
-							result[key] = []interface{}{
-								generateFakeObject(subDef, defs),
-								generateFakeObject(subDef, defs),
-							}
-						}
-					}
-				}
+	if bodies, ok := components["requestBodies"].(map[string]interface{}); ok {
+		for _, key := range sortedKeys(bodies) {
+			if body, ok := bodies[key].(map[string]interface{}); ok {
+				m.mockContent("components requestBody "+key, body["content"], directionRequest)
 			}
-		case "object":
-			if props, ok := field["properties"].(map[string]interface{}); ok {
-				nestedObj := make(map[string]interface{})
-				for propKey, propVal := range props {
-					propField, ok := propVal.(map[string]interface{})
-					if !ok {
-						continue
-					}
-
-					if propField["type"] == "string" {
-						nestedObj[propKey] = generateFakeValue(propKey)
-					} else {
-						nestedObj[propKey] = gofakeit.Word()
-					}
-				}
-				result[key] = nestedObj
-			} else if ref, ok := field["$ref"].(string); ok {
-				refName := resolveRef(ref)
-				if subDefRaw, ok := defs[refName]; ok {
-					subDef, ok := subDefRaw.(map[string]interface{})
-					if ok {
-						result[key] = generateFakeObject(subDef, defs)
-					}
-				}
+		}
+	}
+
+	if responses, ok := components["responses"].(map[string]interface{}); ok {
+		for _, key := range sortedKeys(responses) {
+			if response, ok := responses[key].(map[string]interface{}); ok {
+				m.mockResponse("components response "+key, response)
 			}
-		default:
-			if ref, ok := field["$ref"].(string); ok {
-				refName := resolveRef(ref)
-				if subDefRaw, ok := defs[refName]; ok {
-					subDef, ok := subDefRaw.(map[string]interface{})
-					if ok {
-						result[key] = generateFakeObject(subDef, defs)
-					}
-				}
+		}
+	}
+
+	if headers, ok := components["headers"].(map[string]interface{}); ok {
+		for _, key := range sortedKeys(headers) {
+			if header, ok := headers[key].(map[string]interface{}); ok {
+				m.mockParameter("components header "+key, key, header)
 			}
 		}
 	}
-	return result
 }
 
-// Generate context-aware fake values based on field name
-func generateFakeValue(fieldName string) interface{} {
-	field := strings.ToLower(fieldName)
-
-	switch {
-	case strings.Contains(field, "date"):
-		return gofakeit.Date().Format("2006-01-02")
-	case strings.Contains(field, "time"):
-		return gofakeit.Date().Format("15:04:05")
-	case strings.Contains(field, "uuid") || strings.Contains(field, "id"):
-		return gofakeit.UUID()
-	case strings.Contains(field, "email"):
-		return gofakeit.Email()
-	case strings.Contains(field, "name") && strings.Contains(field, "first"):
-		return gofakeit.FirstName()
-	case strings.Contains(field, "name") && strings.Contains(field, "last"):
-		return gofakeit.LastName()
-	case strings.Contains(field, "name") && !strings.Contains(field, "first") && !strings.Contains(field, "last"):
-		return gofakeit.Name()
-	case strings.Contains(field, "city"):
-		return gofakeit.City()
-	case strings.Contains(field, "country"):
-		return gofakeit.Country()
-	case strings.Contains(field, "phone"):
-		return gofakeit.Phone()
-	case strings.Contains(field, "postal") || strings.Contains(field, "zip"):
-		return gofakeit.Zip()
-	case strings.Contains(field, "address"):
-		return gofakeit.Address().Address
-	case strings.Contains(field, "status"):
-		return gofakeit.RandomString([]string{"active", "inactive", "pending"})
-	case strings.Contains(field, "description"):
-		return gofakeit.Sentence(5)
-	case strings.Contains(field, "title"):
-		return gofakeit.Sentence(3)
-	case strings.Contains(field, "url") || strings.Contains(field, "link"):
-		return gofakeit.URL()
+// autoExampleName is the key of generated entries in examples maps
+const autoExampleName = "auto_example"
+
+// setExample stores a generated example in a media type object according to the example policy.
+// The value is only generated when the policy needs it.
+func setExample(mediaType map[string]interface{}, policy ExamplePolicy, generate func() interface{}) {
+	example, hasExample := mediaType["example"]
+	examples, hasExamples := mediaType["examples"].(map[string]interface{})
+
+	switch policy {
+	case ExamplePolicyReplace:
+		delete(mediaType, "example")
+		mediaType["examples"] = map[string]interface{}{
+			autoExampleName: map[string]interface{}{"value": generate()},
+		}
+	case ExamplePolicyAppend:
+		if !hasExamples {
+			examples = make(map[string]interface{})
+		}
+		// example and examples are mutually exclusive, keep the author one under examples
+		if hasExample {
+			examples["example"] = map[string]interface{}{"value": example}
+			delete(mediaType, "example")
+		}
+		examples[autoExampleName] = map[string]interface{}{"value": generate()}
+		mediaType["examples"] = examples
 	default:
-		return gofakeit.Word()
+		if hasExample || hasExamples {
+			return
+		}
+		mediaType["examples"] = map[string]interface{}{
+			autoExampleName: map[string]interface{}{"value": generate()},
+		}
+	}
+}
+
+// setParameterExample stores a generated example on a parameter or header.
+// Renderers show the singular example of parameters, so it is preferred when nothing else is set.
+func setParameterExample(param map[string]interface{}, policy ExamplePolicy, generate func() interface{}) {
+	_, hasExample := param["example"]
+	_, hasExamples := param["examples"]
+
+	if policy == ExamplePolicyFillMissing || policy == "" {
+		if !hasExample && !hasExamples {
+			param["example"] = generate()
+		}
+		return
+	}
+	if policy == ExamplePolicyReplace {
+		delete(param, "examples")
+		delete(param, "example")
+		param["example"] = generate()
+		return
 	}
+	setExample(param, policy, generate)
 }
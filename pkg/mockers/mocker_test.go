@@ -0,0 +1,178 @@
+package mockers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// dig follows keys through nested maps and lists, nil when a key is missing
+func dig(value interface{}, keys ...interface{}) interface{} {
+	for _, key := range keys {
+		switch k := key.(type) {
+		case string:
+			m, _ := value.(map[string]interface{})
+			value = m[k]
+		case int:
+			l, _ := value.([]interface{})
+			if k >= len(l) {
+				return nil
+			}
+			value = l[k]
+		}
+	}
+	return value
+}
+
+// exampleNames returns the names of an examples map
+func exampleNames(object interface{}) []string {
+	examples, _ := dig(object, "examples").(map[string]interface{})
+	if examples == nil {
+		return nil
+	}
+	return sortedKeys(examples)
+}
+
+func TestExamplePolicy(t *testing.T) {
+	input, err := os.ReadFile(filepath.Join("testdata", "examples.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	author := map[string]interface{}{"id": 42, "name": "Rex", "owner": map[string]interface{}{"name": "Alice"}}
+
+	type check struct {
+		name  string
+		check func(t *testing.T, doc openapi.Document)
+	}
+	// Locations of the author examples in the fixture
+	media := func(doc openapi.Document) interface{} {
+		return dig(doc, "paths", "/pets/{petId}", "get", "responses", "200", "content", "application/json")
+	}
+	mediaExamples := func(doc openapi.Document) interface{} {
+		return dig(doc, "paths", "/pets/{petId}", "get", "responses", "404", "content", "application/json")
+	}
+	body := func(doc openapi.Document) map[string]interface{} {
+		value, _ := dig(doc, "paths", "/pets", "post", "requestBody", "content", "application/json", "examples", autoExampleName, "value").(map[string]interface{})
+		return value
+	}
+	pathParameter := func(doc openapi.Document) interface{} {
+		return dig(doc, "paths", "/pets/{petId}", "parameters", 0)
+	}
+	queryParameter := func(doc openapi.Document, i int) interface{} {
+		return dig(doc, "paths", "/pets/{petId}", "get", "parameters", i)
+	}
+
+	tests := []struct {
+		policy ExamplePolicy
+		checks []check
+	}{
+		{ExamplePolicyFillMissing, []check{
+			{"media example kept", func(t *testing.T, doc openapi.Document) {
+				if !reflect.DeepEqual(dig(media(doc), "example"), author) || exampleNames(media(doc)) != nil {
+					t.Errorf("media = %v, want only the author example", media(doc))
+				}
+			}},
+			{"media examples kept", func(t *testing.T, doc openapi.Document) {
+				if names := exampleNames(mediaExamples(doc)); !reflect.DeepEqual(names, []string{"missing"}) {
+					t.Errorf("examples = %v, want [missing]", names)
+				}
+			}},
+			{"schema and property examples used", func(t *testing.T, doc openapi.Document) {
+				if value := body(doc); value["name"] != "Rex" || !reflect.DeepEqual(value["owner"], author["owner"]) {
+					t.Errorf("generated body = %v, want the name and owner examples", value)
+				}
+			}},
+			{"parameter example kept", func(t *testing.T, doc openapi.Document) {
+				if dig(pathParameter(doc), "example") != 42 || exampleNames(pathParameter(doc)) != nil {
+					t.Errorf("petId = %v, want only the author example", pathParameter(doc))
+				}
+			}},
+			{"parameter examples kept", func(t *testing.T, doc openapi.Document) {
+				tag := queryParameter(doc, 0)
+				if dig(tag, "example") != nil || !reflect.DeepEqual(exampleNames(tag), []string{"dog"}) {
+					t.Errorf("tag = %v, want only the author examples", tag)
+				}
+			}},
+			{"missing parameter example generated", func(t *testing.T, doc openapi.Document) {
+				if _, ok := dig(queryParameter(doc, 1), "example").(int); !ok {
+					t.Errorf("limit = %v, want a generated example", queryParameter(doc, 1))
+				}
+			}},
+		}},
+		{ExamplePolicyAppend, []check{
+			{"media example moved next to the generated one", func(t *testing.T, doc openapi.Document) {
+				if dig(media(doc), "example") != nil || !reflect.DeepEqual(exampleNames(media(doc)), []string{autoExampleName, "example"}) {
+					t.Errorf("media = %v, want the author and generated examples", media(doc))
+				}
+				if !reflect.DeepEqual(dig(media(doc), "examples", "example", "value"), author) {
+					t.Errorf("author example = %v, want %v", dig(media(doc), "examples", "example", "value"), author)
+				}
+			}},
+			{"media examples completed", func(t *testing.T, doc openapi.Document) {
+				if names := exampleNames(mediaExamples(doc)); !reflect.DeepEqual(names, []string{autoExampleName, "missing"}) {
+					t.Errorf("examples = %v, want the author and generated examples", names)
+				}
+			}},
+			{"schema and property examples ignored", func(t *testing.T, doc openapi.Document) {
+				if value := body(doc); value == nil || value["name"] == "Rex" || reflect.DeepEqual(value["owner"], author["owner"]) {
+					t.Errorf("generated body = %v, want generated name and owner", value)
+				}
+			}},
+			{"parameter example moved next to the generated one", func(t *testing.T, doc openapi.Document) {
+				petID := pathParameter(doc)
+				if dig(petID, "example") != nil || dig(petID, "examples", "example", "value") != 42 || dig(petID, "examples", autoExampleName) == nil {
+					t.Errorf("petId = %v, want the author and generated examples", petID)
+				}
+			}},
+			{"parameter examples completed", func(t *testing.T, doc openapi.Document) {
+				if names := exampleNames(queryParameter(doc, 0)); !reflect.DeepEqual(names, []string{autoExampleName, "dog"}) {
+					t.Errorf("tag examples = %v, want the author and generated examples", names)
+				}
+			}},
+		}},
+		{ExamplePolicyReplace, []check{
+			{"media example replaced", func(t *testing.T, doc openapi.Document) {
+				if dig(media(doc), "example") != nil || !reflect.DeepEqual(exampleNames(media(doc)), []string{autoExampleName}) {
+					t.Errorf("media = %v, want only a generated example", media(doc))
+				}
+				if reflect.DeepEqual(dig(media(doc), "examples", autoExampleName, "value"), author) {
+					t.Error("the generated example is the author one")
+				}
+			}},
+			{"media examples replaced", func(t *testing.T, doc openapi.Document) {
+				if names := exampleNames(mediaExamples(doc)); !reflect.DeepEqual(names, []string{autoExampleName}) {
+					t.Errorf("examples = %v, want only a generated example", names)
+				}
+			}},
+			{"schema and property examples ignored", func(t *testing.T, doc openapi.Document) {
+				if value := body(doc); value == nil || value["name"] == "Rex" || reflect.DeepEqual(value["owner"], author["owner"]) {
+					t.Errorf("generated body = %v, want generated name and owner", value)
+				}
+			}},
+			{"parameter examples replaced", func(t *testing.T, doc openapi.Document) {
+				tag := queryParameter(doc, 0)
+				if _, ok := dig(tag, "example").(string); !ok || exampleNames(tag) != nil {
+					t.Errorf("tag = %v, want only a generated example", tag)
+				}
+			}},
+		}},
+	}
+	for _, tt := range tests {
+		result, err := MockOpenAPISpec(string(input), Options{Seed: 1, ExamplePolicy: tt.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := openapi.Parse([]byte(result.Content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range tt.checks {
+			t.Run(string(tt.policy)+"/"+c.name, func(t *testing.T) {
+				c.check(t, doc)
+			})
+		}
+	}
+}
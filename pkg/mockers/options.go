@@ -0,0 +1,117 @@
+package mockers
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Default limits applied when Options leaves them unset
+const (
+	DefaultMaxDepth       = 8
+	DefaultMaxArrayLength = 20
+)
+
+// ExamplePolicy decides how generated examples are merged with the ones written by the author
+type ExamplePolicy string
+
+const (
+	// ExamplePolicyFillMissing keeps every author example and only generates the missing ones
+	ExamplePolicyFillMissing ExamplePolicy = "fillMissing"
+	// ExamplePolicyAppend adds a generated example next to the author examples
+	ExamplePolicyAppend ExamplePolicy = "append"
+	// ExamplePolicyReplace discards the author examples in favor of generated ones
+	ExamplePolicyReplace ExamplePolicy = "replace"
+)
+
+// Options tunes the generation of fake examples
+type Options struct {
+	// MaxDepth limits how deeply nested objects and arrays are generated
+	MaxDepth int
+
+	// MaxArrayLength caps the number of items generated for arrays and maps
+	MaxArrayLength int
+
+	// Seed makes the generated data reproducible. Every example is generated
+	// from a seed derived from this value and the example location, so the same
+	// spec and seed always give the same output.
+	Seed int64
+
+	// ExamplePolicy decides what happens to existing examples, fillMissing by default
+	ExamplePolicy ExamplePolicy
+
+	// Locale is the language of generated names, addresses, phone numbers and text,
+	// such as "fr" or "de-DE". English is used by default.
+	Locale string
+
+	// Consistent makes examples share a pool of instances per component schema:
+	// references reuse the same objects, ids such as customerId point at existing
+	// instances and successful responses echo the values sent in the request.
+	Consistent bool
+
+	// FixturePoolSize is the number of shared instances per component schema in consistent mode
+	FixturePoolSize int
+
+	// FormatGenerators produce the values of the schemas with a format, taking precedence
+	// over the generators registered for the whole process with RegisterFormat
+	FormatGenerators map[string]Generator
+
+	// PropertyGenerators produce the values of the properties with a name, matched
+	// case-insensitively, taking precedence over the ones registered with RegisterProperty
+	PropertyGenerators map[string]Generator
+}
+
+// SeedFor derives a stable seed from a list of identifiers, such as a namespace and name
+func SeedFor(parts ...string) int64 {
+	h := fnv.New64a()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return int64(h.Sum64())
+}
+
+// deriveSeed combines the base seed with a key identifying an example.
+// It never returns 0, which gofakeit treats as a request for a random seed.
+func deriveSeed(seed int64, key string) int64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(seed))
+	h.Write(buf[:])
+	h.Write([]byte(key))
+	if derived := int64(h.Sum64()); derived != 0 {
+		return derived
+	}
+	return 1
+}
+
+// withDefaults fills unset options with their default values
+func (o Options) withDefaults() Options {
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = DefaultMaxDepth
+	}
+	if o.MaxArrayLength <= 0 {
+		o.MaxArrayLength = DefaultMaxArrayLength
+	}
+	if o.Locale == "" {
+		o.Locale = DefaultLocale
+	}
+	if o.FixturePoolSize <= 0 {
+		o.FixturePoolSize = DefaultFixturePoolSize
+	}
+	if o.ExamplePolicy == "" {
+		o.ExamplePolicy = ExamplePolicyFillMissing
+	}
+	return o
+}
+
+// Result holds a mocked specification and the problems met while generating it
+type Result struct {
+	// Content is the mocked specification in YAML
+	Content string
+
+	// Warnings lists the examples that had to be truncated or simplified
+	Warnings []string
+
+	// InvalidExamples lists the generated examples that do not satisfy their schema
+	InvalidExamples []string
+}
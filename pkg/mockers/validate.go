@@ -0,0 +1,348 @@
+package mockers
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// maxGenerationAttempts bounds how many times an invalid example is generated again with another seed
+const maxGenerationAttempts = 3
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// generateValid generates an example for the schema and checks it against the schema.
+// Invalid examples are generated again with other seeds, then replaced by the smallest
+// value of the schema, which is reported as a warning. Examples that still fail are kept
+// and reported as invalid.
+func (g *generator) generateValid(key string, schema map[string]interface{}, name string) interface{} {
+	var first interface{}
+	var problems []string
+	for attempt := 0; attempt < maxGenerationAttempts; attempt++ {
+		seedKey := key
+		if attempt > 0 {
+			seedKey = fmt.Sprintf("%s#%d", key, attempt)
+		}
+		g.reseed(seedKey)
+		g.context = key
+
+		value := g.generate(schema, name)
+		errs := g.validate(schema, value, "")
+		if len(errs) == 0 {
+			return value
+		}
+		if attempt == 0 {
+			first, problems = value, errs
+		}
+	}
+
+	g.reseed(key)
+	if fallback := g.terminal(schema); len(g.validate(schema, fallback, "")) == 0 {
+		g.warn("no valid example after %d attempts (%s), using the minimal example", maxGenerationAttempts, strings.Join(problems, ", "))
+		return fallback
+	}
+
+	g.invalidExamples = append(g.invalidExamples, fmt.Sprintf("%s: %s", key, strings.Join(problems, ", ")))
+	return first
+}
+
+// Validate checks a value decoded from JSON against a schema of the document and returns the violations found
+func Validate(doc openapi.Document, schema map[string]interface{}, value interface{}) []string {
+	gen := newGenerator(doc, Options{})
+	gen.direction = directionResponse
+	return gen.validate(schema, value, "")
+}
+
+// validate checks a value against a schema and returns the violations found.
+// Properties left out because of the current direction are not required.
+func (g *generator) validate(schema map[string]interface{}, value interface{}, path string) []string {
+	// A reference met again for the same value comes from an allOf cycle
+	if ref, ok := schema["$ref"].(string); ok {
+		key := ref + " " + path
+		if g.validating[key] {
+			return nil
+		}
+		g.validating[key] = true
+		defer delete(g.validating, key)
+	}
+
+	schema, _ = g.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	if value == nil && nullable(schema) {
+		return nil
+	}
+
+	var errs []string
+	fail := func(format string, args ...interface{}) {
+		at := path
+		if at == "" {
+			at = "the root"
+		}
+		errs = append(errs, fmt.Sprintf("%s at %s", fmt.Sprintf(format, args...), at))
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, raw := range allOf {
+			if part, ok := raw.(map[string]interface{}); ok {
+				errs = append(errs, g.validate(part, value, path)...)
+			}
+		}
+	}
+
+	// Alternatives often overlap in real specs, so oneOf is checked like anyOf
+	if variants := schemaVariants(schema); len(variants) > 0 {
+		matched := false
+		for _, raw := range variants {
+			if variant, ok := raw.(map[string]interface{}); ok && len(g.validate(variant, value, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("value matches none of the alternatives")
+		}
+	}
+
+	if expected, ok := schema["const"]; ok && toKey(expected) != toKey(value) {
+		fail("expected %v", expected)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 && !inEnum(enum, value) {
+		fail("value %v is not in the enum", value)
+	}
+
+	if types := declaredTypes(schema); len(types) > 0 && !matchesType(types, value) {
+		fail("expected %s, got %s", strings.Join(types, " or "), valueType(value))
+		return errs
+	}
+
+	// Nested violations are collected apart since fail appends to errs
+	var nested []string
+	switch v := value.(type) {
+	case string:
+		validateString(schema, v, fail)
+	case []interface{}:
+		nested = g.validateArray(schema, v, path, fail)
+	case map[string]interface{}:
+		nested = g.validateObject(schema, v, path, fail)
+	default:
+		if number, ok := toFloat(value); ok {
+			validateNumber(schema, number, fail)
+		}
+	}
+	return append(errs, nested...)
+}
+
+func validateString(schema map[string]interface{}, value string, fail func(string, ...interface{})) {
+	length := len([]rune(value))
+	if minLength, ok := intKeyword(schema, "minLength"); ok && length < minLength {
+		fail("length %d is below minLength %d", length, minLength)
+	}
+	if maxLength, ok := intKeyword(schema, "maxLength"); ok && length > maxLength {
+		fail("length %d is above maxLength %d", length, maxLength)
+	}
+	// Patterns using ECMA-only syntax cannot be checked with Go regular expressions
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+			fail("value %q does not match pattern %s", value, pattern)
+		}
+	}
+	if format, ok := schema["format"].(string); ok && !matchesFormat(format, value) {
+		fail("value %q is not a valid %s", value, format)
+	}
+}
+
+func validateNumber(schema map[string]interface{}, value float64, fail func(string, ...interface{})) {
+	if minimum, ok := floatKeyword(schema, "minimum"); ok {
+		if exclusive, _ := schema["exclusiveMinimum"].(bool); exclusive && value <= minimum {
+			fail("%v is not above %v", value, minimum)
+		} else if value < minimum {
+			fail("%v is below minimum %v", value, minimum)
+		}
+	}
+	if maximum, ok := floatKeyword(schema, "maximum"); ok {
+		if exclusive, _ := schema["exclusiveMaximum"].(bool); exclusive && value >= maximum {
+			fail("%v is not below %v", value, maximum)
+		} else if value > maximum {
+			fail("%v is above maximum %v", value, maximum)
+		}
+	}
+	if bound, ok := floatKeyword(schema, "exclusiveMinimum"); ok && value <= bound {
+		fail("%v is not above %v", value, bound)
+	}
+	if bound, ok := floatKeyword(schema, "exclusiveMaximum"); ok && value >= bound {
+		fail("%v is not below %v", value, bound)
+	}
+	if multipleOf, ok := floatKeyword(schema, "multipleOf"); ok && multipleOf > 0 {
+		quotient := value / multipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			fail("%v is not a multiple of %v", value, multipleOf)
+		}
+	}
+}
+
+func (g *generator) validateArray(schema map[string]interface{}, value []interface{}, path string, fail func(string, ...interface{})) []string {
+	if minItems, ok := intKeyword(schema, "minItems"); ok && len(value) < minItems {
+		fail("%d items, below minItems %d", len(value), minItems)
+	}
+	if maxItems, ok := intKeyword(schema, "maxItems"); ok && len(value) > maxItems {
+		fail("%d items, above maxItems %d", len(value), maxItems)
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		seen := make(map[string]bool)
+		for _, item := range value {
+			if seen[toKey(item)] {
+				fail("duplicate item %v", item)
+				break
+			}
+			seen[toKey(item)] = true
+		}
+	}
+
+	var errs []string
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range value {
+			errs = append(errs, g.validate(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return errs
+}
+
+func (g *generator) validateObject(schema map[string]interface{}, value map[string]interface{}, path string, fail func(string, ...interface{})) []string {
+	props, _ := schema["properties"].(map[string]interface{})
+
+	for _, key := range sortedBoolKeys(requiredSet(schema)) {
+		if _, ok := value[key]; ok {
+			continue
+		}
+		if prop, ok := props[key].(map[string]interface{}); ok && g.skipProperty(prop) {
+			continue
+		}
+		fail("missing required property %s", key)
+	}
+	if minProps, ok := intKeyword(schema, "minProperties"); ok && len(value) < minProps {
+		fail("%d properties, below minProperties %d", len(value), minProps)
+	}
+	if maxProps, ok := intKeyword(schema, "maxProperties"); ok && len(value) > maxProps {
+		fail("%d properties, above maxProperties %d", len(value), maxProps)
+	}
+
+	var errs []string
+	for _, key := range sortedKeys(value) {
+		propPath := key
+		if path != "" {
+			propPath = path + "." + key
+		}
+		if prop, ok := props[key].(map[string]interface{}); ok {
+			errs = append(errs, g.validate(prop, value[key], propPath)...)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				fail("unexpected property %s", key)
+			}
+		case map[string]interface{}:
+			errs = append(errs, g.validate(additional, value[key], propPath)...)
+		}
+	}
+	return errs
+}
+
+// declaredTypes lists the types a schema accepts, empty when any type is accepted
+func declaredTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(types []string, value interface{}) bool {
+	actual := valueType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// valueType returns the JSON schema type of a generated or parsed value
+func valueType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	if number, ok := toFloat(value); ok {
+		if number == math.Trunc(number) && !math.IsInf(number, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	return floatKeyword(map[string]interface{}{"v": value}, "v")
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, candidate := range enum {
+		if toKey(candidate) == toKey(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesFormat checks the formats whose syntax is well defined, other formats are accepted
+func matchesFormat(format, value string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", value)
+		return err == nil
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	case "email", "idn-email":
+		at := strings.LastIndex(value, "@")
+		return at > 0 && at < len(value)-1
+	case "ipv4":
+		ip := net.ParseIP(value)
+		return ip != nil && ip.To4() != nil && strings.Contains(value, ".")
+	case "ipv6":
+		ip := net.ParseIP(value)
+		return ip != nil && strings.Contains(value, ":")
+	case "uri", "url":
+		u, err := url.Parse(value)
+		return err == nil && u.Scheme != ""
+	}
+	return true
+}
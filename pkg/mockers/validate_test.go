@@ -0,0 +1,64 @@
+package mockers
+
+import (
+	"strings"
+	"testing"
+)
+
+const unsatisfiableSpec = `
+openapi: 3.0.3
+info: {title: Unsatisfiable, version: "1"}
+paths:
+  /a:
+    get:
+      responses:
+        200:
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [id]
+                properties:
+                  id: {type: integer, minimum: 5, maximum: 5}
+                  name: %s
+`
+
+func TestGenerateValidReportsFallbacks(t *testing.T) {
+	tests := []struct {
+		name        string
+		property    string
+		required    bool
+		wantWarning bool
+		wantInvalid bool
+	}{
+		{"satisfiable", "{type: string, maxLength: 4}", false, false, false},
+		{"optional property dropped", "{type: string, minLength: 10, maxLength: 2}", false, true, false},
+		{"required property invalid", "{type: string, minLength: 10, maxLength: 2}", true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := strings.Replace(unsatisfiableSpec, "%s", tt.property, 1)
+			if tt.required {
+				spec = strings.Replace(spec, "required: [id]", "required: [id, name]", 1)
+			}
+			result, err := MockOpenAPISpec(spec, Options{Seed: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			warned := false
+			for _, warning := range result.Warnings {
+				if strings.HasPrefix(warning, "GET /a 200 application/json: no valid example") {
+					warned = true
+				}
+			}
+			if warned != tt.wantWarning {
+				t.Errorf("fallback warning = %v, want %v (warnings: %v)", warned, tt.wantWarning, result.Warnings)
+			}
+			if invalid := len(result.InvalidExamples) > 0; invalid != tt.wantInvalid {
+				t.Errorf("invalid examples = %v, want reported %v", result.InvalidExamples, tt.wantInvalid)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package mockers
+
+import (
+	"strings"
+)
+
+// fakeValue generates a context-aware value from the field name.
+// It is only used when the schema does not constrain the value.
+func (g *generator) fakeValue(fieldName string) interface{} {
+	field := strings.ToLower(fieldName)
+
+	switch {
+	case strings.Contains(field, "date"):
+		return g.date().Format("2006-01-02")
+	case strings.Contains(field, "time"):
+		return g.date().Format("15:04:05")
+	case strings.Contains(field, "uuid") || strings.Contains(field, "id"):
+		return g.faker.UUID()
+	case strings.Contains(field, "email"):
+		return g.faker.Email()
+	case strings.Contains(field, "name") && strings.Contains(field, "first"):
+		return g.firstName()
+	case strings.Contains(field, "name") && strings.Contains(field, "last"):
+		return g.lastName()
+	case strings.Contains(field, "name") && !strings.Contains(field, "first") && !strings.Contains(field, "last"):
+		return g.fullName()
+	case strings.Contains(field, "city"):
+		return g.city()
+	case strings.Contains(field, "country"):
+		return g.country()
+	case strings.Contains(field, "phone"):
+		return g.phone()
+	case strings.Contains(field, "postal") || strings.Contains(field, "zip"):
+		return g.postalCode()
+	case strings.Contains(field, "street"):
+		return g.street()
+	case strings.Contains(field, "address"):
+		return g.address()
+	case strings.Contains(field, "status"):
+		return g.faker.RandomString([]string{"active", "inactive", "pending"})
+	case strings.Contains(field, "description"):
+		return g.sentence(5)
+	case strings.Contains(field, "title"):
+		return g.sentence(3)
+	case strings.Contains(field, "url") || strings.Contains(field, "link"):
+		return g.faker.URL()
+	default:
+		return g.word()
+	}
+}
@@ -0,0 +1,145 @@
+// Package openapi contains helpers shared by the packages that read and
+// rewrite OpenAPI documents as generic maps.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is an OpenAPI or Swagger document decoded into generic maps
+type Document = map[string]interface{}
+
+// Parse decodes a specification written in JSON or YAML
+func Parse(content []byte) (Document, error) {
+	var doc Document
+
+	// Try parsing as JSON first
+	if err := json.Unmarshal(content, &doc); err != nil {
+		// If JSON parsing fails, try YAML
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, fmt.Errorf("error parsing OpenAPI spec (neither valid JSON nor YAML): %v", err)
+		}
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("error parsing OpenAPI spec: document is empty")
+	}
+	return StringKeys(doc).(Document), nil
+}
+
+// StringKeys converts the maps decoded from YAML with non-string keys, such as the unquoted
+// status codes of "responses: {200: ...}", into maps keyed by strings
+func StringKeys(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			value[key] = StringKeys(child)
+		}
+		return value
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			out[fmt.Sprint(key)] = StringKeys(child)
+		}
+		return out
+	case []interface{}:
+		for i, child := range value {
+			value[i] = StringKeys(child)
+		}
+		return value
+	}
+	return value
+}
+
+// Marshal encodes a document back to YAML
+func Marshal(doc Document) ([]byte, error) {
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding OpenAPI spec: %v", err)
+	}
+	return out, nil
+}
+
+// IsSwagger2 reports whether the document is a Swagger 2.0 specification
+func IsSwagger2(doc Document) bool {
+	version, ok := doc["swagger"].(string)
+	return ok && strings.HasPrefix(version, "2.")
+}
+
+// Version returns the OpenAPI version of the document, or "2.0" for Swagger documents
+func Version(doc Document) string {
+	if version, ok := doc["openapi"].(string); ok {
+		return version
+	}
+	if version, ok := doc["swagger"].(string); ok {
+		return version
+	}
+	return ""
+}
+
+// RefName extracts the last segment of a JSON reference
+func RefName(ref string) string {
+	parts := strings.Split(ref, "/")
+	return unescapePointer(parts[len(parts)-1])
+}
+
+// Resolve follows a local JSON reference such as "#/components/schemas/Pet"
+func Resolve(doc Document, ref string) (map[string]interface{}, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+
+	var current interface{} = doc
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		node, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = node[unescapePointer(part)]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	target, ok := current.(map[string]interface{})
+	return target, ok
+}
+
+// Schemas returns the reusable schemas of the document, whatever its version
+func Schemas(doc Document) map[string]interface{} {
+	if components, ok := doc["components"].(map[string]interface{}); ok {
+		if schemas, ok := components["schemas"].(map[string]interface{}); ok {
+			return schemas
+		}
+	}
+	if definitions, ok := doc["definitions"].(map[string]interface{}); ok {
+		return definitions
+	}
+	return nil
+}
+
+// Methods lists the path item keys that hold operations
+var Methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// IsMethod reports whether a path item key holds an operation
+func IsMethod(key string) bool {
+	for _, method := range Methods {
+		if key == method {
+			return true
+		}
+	}
+	return false
+}
+
+func unescapePointer(token string) string {
+	token = strings.ReplaceAll(token, "~1", "/")
+	return strings.ReplaceAll(token, "~0", "~")
+}
+
+func escapePointer(token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	return strings.ReplaceAll(token, "/", "~1")
+}
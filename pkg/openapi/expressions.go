@@ -0,0 +1,132 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// RuntimeContext holds the request and response a runtime expression is evaluated against
+type RuntimeContext struct {
+	URL        string
+	Method     string
+	StatusCode int
+
+	RequestHeaders http.Header
+	Query          url.Values
+	PathParams     map[string]string
+	RequestBody    interface{}
+
+	ResponseHeaders http.Header
+	ResponseBody    interface{}
+}
+
+// Evaluate expands the runtime expressions of a callback key, such as
+// "{$request.body#/callbackUrl}/events" or "$request.query.url"
+func (c *RuntimeContext) Evaluate(template string) (string, error) {
+	if strings.HasPrefix(template, "$") {
+		return c.expression(template)
+	}
+
+	var out strings.Builder
+	for {
+		start := strings.Index(template, "{$")
+		if start < 0 {
+			out.WriteString(template)
+			return out.String(), nil
+		}
+		end := strings.Index(template[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated expression in %q", template)
+		}
+		value, err := c.expression(template[start+1 : start+end])
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(template[:start])
+		out.WriteString(value)
+		template = template[start+end+1:]
+	}
+}
+
+// expression evaluates a single runtime expression
+func (c *RuntimeContext) expression(expr string) (string, error) {
+	switch expr {
+	case "$url":
+		return c.URL, nil
+	case "$method":
+		return c.Method, nil
+	case "$statusCode":
+		return strconv.Itoa(c.StatusCode), nil
+	}
+
+	source, rest, ok := strings.Cut(expr, ".")
+	if !ok || (source != "$request" && source != "$response") {
+		return "", fmt.Errorf("unsupported expression %q", expr)
+	}
+
+	headers, body := c.RequestHeaders, c.RequestBody
+	if source == "$response" {
+		headers, body = c.ResponseHeaders, c.ResponseBody
+	}
+
+	switch {
+	case strings.HasPrefix(rest, "header."):
+		if value := headers.Get(strings.TrimPrefix(rest, "header.")); value != "" {
+			return value, nil
+		}
+	case strings.HasPrefix(rest, "query.") && source == "$request":
+		if value := c.Query.Get(strings.TrimPrefix(rest, "query.")); value != "" {
+			return value, nil
+		}
+	case strings.HasPrefix(rest, "path.") && source == "$request":
+		if value, ok := c.PathParams[strings.TrimPrefix(rest, "path.")]; ok {
+			return value, nil
+		}
+	case rest == "body" || strings.HasPrefix(rest, "body#"):
+		value, ok := Pointer(body, strings.TrimPrefix(strings.TrimPrefix(rest, "body"), "#"))
+		if ok && value != nil {
+			if s, isString := value.(string); isString {
+				return s, nil
+			}
+			return fmt.Sprint(value), nil
+		}
+	default:
+		return "", fmt.Errorf("unsupported expression %q", expr)
+	}
+	return "", fmt.Errorf("expression %s has no value", expr)
+}
+
+// Pointer evaluates a JSON pointer such as "/items/0/url" against a decoded JSON value
+func Pointer(value interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return value, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+
+	current := value
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = unescapePointer(token)
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[token]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
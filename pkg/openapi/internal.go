@@ -0,0 +1,384 @@
+package openapi
+
+import (
+	"strings"
+)
+
+// DefaultInternalExtension flags the elements of a document meant for internal readers only
+const DefaultInternalExtension = "x-internal"
+
+// Keys holding literal values rather than OpenAPI objects, skipped when looking for schemas
+var literalKeys = map[string]bool{"example": true, "examples": true, "default": true, "enum": true, "const": true}
+
+// Keys holding maps of named objects, whose names may collide with the literal keys,
+// such as a property named "default" or the default response
+var namedKeys = map[string]bool{
+	"properties": true, "patternProperties": true, "definitions": true, "$defs": true, "schemas": true,
+	"paths": true, "webhooks": true, "pathItems": true, "responses": true, "parameters": true,
+	"headers": true, "requestBodies": true, "callbacks": true,
+}
+
+// Sections of the document holding reusable components, by version
+var componentSections = map[string]bool{"components": true, "definitions": true, "parameters": true, "responses": true}
+
+// StripInternal removes the operations, path items, parameters, schema properties, tags and components
+// flagged with an extension, such as "x-internal: true". Operations whose tags are all internal are removed too.
+// Components only referenced by removed elements are pruned, while components that were already
+// unreferenced are kept. It returns the number of removed elements and components.
+func StripInternal(doc Document, extension string) int {
+	if extension == "" {
+		extension = DefaultInternalExtension
+	}
+	s := &stripper{doc: doc, extension: extension, internalTags: make(map[string]bool)}
+	before := referencedComponents(doc)
+	// What only internal components use goes with them
+	internalComponents := s.internalComponents()
+	for _, component := range internalComponents {
+		target, _ := Resolve(doc, "#/"+component)
+		for referenced := range referencedFrom(doc, target) {
+			before[referenced] = true
+		}
+	}
+
+	s.stripTags()
+	for _, section := range []string{"paths", "webhooks", "x-webhooks"} {
+		if items, ok := doc[section].(map[string]interface{}); ok {
+			s.stripPathItems(items)
+		}
+	}
+	s.stripSchemas(doc, false)
+
+	// Flagged components are removed last, as the other elements are checked through their references
+	for _, component := range internalComponents {
+		if removeComponent(doc, component) {
+			s.removed++
+		}
+	}
+
+	after := referencedComponents(doc)
+	for component := range before {
+		if !after[component] && removeComponent(doc, component) {
+			s.removed++
+		}
+	}
+	return s.removed
+}
+
+// stripper removes the internal elements of a document
+type stripper struct {
+	doc          Document
+	extension    string
+	internalTags map[string]bool
+	removed      int
+}
+
+// internal tells whether a node, or the component it references, is flagged internal
+func (s *stripper) internal(node interface{}) bool {
+	object, ok := node.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if flagged(object[s.extension]) {
+		return true
+	}
+	if ref, ok := object["$ref"].(string); ok {
+		if target, ok := Resolve(s.doc, ref); ok {
+			return flagged(target[s.extension])
+		}
+	}
+	return false
+}
+
+// internalComponents lists the components flagged internal, as pointers such as "components/schemas/AdminUser"
+func (s *stripper) internalComponents() []string {
+	var components []string
+	collect := func(prefix string, section interface{}) {
+		named, _ := section.(map[string]interface{})
+		for name, component := range named {
+			if object, ok := component.(map[string]interface{}); ok && flagged(object[s.extension]) {
+				components = append(components, prefix+"/"+escapePointer(name))
+			}
+		}
+	}
+	for key, section := range s.doc {
+		switch {
+		case key == "components":
+			sections, _ := section.(map[string]interface{})
+			for name, named := range sections {
+				collect("components/"+name, named)
+			}
+		case componentSections[key]:
+			collect(key, section)
+		}
+	}
+	return components
+}
+
+// stripTags removes the internal tags and remembers their names
+func (s *stripper) stripTags() {
+	tags, ok := s.doc["tags"].([]interface{})
+	if !ok {
+		return
+	}
+	kept := tags[:0]
+	for _, tag := range tags {
+		if s.internal(tag) {
+			name, _ := tag.(map[string]interface{})["name"].(string)
+			s.internalTags[name] = true
+			s.removed++
+			continue
+		}
+		kept = append(kept, tag)
+	}
+	setList(s.doc, "tags", kept)
+}
+
+// stripPathItems removes the internal path items, operations and parameters
+func (s *stripper) stripPathItems(items map[string]interface{}) {
+	for key, raw := range items {
+		if s.internal(raw) {
+			delete(items, key)
+			s.removed++
+			continue
+		}
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		s.stripParameters(item)
+
+		operations := 0
+		for method, operation := range item {
+			if !IsMethod(method) {
+				continue
+			}
+			if s.internal(operation) || s.internallyTagged(operation) {
+				delete(item, method)
+				s.removed++
+				continue
+			}
+			operations++
+			if object, ok := operation.(map[string]interface{}); ok {
+				s.stripParameters(object)
+				s.stripOperationTags(object)
+			}
+		}
+		// Path items left without operations would render as empty sections
+		if operations == 0 && item["$ref"] == nil {
+			delete(items, key)
+		}
+	}
+}
+
+// internallyTagged tells whether all the tags of an operation are internal
+func (s *stripper) internallyTagged(operation interface{}) bool {
+	object, _ := operation.(map[string]interface{})
+	tags, _ := object["tags"].([]interface{})
+	if len(tags) == 0 || len(s.internalTags) == 0 {
+		return false
+	}
+	for _, tag := range tags {
+		if name, _ := tag.(string); !s.internalTags[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// stripOperationTags removes the internal tags from the tags of an operation
+func (s *stripper) stripOperationTags(operation map[string]interface{}) {
+	tags, ok := operation["tags"].([]interface{})
+	if !ok || len(s.internalTags) == 0 {
+		return
+	}
+	kept := tags[:0]
+	for _, tag := range tags {
+		if name, _ := tag.(string); !s.internalTags[name] {
+			kept = append(kept, tag)
+		}
+	}
+	setList(operation, "tags", kept)
+}
+
+// stripParameters removes the internal parameters of a path item or operation
+func (s *stripper) stripParameters(object map[string]interface{}) {
+	parameters, ok := object["parameters"].([]interface{})
+	if !ok {
+		return
+	}
+	kept := parameters[:0]
+	for _, parameter := range parameters {
+		if s.internal(parameter) {
+			s.removed++
+			continue
+		}
+		kept = append(kept, parameter)
+	}
+	setList(object, "parameters", kept)
+}
+
+// setList sets a list, or removes it when it is empty
+func setList(object map[string]interface{}, key string, list []interface{}) {
+	if len(list) == 0 {
+		delete(object, key)
+		return
+	}
+	object[key] = list
+}
+
+// stripSchemas walks the document and removes the internal properties and composition members of the schemas.
+// Named maps hold objects under arbitrary names rather than keywords.
+func (s *stripper) stripSchemas(node interface{}, named bool) {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		if named {
+			for _, child := range node {
+				s.stripSchemas(child, false)
+			}
+			return
+		}
+		if properties, ok := node["properties"].(map[string]interface{}); ok {
+			for name, property := range properties {
+				if s.internal(property) {
+					delete(properties, name)
+					removeRequired(node, name)
+					s.removed++
+				}
+			}
+		}
+		for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+			if members, ok := node[keyword].([]interface{}); ok {
+				kept := members[:0]
+				for _, member := range members {
+					if s.internal(member) {
+						s.removed++
+						continue
+					}
+					kept = append(kept, member)
+				}
+				node[keyword] = kept
+			}
+		}
+		for key, child := range node {
+			if !literalKeys[key] && !strings.HasPrefix(key, "x-") {
+				s.stripSchemas(child, namedKeys[key])
+			}
+		}
+	case []interface{}:
+		for _, child := range node {
+			s.stripSchemas(child, false)
+		}
+	}
+}
+
+// removeRequired removes a property from the required properties of a schema
+func removeRequired(schema map[string]interface{}, name string) {
+	required, ok := schema["required"].([]interface{})
+	if !ok {
+		return
+	}
+	kept := make([]interface{}, 0, len(required))
+	for _, item := range required {
+		if item != name {
+			kept = append(kept, item)
+		}
+	}
+	if len(kept) == 0 {
+		delete(schema, "required")
+		return
+	}
+	schema["required"] = kept
+}
+
+// referencedComponents lists the components reachable from the rest of the document,
+// as pointers such as "components/schemas/Pet" or "definitions/Pet"
+func referencedComponents(doc Document) map[string]bool {
+	var roots []interface{}
+	for key, value := range doc {
+		if !componentSections[key] {
+			roots = append(roots, value)
+		}
+	}
+	return referencedFrom(doc, roots...)
+}
+
+// referencedFrom lists the components reachable from some nodes of a document
+func referencedFrom(doc Document, roots ...interface{}) map[string]bool {
+	referenced := make(map[string]bool)
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch node := node.(type) {
+		case map[string]interface{}:
+			if ref, ok := node["$ref"].(string); ok {
+				if component := componentOf(ref); component != "" && !referenced[component] {
+					referenced[component] = true
+					if target, ok := Resolve(doc, "#/"+component); ok {
+						walk(target)
+					}
+				}
+			}
+			for _, child := range node {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range node {
+				walk(child)
+			}
+		}
+	}
+
+	for _, root := range roots {
+		walk(root)
+	}
+	return referenced
+}
+
+// componentOf returns the component a local reference points into, empty for other references
+func componentOf(ref string) string {
+	if !strings.HasPrefix(ref, "#/") {
+		return ""
+	}
+	parts := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+	switch {
+	case parts[0] == "components" && len(parts) >= 3:
+		return strings.Join(parts[:3], "/")
+	case componentSections[parts[0]] && parts[0] != "components" && len(parts) >= 2:
+		return strings.Join(parts[:2], "/")
+	}
+	return ""
+}
+
+// removeComponent deletes a component given as a pointer such as "components/schemas/Pet"
+func removeComponent(doc Document, component string) bool {
+	parts := strings.Split(component, "/")
+	parent, ok := Resolve(doc, "#/"+strings.Join(parts[:len(parts)-1], "/"))
+	if !ok {
+		return false
+	}
+	name := unescapePointer(parts[len(parts)-1])
+	if _, ok := parent[name]; !ok {
+		return false
+	}
+	delete(parent, name)
+
+	// Drop the sections left empty, such as components.parameters
+	if len(parent) == 0 {
+		if len(parts) == 2 {
+			delete(doc, parts[0])
+		} else if components, ok := doc["components"].(map[string]interface{}); ok {
+			delete(components, parts[1])
+		}
+	}
+	return true
+}
+
+// flagged tells whether an extension value is true
+func flagged(value interface{}) bool {
+	switch value := value.(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	}
+	return false
+}
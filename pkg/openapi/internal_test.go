@@ -0,0 +1,191 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+const internalFixture = `
+openapi: 3.0.3
+info: {title: Shop, version: "1"}
+tags:
+  - name: users
+  - name: admin
+    x-internal: true
+paths:
+  /users:
+    parameters:
+      - $ref: "#/components/parameters/Debug"
+      - name: page
+        in: query
+        schema: {type: integer}
+    get:
+      tags: [users, admin]
+      parameters:
+        - name: trace
+          in: header
+          x-internal: true
+          schema: {type: string}
+      responses:
+        200:
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/User"}
+    post:
+      x-internal: true
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/UserImport"}
+      responses:
+        201: {description: created}
+  /admin/stats:
+    get:
+      tags: [admin]
+      responses:
+        200:
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Stats"}
+  /internal:
+    x-internal: true
+    get:
+      responses:
+        200: {description: ok}
+components:
+  parameters:
+    Debug:
+      name: debug
+      in: query
+      x-internal: true
+      schema: {type: boolean}
+  schemas:
+    User:
+      type: object
+      required: [id, passwordHash]
+      properties:
+        id: {type: integer}
+        passwordHash: {type: string, x-internal: true}
+        role: {$ref: "#/components/schemas/Role"}
+        default: {type: string, x-internal: "true"}
+    Role:
+      type: string
+      x-internal: true
+    UserImport: {type: object}
+    Stats: {type: object}
+    AdminUser:
+      type: object
+      x-internal: true
+      properties:
+        permissions: {$ref: "#/components/schemas/Permission"}
+    Permission: {type: string}
+    Unused: {type: object}
+`
+
+func TestStripInternal(t *testing.T) {
+	doc, err := Parse([]byte(internalFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := StripInternal(doc, "")
+
+	present := func(pointer string) bool {
+		_, ok := Resolve(doc, pointer)
+		return ok
+	}
+	tests := []struct {
+		name    string
+		pointer string
+		want    bool
+	}{
+		// Operations and path items
+		{"public operation", "#/paths/~1users/get", true},
+		{"internal operation", "#/paths/~1users/post", false},
+		{"internal path item", "#/paths/~1internal", false},
+		{"operation with internal tags only", "#/paths/~1admin~1stats", false},
+
+		// Schema properties
+		{"public property", "#/components/schemas/User/properties/id", true},
+		{"internal property", "#/components/schemas/User/properties/passwordHash", false},
+		{"property referencing an internal schema", "#/components/schemas/User/properties/role", false},
+		{"property named default", "#/components/schemas/User/properties/default", false},
+
+		// Components
+		{"internal schema", "#/components/schemas/AdminUser", false},
+		{"internal referenced schema", "#/components/schemas/Role", false},
+		{"internal parameter", "#/components/parameters", false},
+		{"schema of a removed operation", "#/components/schemas/UserImport", false},
+		{"schema of an internally tagged operation", "#/components/schemas/Stats", false},
+		{"schema only used by an internal schema", "#/components/schemas/Permission", false},
+		{"schema already unreferenced", "#/components/schemas/Unused", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := present(tt.pointer); got != tt.want {
+				t.Errorf("%s present = %v, want %v", tt.pointer, got, tt.want)
+			}
+		})
+	}
+
+	users, _ := Resolve(doc, "#/paths/~1users")
+	get := users["get"].(map[string]interface{})
+	lists := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"document tags", doc["tags"], []interface{}{map[string]interface{}{"name": "users"}}},
+		{"operation tags", get["tags"], []interface{}{"users"}},
+		{"operation parameters", get["parameters"], nil},
+		{"path parameters", users["parameters"], []interface{}{map[string]interface{}{"name": "page", "in": "query", "schema": map[string]interface{}{"type": "integer"}}}},
+		{"required properties", doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})["User"].(map[string]interface{})["required"], []interface{}{"id"}},
+	}
+	for _, tt := range lists {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+
+	// 12 flagged elements and components, 3 components pruned
+	if removed != 15 {
+		t.Errorf("removed = %d, want 15", removed)
+	}
+}
+
+func TestStripInternalSwagger2(t *testing.T) {
+	doc, err := Parse([]byte(`
+swagger: "2.0"
+info: {title: Shop, version: "1"}
+paths:
+  /users:
+    get:
+      responses:
+        200:
+          description: ok
+          schema: {$ref: "#/definitions/User"}
+definitions:
+  User:
+    type: object
+    properties:
+      secret: {type: string, x-private: true}
+  Audit:
+    type: object
+    x-private: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed := StripInternal(doc, "x-private"); removed != 2 {
+		t.Errorf("removed = %d, want 2", removed)
+	}
+	if _, ok := Resolve(doc, "#/definitions/Audit"); ok {
+		t.Error("internal definition kept")
+	}
+	if _, ok := Resolve(doc, "#/definitions/User/properties/secret"); ok {
+		t.Error("internal property kept")
+	}
+}
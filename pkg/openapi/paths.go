@@ -0,0 +1,99 @@
+package openapi
+
+import (
+	"net/url"
+	"strings"
+)
+
+// MatchPath finds the path template of the document that matches a request path,
+// such as "/pets/{petId}" for "/pets/42", and returns the path parameter values.
+// Request paths may include the base path of one of the document servers.
+// When several templates match, the one with the most literal segments wins.
+func MatchPath(doc Document, requestPath string) (string, map[string]string, bool) {
+	paths, ok := doc["paths"].(map[string]interface{})
+	if !ok {
+		return "", nil, false
+	}
+
+	for _, candidate := range candidatePaths(doc, requestPath) {
+		bestTemplate, bestParams, bestScore := "", map[string]string(nil), -1
+		for template := range paths {
+			params, score, ok := matchTemplate(template, candidate)
+			if !ok {
+				continue
+			}
+			if score > bestScore || (score == bestScore && template < bestTemplate) {
+				bestTemplate, bestParams, bestScore = template, params, score
+			}
+		}
+		if bestScore >= 0 {
+			return bestTemplate, bestParams, true
+		}
+	}
+	return "", nil, false
+}
+
+// candidatePaths lists the request path followed by the path stripped of each server base path
+func candidatePaths(doc Document, requestPath string) []string {
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	candidates := []string{requestPath}
+
+	servers, _ := doc["servers"].([]interface{})
+	for _, raw := range servers {
+		server, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		serverURL, _ := server["url"].(string)
+		parsed, err := url.Parse(serverURL)
+		if err != nil {
+			continue
+		}
+		base := strings.TrimSuffix(parsed.Path, "/")
+		if base != "" && strings.HasPrefix(requestPath, base+"/") {
+			candidates = append(candidates, strings.TrimPrefix(requestPath, base))
+		}
+	}
+	return candidates
+}
+
+// matchTemplate compares a path template with a concrete path segment by segment
+func matchTemplate(template, path string) (map[string]string, int, bool) {
+	templateParts := strings.Split(strings.Trim(template, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(templateParts) != len(pathParts) {
+		return nil, 0, false
+	}
+
+	params := make(map[string]string)
+	score := 0
+	for i, part := range templateParts {
+		open := strings.Index(part, "{")
+		if open < 0 {
+			if part != pathParts[i] {
+				return nil, 0, false
+			}
+			score++
+			continue
+		}
+
+		// Segments may mix literals and parameters, such as "{id}.json"
+		close := strings.Index(part, "}")
+		if close < open {
+			return nil, 0, false
+		}
+		prefix, suffix := part[:open], part[close+1:]
+		value := pathParts[i]
+		if !strings.HasPrefix(value, prefix) || !strings.HasSuffix(value, suffix) || len(value) <= len(prefix)+len(suffix) {
+			return nil, 0, false
+		}
+		decoded, err := url.PathUnescape(value[len(prefix) : len(value)-len(suffix)])
+		if err != nil {
+			return nil, 0, false
+		}
+		params[part[open+1:close]] = decoded
+	}
+	return params, score, true
+}
@@ -0,0 +1,675 @@
+package overlay
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// node is a value selected by a JSONPath query, with the container holding it
+type node struct {
+	value interface{}
+
+	// Map or list holding the value, nil for the root
+	parent interface{}
+	key    string
+	index  int
+}
+
+// Path is a compiled JSONPath query (RFC 9535). It supports member names, wildcards, indexes,
+// slices, descendants and filters with comparisons, existence tests and logical operators.
+type Path struct {
+	source   string
+	segments []segment
+}
+
+// segment selects children, or descendants, of the nodes selected so far
+type segment struct {
+	descendant bool
+	selectors  []selector
+}
+
+// selector selects children of a node
+type selector func(n node, root interface{}) []node
+
+// ParsePath compiles a JSONPath query such as "$.paths['/pets'].get" or "$..[?@.deprecated == true]"
+func ParsePath(query string) (*Path, error) {
+	p := &parser{input: query}
+	p.skipSpaces()
+	if !p.consume("$") {
+		return nil, fmt.Errorf("invalid JSONPath %q: must start with $", query)
+	}
+	segments, err := p.segments()
+	if err == nil {
+		p.skipSpaces()
+		if p.pos < len(p.input) {
+			err = p.errorf("unexpected character %q", p.input[p.pos])
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath %q: %v", query, err)
+	}
+	return &Path{source: query, segments: segments}, nil
+}
+
+// String returns the source of the query
+func (p *Path) String() string {
+	return p.source
+}
+
+// selectNodes evaluates the query against a document
+func (p *Path) selectNodes(root interface{}) []node {
+	return evaluate(p.segments, node{value: root}, root)
+}
+
+// evaluate applies segments to a starting node
+func evaluate(segments []segment, start node, root interface{}) []node {
+	nodes := []node{start}
+	for _, seg := range segments {
+		var next []node
+		for _, n := range nodes {
+			targets := []node{n}
+			if seg.descendant {
+				targets = descendants(n)
+			}
+			for _, target := range targets {
+				for _, sel := range seg.selectors {
+					next = append(next, sel(target, root)...)
+				}
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// children lists the children of a node in document order, map keys being sorted
+func children(n node) []node {
+	switch value := n.value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		out := make([]node, 0, len(keys))
+		for _, key := range keys {
+			out = append(out, node{value: value[key], parent: value, key: key})
+		}
+		return out
+	case []interface{}:
+		out := make([]node, 0, len(value))
+		for i, item := range value {
+			out = append(out, node{value: item, parent: value, index: i})
+		}
+		return out
+	}
+	return nil
+}
+
+// descendants lists a node followed by all its descendants
+func descendants(n node) []node {
+	out := []node{n}
+	for _, child := range children(n) {
+		out = append(out, descendants(child)...)
+	}
+	return out
+}
+
+// nameSelector selects a member of an object
+func nameSelector(name string) selector {
+	return func(n node, _ interface{}) []node {
+		if object, ok := n.value.(map[string]interface{}); ok {
+			if value, ok := object[name]; ok {
+				return []node{{value: value, parent: object, key: name}}
+			}
+		}
+		return nil
+	}
+}
+
+// wildcardSelector selects all the children of a node
+func wildcardSelector(n node, _ interface{}) []node {
+	return children(n)
+}
+
+// indexSelector selects an item of a list, counting from the end when negative
+func indexSelector(index int) selector {
+	return func(n node, _ interface{}) []node {
+		list, ok := n.value.([]interface{})
+		if !ok {
+			return nil
+		}
+		i := index
+		if i < 0 {
+			i += len(list)
+		}
+		if i < 0 || i >= len(list) {
+			return nil
+		}
+		return []node{{value: list[i], parent: list, index: i}}
+	}
+}
+
+// sliceSelector selects the items of a list between two bounds, as in Python
+func sliceSelector(start, end *int, step int) selector {
+	return func(n node, _ interface{}) []node {
+		list, ok := n.value.([]interface{})
+		if !ok || step == 0 {
+			return nil
+		}
+		length := len(list)
+		normalize := func(bound *int, fallback int) int {
+			if bound == nil {
+				return fallback
+			}
+			if *bound < 0 {
+				return *bound + length
+			}
+			return *bound
+		}
+
+		var out []node
+		if step > 0 {
+			lower := max(min(normalize(start, 0), length), 0)
+			upper := max(min(normalize(end, length), length), 0)
+			for i := lower; i < upper; i += step {
+				out = append(out, node{value: list[i], parent: list, index: i})
+			}
+			return out
+		}
+		upper := max(min(normalize(start, length-1), length-1), -1)
+		lower := max(min(normalize(end, -length-1), length-1), -1)
+		for i := upper; i > lower; i += step {
+			out = append(out, node{value: list[i], parent: list, index: i})
+		}
+		return out
+	}
+}
+
+// filterSelector selects the children of a node matching a logical expression
+func filterSelector(filter expression) selector {
+	return func(n node, root interface{}) []node {
+		var out []node
+		for _, child := range children(n) {
+			if filter.test(child, root) {
+				out = append(out, child)
+			}
+		}
+		return out
+	}
+}
+
+// expression is a logical expression of a filter
+type expression interface {
+	test(current node, root interface{}) bool
+}
+
+type orExpression []expression
+type andExpression []expression
+type notExpression struct{ operand expression }
+
+// existenceExpression is true when a query selects at least one node
+type existenceExpression struct {
+	relative bool
+	segments []segment
+}
+
+// comparisonExpression compares two values
+type comparisonExpression struct {
+	left, right comparable
+	operator    string
+}
+
+// comparable is a literal or the value selected by a singular query
+type comparable struct {
+	literal  interface{}
+	query    bool
+	relative bool
+	segments []segment
+}
+
+func (e orExpression) test(current node, root interface{}) bool {
+	for _, operand := range e {
+		if operand.test(current, root) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e andExpression) test(current node, root interface{}) bool {
+	for _, operand := range e {
+		if !operand.test(current, root) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e notExpression) test(current node, root interface{}) bool {
+	return !e.operand.test(current, root)
+}
+
+func (e existenceExpression) test(current node, root interface{}) bool {
+	start := node{value: root}
+	if e.relative {
+		start = current
+	}
+	return len(evaluate(e.segments, start, root)) > 0
+}
+
+func (e comparisonExpression) test(current node, root interface{}) bool {
+	left, leftOK := e.left.resolve(current, root)
+	right, rightOK := e.right.resolve(current, root)
+
+	switch e.operator {
+	case "==":
+		return equal(left, leftOK, right, rightOK)
+	case "!=":
+		return !equal(left, leftOK, right, rightOK)
+	case "<":
+		return less(left, leftOK, right, rightOK)
+	case ">":
+		return less(right, rightOK, left, leftOK)
+	case "<=":
+		return less(left, leftOK, right, rightOK) || equal(left, leftOK, right, rightOK)
+	case ">=":
+		return less(right, rightOK, left, leftOK) || equal(left, leftOK, right, rightOK)
+	}
+	return false
+}
+
+// resolve returns the value of a comparable, false when its query selects nothing
+func (c comparable) resolve(current node, root interface{}) (interface{}, bool) {
+	if !c.query {
+		return c.literal, true
+	}
+	start := node{value: root}
+	if c.relative {
+		start = current
+	}
+	nodes := evaluate(c.segments, start, root)
+	if len(nodes) != 1 {
+		return nil, false
+	}
+	return nodes[0].value, true
+}
+
+// equal compares two values, numbers of different types being equal when their values are
+func equal(left interface{}, leftOK bool, right interface{}, rightOK bool) bool {
+	if !leftOK || !rightOK {
+		return leftOK == rightOK
+	}
+	if l, ok := number(left); ok {
+		r, ok := number(right)
+		return ok && l == r
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// less compares two numbers or two strings
+func less(left interface{}, leftOK bool, right interface{}, rightOK bool) bool {
+	if !leftOK || !rightOK {
+		return false
+	}
+	if l, ok := number(left); ok {
+		r, ok := number(right)
+		return ok && l < r
+	}
+	l, lok := left.(string)
+	r, rok := right.(string)
+	return lok && rok && l < r
+}
+
+// number converts the numeric values decoded from JSON or YAML
+func number(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+// parser reads a JSONPath query
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && strings.ContainsRune(" \t\n\r", rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *parser) peek(prefix string) bool {
+	return strings.HasPrefix(p.input[p.pos:], prefix)
+}
+
+func (p *parser) consume(prefix string) bool {
+	if p.peek(prefix) {
+		p.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+// segments reads the segments following a root or current node identifier
+func (p *parser) segments() ([]segment, error) {
+	var segments []segment
+	for {
+		// Spaces are allowed before brackets and dots, but must not swallow the operators of filters
+		save := p.pos
+		p.skipSpaces()
+		switch {
+		case p.consume(".."):
+			seg := segment{descendant: true}
+			switch {
+			case p.peek("["):
+				selectors, err := p.bracket()
+				if err != nil {
+					return nil, err
+				}
+				seg.selectors = selectors
+			case p.consume("*"):
+				seg.selectors = []selector{wildcardSelector}
+			default:
+				name, err := p.memberName()
+				if err != nil {
+					return nil, err
+				}
+				seg.selectors = []selector{nameSelector(name)}
+			}
+			segments = append(segments, seg)
+		case p.consume("."):
+			if p.consume("*") {
+				segments = append(segments, segment{selectors: []selector{wildcardSelector}})
+				continue
+			}
+			name, err := p.memberName()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment{selectors: []selector{nameSelector(name)}})
+		case p.peek("["):
+			selectors, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment{selectors: selectors})
+		default:
+			p.pos = save
+			return segments, nil
+		}
+	}
+}
+
+// memberName reads the name of a dot notation, such as "paths" or "x-logo"
+func (p *parser) memberName() (string, error) {
+	start := p.pos
+	for p.pos < len(p.input) {
+		r, size := utf8.DecodeRuneInString(p.input[p.pos:])
+		if r == '_' || r == '-' || r == '$' || r >= 0x80 || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			p.pos += size
+			continue
+		}
+		break
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a member name")
+	}
+	return p.input[start:p.pos], nil
+}
+
+// bracket reads a list of selectors between brackets
+func (p *parser) bracket() ([]selector, error) {
+	p.consume("[")
+	var selectors []selector
+	for {
+		p.skipSpaces()
+		sel, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, sel)
+		p.skipSpaces()
+		if p.consume("]") {
+			return selectors, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected , or ]")
+		}
+	}
+}
+
+// selector reads a selector of a bracket
+func (p *parser) selector() (selector, error) {
+	switch {
+	case p.peek("'") || p.peek(`"`):
+		name, err := p.stringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return nameSelector(name), nil
+	case p.consume("*"):
+		return wildcardSelector, nil
+	case p.consume("?"):
+		p.skipSpaces()
+		filter, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return filterSelector(filter), nil
+	}
+
+	// Index or slice
+	var bounds [3]*int
+	part := 0
+	for {
+		p.skipSpaces()
+		if value, ok := p.integer(); ok {
+			bounds[part] = &value
+		}
+		p.skipSpaces()
+		if part < 2 && p.consume(":") {
+			part++
+			continue
+		}
+		break
+	}
+	if part == 0 {
+		if bounds[0] == nil {
+			return nil, p.errorf("expected a selector")
+		}
+		return indexSelector(*bounds[0]), nil
+	}
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	return sliceSelector(bounds[0], bounds[1], step), nil
+}
+
+// integer reads an optional integer
+func (p *parser) integer() (int, bool) {
+	start := p.pos
+	p.consume("-")
+	for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+		p.pos++
+	}
+	value, err := strconv.Atoi(p.input[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+	return value, true
+}
+
+// stringLiteral reads a quoted string with JSON escapes
+func (p *parser) stringLiteral() (string, error) {
+	quote := p.input[p.pos]
+	p.pos++
+	var out strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return out.String(), nil
+		case c == '\\' && p.pos+1 < len(p.input):
+			p.pos++
+			escapes := map[byte]string{'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t", '/': "/", '\\': "\\", '\'': "'", '"': `"`}
+			if e, ok := escapes[p.input[p.pos]]; ok {
+				out.WriteString(e)
+				p.pos++
+				continue
+			}
+			if p.input[p.pos] == 'u' && p.pos+5 <= len(p.input) {
+				code, err := strconv.ParseUint(p.input[p.pos+1:p.pos+5], 16, 32)
+				if err != nil {
+					return "", p.errorf("invalid unicode escape")
+				}
+				out.WriteRune(rune(code))
+				p.pos += 5
+				continue
+			}
+			return "", p.errorf("invalid escape")
+		default:
+			out.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// or reads a disjunction of a filter
+func (p *parser) or() (expression, error) {
+	var operands orExpression
+	for {
+		operand, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+		p.skipSpaces()
+		if !p.consume("||") {
+			break
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return operands, nil
+}
+
+// and reads a conjunction of a filter
+func (p *parser) and() (expression, error) {
+	var operands andExpression
+	for {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+		p.skipSpaces()
+		if !p.consume("&&") {
+			break
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return operands, nil
+}
+
+// unary reads a negation, a parenthesized expression, a comparison or an existence test
+func (p *parser) unary() (expression, error) {
+	p.skipSpaces()
+	if p.peek("!") && !p.peek("!=") {
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpression{operand}, nil
+	}
+	if p.consume("(") {
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return inner, nil
+	}
+
+	left, err := p.comparable()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	for _, operator := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(operator) {
+			p.skipSpaces()
+			right, err := p.comparable()
+			if err != nil {
+				return nil, err
+			}
+			return comparisonExpression{left: left, right: right, operator: operator}, nil
+		}
+	}
+	if !left.query {
+		return nil, p.errorf("expected a comparison")
+	}
+	return existenceExpression{relative: left.relative, segments: left.segments}, nil
+}
+
+// comparable reads a literal or a query of a filter
+func (p *parser) comparable() (comparable, error) {
+	switch {
+	case p.consume("@"), p.consume("$"):
+		relative := p.input[p.pos-1] == '@'
+		segments, err := p.segments()
+		if err != nil {
+			return comparable{}, err
+		}
+		return comparable{query: true, relative: relative, segments: segments}, nil
+	case p.peek("'") || p.peek(`"`):
+		value, err := p.stringLiteral()
+		return comparable{literal: value}, err
+	case p.consume("true"):
+		return comparable{literal: true}, nil
+	case p.consume("false"):
+		return comparable{literal: false}, nil
+	case p.consume("null"):
+		return comparable{literal: nil}, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && strings.ContainsRune("+-.eE0123456789", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil || math.IsInf(value, 0) {
+		p.pos = start
+		return comparable{}, p.errorf("expected a value")
+	}
+	return comparable{literal: value}, nil
+}
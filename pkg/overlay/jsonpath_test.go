@@ -0,0 +1,164 @@
+package overlay
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+const storeFixture = `
+store:
+  book:
+    - {category: reference, author: Rees, title: Sayings, price: 8.95}
+    - {category: fiction, author: Waugh, title: Sword, price: 12.99}
+    - {category: fiction, author: Melville, title: Moby Dick, isbn: 0-553, price: 8.99}
+    - {category: fiction, author: Tolkien, title: Lord, isbn: 0-395, price: 22.99, tags: [epic]}
+  bicycle: {color: red, price: 399, available: true, rental: null}
+"a b": 1
+"x-logo": {url: logo.png}
+`
+
+func parseFixture(t *testing.T, content string) openapi.Document {
+	t.Helper()
+	doc, err := openapi.Parse([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// selectValues returns the values selected by a query
+func selectValues(t *testing.T, doc openapi.Document, query string) []interface{} {
+	t.Helper()
+	path, err := ParsePath(query)
+	if err != nil {
+		t.Fatalf("ParsePath(%q): %v", query, err)
+	}
+	values := []interface{}{}
+	for _, n := range path.selectNodes(map[string]interface{}(doc)) {
+		values = append(values, n.value)
+	}
+	return values
+}
+
+func TestSelectors(t *testing.T) {
+	doc := parseFixture(t, storeFixture)
+	authors := []interface{}{"Rees", "Waugh", "Melville", "Tolkien"}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []interface{}
+	}{
+		// Names
+		{"dot name", "$.store.bicycle.color", []interface{}{"red"}},
+		{"dashed name", "$.x-logo.url", []interface{}{"logo.png"}},
+		{"quoted name", "$['a b']", []interface{}{1}},
+		{"double quoted name", `$["store"]["bicycle"]["color"]`, []interface{}{"red"}},
+		{"missing name", "$.store.car", []interface{}{}},
+		{"name of a list", "$.store.book.title", []interface{}{}},
+
+		// Indexes
+		{"index", "$.store.book[0].author", []interface{}{"Rees"}},
+		{"negative index", "$.store.book[-1].author", []interface{}{"Tolkien"}},
+		{"index out of range", "$.store.book[4]", []interface{}{}},
+		{"negative index out of range", "$.store.book[-5]", []interface{}{}},
+		{"several selectors", "$.store.book[2,0].author", []interface{}{"Melville", "Rees"}},
+
+		// Wildcards, map members being sorted by name
+		{"wildcard on an object", "$.store.bicycle.*", []interface{}{true, "red", 399, nil}},
+		{"wildcard on a list", "$.store.book[*].author", authors},
+
+		// Slices
+		{"slice", "$.store.book[1:3].author", []interface{}{"Waugh", "Melville"}},
+		{"open slice", "$.store.book[2:].author", []interface{}{"Melville", "Tolkien"}},
+		{"negative bounds", "$.store.book[-3:-1].author", []interface{}{"Waugh", "Melville"}},
+		{"step", "$.store.book[::2].author", []interface{}{"Rees", "Melville"}},
+		{"negative step", "$.store.book[::-1].author", []interface{}{"Tolkien", "Melville", "Waugh", "Rees"}},
+		{"negative step with bounds", "$.store.book[3:0:-2].author", []interface{}{"Tolkien", "Waugh"}},
+		{"zero step", "$.store.book[::0]", []interface{}{}},
+		{"slice out of range", "$.store.book[10:20]", []interface{}{}},
+
+		// Descendants
+		{"descendant name", "$..author", authors},
+		{"descendant order", "$..price", []interface{}{399, 8.95, 12.99, 8.99, 22.99}},
+		{"descendant index", "$..tags[0]", []interface{}{"epic"}},
+		{"descendant wildcard", "$.store.bicycle..*", []interface{}{true, "red", 399, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectValues(t, doc, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilters(t *testing.T) {
+	doc := parseFixture(t, storeFixture)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []interface{}
+	}{
+		// Comparisons
+		{"less than", "$.store.book[?@.price < 10].title", []interface{}{"Sayings", "Moby Dick"}},
+		{"greater or equal", "$.store.book[?@.price >= 12.99].title", []interface{}{"Sword", "Lord"}},
+		{"string equality", "$.store.book[?@.category == 'reference'].title", []interface{}{"Sayings"}},
+		{"string ordering", `$.store.book[?@.author < "N"].title`, []interface{}{"Moby Dick"}},
+		{"integer and float", "$.store[?@.price == 399.0].color", []interface{}{"red"}},
+		{"boolean", "$.store[?@.available == true].color", []interface{}{"red"}},
+		{"null", "$.store[?@.rental == null].color", []interface{}{"red"}},
+		{"absolute query", "$.store.book[?@.author == $.store.book[0].author].title", []interface{}{"Sayings"}},
+
+		// Type mismatches and missing values never order
+		{"number and string", "$.store.book[?@.price < 'z'].title", []interface{}{}},
+		{"missing member", "$.store.book[?@.isbn > 0].title", []interface{}{}},
+		{"missing members are equal", "$.store.book[?@.missing == @.other].title", []interface{}{"Sayings", "Sword", "Moby Dick", "Lord"}},
+		{"missing member differs from null", "$.store.book[?@.isbn != null].title", []interface{}{"Sayings", "Sword", "Moby Dick", "Lord"}},
+		{"missing members compare equal", "$.store.book[?@.missing <= @.other].title", []interface{}{"Sayings", "Sword", "Moby Dick", "Lord"}},
+		{"non singular query", "$.store[?@.* == 'red'].price", []interface{}{}},
+
+		// Existence and logical operators
+		{"existence", "$.store.book[?@.isbn].title", []interface{}{"Moby Dick", "Lord"}},
+		{"negated existence", "$.store.book[?!@.isbn].title", []interface{}{"Sayings", "Sword"}},
+		{"and", "$.store.book[?@.category == 'fiction' && @.price > 20].title", []interface{}{"Lord"}},
+		{"or", "$.store.book[?@.author == 'Rees' || @.price > 20].title", []interface{}{"Sayings", "Lord"}},
+		{"precedence", "$.store.book[?@.isbn && @.price < 10 || @.category == 'reference'].title", []interface{}{"Sayings", "Moby Dick"}},
+		{"parentheses", "$.store.book[?@.isbn && (@.price < 10 || @.category == 'reference')].title", []interface{}{"Moby Dick"}},
+		{"negated parentheses", "$.store.book[?!(@.price < 10)].title", []interface{}{"Sword", "Lord"}},
+		{"descendant filter", "$..[?@.color].price", []interface{}{399}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selectValues(t, doc, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"store.book",
+		"$.",
+		"$[",
+		"$[0",
+		"$['unterminated]",
+		"$.store[?@.price <]",
+		"$.store[?@.price == 1",
+		"$.store[?(@.price == 1]",
+		"$.store[?1]",
+		"$.store.book[1:2:x]",
+		"$.info[title]",
+	} {
+		t.Run(query, func(t *testing.T) {
+			if _, err := ParsePath(query); err == nil {
+				t.Errorf("expected an error for %q", query)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Package overlay applies OpenAPI Overlay 1.0 documents, whose actions update or remove
+// the nodes of an OpenAPI document selected by JSONPath queries.
+package overlay
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// Overlay is an OpenAPI Overlay document
+type Overlay struct {
+	Overlay string   `yaml:"overlay"`
+	Info    Info     `yaml:"info"`
+	Extends string   `yaml:"extends,omitempty"`
+	Actions []Action `yaml:"actions"`
+}
+
+// Info identifies an overlay
+type Info struct {
+	Title   string `yaml:"title"`
+	Version string `yaml:"version"`
+}
+
+// Action updates or removes the nodes selected by its target
+type Action struct {
+	Target      string      `yaml:"target"`
+	Description string      `yaml:"description,omitempty"`
+	Update      interface{} `yaml:"update,omitempty"`
+	Remove      bool        `yaml:"remove,omitempty"`
+}
+
+// Result describes the outcome of applying an overlay
+type Result struct {
+	// Targets of the actions that selected nothing
+	UnmatchedTargets []string
+}
+
+// Parse decodes an overlay written in JSON or YAML and checks its required fields
+func Parse(content []byte) (*Overlay, error) {
+	var overlay Overlay
+	if err := yaml.Unmarshal(content, &overlay); err != nil {
+		return nil, fmt.Errorf("error parsing overlay: %v", err)
+	}
+	if !strings.HasPrefix(overlay.Overlay, "1.") {
+		return nil, fmt.Errorf("unsupported overlay version %q, expected 1.x", overlay.Overlay)
+	}
+	if overlay.Info.Title == "" || overlay.Info.Version == "" {
+		return nil, fmt.Errorf("overlay info must have a title and a version")
+	}
+	if len(overlay.Actions) == 0 {
+		return nil, fmt.Errorf("overlay %q has no actions", overlay.Info.Title)
+	}
+	for i, action := range overlay.Actions {
+		overlay.Actions[i].Update = openapi.StringKeys(action.Update)
+		if action.Target == "" {
+			return nil, fmt.Errorf("action %d of overlay %q has no target", i, overlay.Info.Title)
+		}
+		if !action.Remove && action.Update == nil {
+			return nil, fmt.Errorf("action %d of overlay %q neither updates nor removes %s", i, overlay.Info.Title, action.Target)
+		}
+		if _, err := ParsePath(action.Target); err != nil {
+			return nil, fmt.Errorf("action %d of overlay %q: %v", i, overlay.Info.Title, err)
+		}
+	}
+	return &overlay, nil
+}
+
+// Apply runs the actions of an overlay in order, each one seeing the changes of the previous ones.
+// Updates merge objects into the selected objects and append to the selected arrays.
+// Unmatched targets are not errors, as an overlay may be shared by several documents.
+func Apply(doc openapi.Document, overlay *Overlay) (*Result, error) {
+	result := &Result{}
+	for i, action := range overlay.Actions {
+		path, err := ParsePath(action.Target)
+		if err != nil {
+			return nil, fmt.Errorf("action %d: %v", i, err)
+		}
+		nodes := path.selectNodes(doc)
+		if len(nodes) == 0 {
+			result.UnmatchedTargets = append(result.UnmatchedTargets, action.Target)
+			continue
+		}
+
+		if action.Remove {
+			if err := remove(doc, nodes); err != nil {
+				return nil, fmt.Errorf("action %d on %s: %v", i, action.Target, err)
+			}
+			continue
+		}
+		for _, n := range nodes {
+			if err := update(n, action.Update); err != nil {
+				return nil, fmt.Errorf("action %d on %s: %v", i, action.Target, err)
+			}
+		}
+	}
+	return result, nil
+}
+
+// removed marks the items of a list being removed, until the lists are compacted
+type removed struct{}
+
+// remove deletes the selected nodes from their containers
+func remove(doc openapi.Document, nodes []node) error {
+	compact := false
+	for _, n := range nodes {
+		switch parent := n.parent.(type) {
+		case map[string]interface{}:
+			delete(parent, n.key)
+		case []interface{}:
+			// Items are marked first, as removing them would shift the indexes of the other selected items
+			parent[n.index] = removed{}
+			compact = true
+		default:
+			return fmt.Errorf("cannot remove the root of the document")
+		}
+	}
+	if compact {
+		compactLists(doc)
+	}
+	return nil
+}
+
+// compactLists drops the items marked for removal from the lists of a node
+func compactLists(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			value[key] = compactLists(child)
+		}
+	case []interface{}:
+		kept := value[:0]
+		for _, item := range value {
+			if _, ok := item.(removed); !ok {
+				kept = append(kept, compactLists(item))
+			}
+		}
+		return kept
+	}
+	return value
+}
+
+// update merges a value into an object, or appends it to a list
+func update(n node, value interface{}) error {
+	switch target := n.value.(type) {
+	case map[string]interface{}:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot merge a %T into an object", value)
+		}
+		merge(target, object)
+	case []interface{}:
+		// Appending may move the list, so it is stored back into its container
+		list := appendValue(target, value)
+		switch parent := n.parent.(type) {
+		case map[string]interface{}:
+			parent[n.key] = list
+		case []interface{}:
+			parent[n.index] = list
+		}
+	default:
+		return fmt.Errorf("cannot update a %T, only objects and arrays can be updated", n.value)
+	}
+	return nil
+}
+
+// appendValue appends a value to a list, or the items of a list of values
+func appendValue(list []interface{}, value interface{}) []interface{} {
+	if items, ok := value.([]interface{}); ok {
+		return append(list, copyValue(items).([]interface{})...)
+	}
+	return append(list, copyValue(value))
+}
+
+// merge copies the members of an object into another. Objects present in both are merged
+// and lists present in both are concatenated, while other values are replaced.
+func merge(target, source map[string]interface{}) {
+	for key, value := range source {
+		switch existing := target[key].(type) {
+		case map[string]interface{}:
+			if object, ok := value.(map[string]interface{}); ok {
+				merge(existing, object)
+				continue
+			}
+		case []interface{}:
+			if items, ok := value.([]interface{}); ok {
+				target[key] = appendValue(existing, items)
+				continue
+			}
+		}
+		target[key] = copyValue(value)
+	}
+}
+
+// copyValue copies a value so that an update applied to several nodes does not share its maps and lists
+func copyValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			out[key] = copyValue(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, child := range value {
+			out[i] = copyValue(child)
+		}
+		return out
+	}
+	return value
+}
@@ -0,0 +1,209 @@
+package overlay
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+const petsFixture = `
+openapi: 3.0.3
+info: {title: Pets, version: "1", contact: {name: Team}}
+tags:
+  - {name: pets}
+  - {name: admin, x-internal: true}
+  - {name: store}
+  - {name: audit, x-internal: true}
+paths:
+  /pets:
+    get:
+      summary: List pets
+      parameters:
+        - {name: limit, in: query}
+        - {name: debug, in: query, x-internal: true}
+        - {name: trace, in: query, x-internal: true}
+        - {name: page, in: query}
+      responses:
+        200: {description: ok}
+    post:
+      summary: Create a pet
+      deprecated: true
+      responses:
+        201: {description: created}
+`
+
+func applyOverlay(t *testing.T, content string) (openapi.Document, *Result) {
+	t.Helper()
+	doc := parseFixture(t, petsFixture)
+	overlay, err := Parse([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Apply(doc, overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc, result
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions string
+		query   string
+		want    []interface{}
+	}{
+		{
+			"remove list items keeps the other indexes",
+			`[{target: "$.paths['/pets'].get.parameters[?@.x-internal]", remove: true}]`,
+			"$.paths['/pets'].get.parameters[*].name",
+			[]interface{}{"limit", "page"},
+		},
+		{
+			"remove items selected by index",
+			`[{target: "$.tags[0,2]", remove: true}]`,
+			"$.tags[*].name",
+			[]interface{}{"admin", "audit"},
+		},
+		{
+			"remove items in several lists",
+			`[{target: "$..[?@.x-internal == true]", remove: true}]`,
+			"$..name",
+			[]interface{}{"Team", "limit", "page", "pets", "store"},
+		},
+		{
+			"remove a member",
+			`[{target: "$.paths['/pets'].post", remove: true}]`,
+			"$.paths['/pets'].*.summary",
+			[]interface{}{"List pets"},
+		},
+		{
+			"merge into an object",
+			`[{target: "$.info", update: {description: Pets API, contact: {email: team@example.com}}}]`,
+			"$.info['description','contact']",
+			[]interface{}{"Pets API", map[string]interface{}{"name": "Team", "email": "team@example.com"}},
+		},
+		{
+			"replace scalars",
+			`[{target: "$.info", update: {title: Pet Store, version: 2}}]`,
+			"$.info['title','version']",
+			[]interface{}{"Pet Store", 2},
+		},
+		{
+			"replace a scalar with an object",
+			`[{target: "$.paths['/pets'].post", update: {deprecated: {since: "2"}}}]`,
+			"$.paths['/pets'].post.deprecated",
+			[]interface{}{map[string]interface{}{"since": "2"}},
+		},
+		{
+			"append to a list",
+			`[{target: "$.tags", update: {name: orders}}]`,
+			"$.tags[-1].name",
+			[]interface{}{"orders"},
+		},
+		{
+			"concatenate lists",
+			`[{target: "$.paths['/pets'].get", update: {parameters: [{name: sort, in: query}]}}]`,
+			"$.paths['/pets'].get.parameters[*].name",
+			[]interface{}{"limit", "debug", "trace", "page", "sort"},
+		},
+		{
+			"update several nodes",
+			`[{target: "$.paths.*[?@.summary]", update: {x-audited: true}}]`,
+			"$..x-audited",
+			[]interface{}{true, true},
+		},
+		{
+			"actions see the previous changes",
+			`[{target: "$.paths['/pets'].get", update: {x-beta: true}}, {target: "$.paths.*[?@.x-beta]", update: {summary: Beta}}]`,
+			"$.paths['/pets'].get.summary",
+			[]interface{}{"Beta"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, result := applyOverlay(t, "overlay: 1.0.0\ninfo: {title: Test, version: 1}\nactions: "+tt.actions)
+			if len(result.UnmatchedTargets) != 0 {
+				t.Errorf("unmatched targets = %v", result.UnmatchedTargets)
+			}
+			if got := selectValues(t, doc, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyUnmatchedTargets(t *testing.T) {
+	doc, result := applyOverlay(t, `
+overlay: 1.0.0
+info: {title: Test, version: 1}
+actions:
+  - {target: "$.paths['/orders']", remove: true}
+  - {target: "$.paths['/pets'].delete", update: {summary: Delete}}
+  - {target: "$.info", update: {x-checked: true}}
+`)
+	want := []string{"$.paths['/orders']", "$.paths['/pets'].delete"}
+	if !reflect.DeepEqual(result.UnmatchedTargets, want) {
+		t.Errorf("unmatched targets = %v, want %v", result.UnmatchedTargets, want)
+	}
+	if got := selectValues(t, doc, "$.info.x-checked"); !reflect.DeepEqual(got, []interface{}{true}) {
+		t.Errorf("matched action not applied: %v", got)
+	}
+}
+
+func TestApplyCopiesUpdates(t *testing.T) {
+	doc, _ := applyOverlay(t, `
+overlay: 1.0.0
+info: {title: Test, version: 1}
+actions:
+  - {target: "$.paths['/pets'].*", update: {x-owner: {team: pets}}}
+`)
+	owners := selectValues(t, doc, "$.paths['/pets'].*.x-owner")
+	owners[0].(map[string]interface{})["team"] = "changed"
+	if got := selectValues(t, doc, "$.paths['/pets'].*.x-owner.team"); !reflect.DeepEqual(got, []interface{}{"changed", "pets"}) {
+		t.Errorf("updated nodes share their values: %v", got)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		actions string
+	}{
+		{"merge a scalar into an object", `[{target: "$.info", update: 1}]`},
+		{"update a scalar", `[{target: "$.info.title", update: {a: 1}}]`},
+		{"remove the root", `[{target: "$", remove: true}]`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			overlay, err := Parse([]byte("overlay: 1.0.0\ninfo: {title: Test, version: 1}\nactions: " + tt.actions))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Apply(parseFixture(t, petsFixture), overlay); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+	}{
+		{"invalid yaml", "overlay: ["},
+		{"unsupported version", "overlay: 2.0.0\ninfo: {title: T, version: 1}\nactions: [{target: $, remove: true}]"},
+		{"missing info", "overlay: 1.0.0\nactions: [{target: $, remove: true}]"},
+		{"no actions", "overlay: 1.0.0\ninfo: {title: T, version: 1}"},
+		{"missing target", "overlay: 1.0.0\ninfo: {title: T, version: 1}\nactions: [{remove: true}]"},
+		{"no update nor remove", "overlay: 1.0.0\ninfo: {title: T, version: 1}\nactions: [{target: $.info}]"},
+		{"invalid target", "overlay: 1.0.0\ninfo: {title: T, version: 1}\nactions: [{target: info, remove: true}]"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.content)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
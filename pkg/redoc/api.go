@@ -0,0 +1,80 @@
+package redoc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/BombartSimon/redokube/pkg/mockers"
+)
+
+// Bounds of the fixtures generated by a single request, which are built in memory
+const (
+	defaultFixtureCount = 10
+	maxFixtureCount     = 1000
+	maxFixtureDuration  = 10 * time.Second
+)
+
+// handleFixtures exports generated instances of a component schema, such as
+// /api/v1/specs/{namespace}/{name}/fixtures?schema=Pet&count=100&format=ndjson.
+// The spec seed is used so the same request always returns the same data. Requests taking
+// longer than 10 seconds to generate, such as many instances of deep schemas, are rejected.
+func (s *Server) handleFixtures(w http.ResponseWriter, r *http.Request) {
+	key, specInfo := s.requestedSpec(r)
+	name, ok := key.String(), specInfo != nil
+
+	if !ok || specInfo.OpenAPI == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("API %s not found", name))
+		return
+	}
+
+	query := r.URL.Query()
+	schema := query.Get("schema")
+	if schema == "" {
+		writeJSONError(w, http.StatusBadRequest, "the schema query parameter is required")
+		return
+	}
+
+	count := defaultFixtureCount
+	if raw := query.Get("count"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxFixtureCount {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("count must be between 1 and %d", maxFixtureCount))
+			return
+		}
+		count = parsed
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = mockers.FixtureFormatJSON
+	}
+	contentType, ok := mockers.FixtureContentTypes[format]
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "format must be json, ndjson or csv")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), maxFixtureDuration)
+	defer cancel()
+	fixtures, err := mockers.GenerateFixtures(ctx, specInfo.OpenAPI, schema, count, specInfo.MockOptions)
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeJSONError(w, http.StatusServiceUnavailable, "generating the fixtures took too long, request fewer of them")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, schema, format))
+	if err := mockers.WriteFixtures(w, fixtures, format); err != nil {
+		klog.Warningf("Failed to write fixtures of %s for %s: %v", schema, name, err)
+	}
+}
@@ -0,0 +1,113 @@
+package redoc
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fixtureSpec = `
+openapi: 3.0.3
+info: {title: Fixtures, version: "1"}
+paths: {}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id: {type: integer}
+        name: {type: string}
+`
+
+func TestHandleFixtures(t *testing.T) {
+	s := newTestServer(t)
+	registerTestSpec(t, s, "ns", "pets", fixtureSpec, nil)
+
+	// count returns the number of fixtures in a body of a format
+	count := func(t *testing.T, format, body string) int {
+		switch format {
+		case "json":
+			var fixtures []map[string]interface{}
+			if err := json.Unmarshal([]byte(body), &fixtures); err != nil {
+				t.Fatal(err)
+			}
+			return len(fixtures)
+		case "ndjson":
+			lines := 0
+			for scanner := bufio.NewScanner(strings.NewReader(body)); scanner.Scan(); lines++ {
+				var fixture map[string]interface{}
+				if err := json.Unmarshal(scanner.Bytes(), &fixture); err != nil {
+					t.Fatalf("line %d: %v", lines+1, err)
+				}
+			}
+			return lines
+		default:
+			records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) == 0 || strings.Join(records[0], ",") != "id,name" {
+				t.Fatalf("CSV header = %v, want id,name", records)
+			}
+			return len(records) - 1
+		}
+	}
+
+	tests := []struct {
+		query           string
+		wantStatus      int
+		format          string
+		wantContentType string
+		wantCount       int
+	}{
+		{"schema=Pet", http.StatusOK, "json", "application/json", defaultFixtureCount},
+		{"schema=Pet&count=3&format=json", http.StatusOK, "json", "application/json", 3},
+		{"schema=Pet&count=3&format=ndjson", http.StatusOK, "ndjson", "application/x-ndjson", 3},
+		{"schema=Pet&count=3&format=csv", http.StatusOK, "csv", "text/csv", 3},
+		{"schema=Pet&count=0", http.StatusBadRequest, "", "", 0},
+		{"schema=Pet&count=1001", http.StatusBadRequest, "", "", 0},
+		{"schema=Pet&count=many", http.StatusBadRequest, "", "", 0},
+		{"schema=Pet&format=xml", http.StatusBadRequest, "", "", 0},
+		{"count=3", http.StatusBadRequest, "", "", 0},
+		{"schema=Unknown", http.StatusNotFound, "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/specs/ns/pets/fixtures?"+tt.query, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != tt.wantContentType {
+				t.Errorf("content type = %q, want %q", contentType, tt.wantContentType)
+			}
+			if got := count(t, tt.format, w.Body.String()); got != tt.wantCount {
+				t.Errorf("fixtures = %d, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestHandleFixturesDeadline(t *testing.T) {
+	s := newTestServer(t)
+	registerTestSpec(t, s, "ns", "pets", fixtureSpec, nil)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/specs/ns/pets/fixtures?schema=Pet&count=3", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d: %s", w.Code, http.StatusServiceUnavailable, w.Body)
+	}
+}
@@ -0,0 +1,237 @@
+package redoc
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// User is the authenticated caller of a request
+type User struct {
+	Name   string
+	Groups []string
+}
+
+// Authenticator identifies the callers of the server
+type Authenticator interface {
+	// Authenticate returns the caller of a request, nil when the request carries no valid credentials
+	Authenticate(r *http.Request) (*User, error)
+
+	// Challenge answers a request without valid credentials, by redirecting to a login page or rejecting it
+	Challenge(w http.ResponseWriter, r *http.Request)
+}
+
+// Paths served without authentication, such as the login flow
+var publicPaths = []string{"/auth/", "/branding/"}
+
+// userKey is the context key of the authenticated user
+type userKey struct{}
+
+// UserFromContext returns the authenticated caller stored in the context of a request, nil when
+// authentication is disabled
+func UserFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userKey{}).(*User)
+	return user
+}
+
+// WithAuthenticator requires the callers of the server to authenticate. Authenticators that are also
+// an http.Handler serve their own routes under /auth/, such as the OIDC login flow.
+func WithAuthenticator(authenticator Authenticator) ServerOption {
+	return func(s *Server) {
+		s.authenticator = authenticator
+	}
+}
+
+// authenticate rejects the requests without valid credentials and stores the caller of the other ones in their context
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authenticator == nil || isPublicPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, err := s.authenticator.Authenticate(r)
+		if err != nil {
+			klog.V(2).Infof("Rejected credentials of %s %s: %v", r.Method, r.URL.Path, err)
+		}
+		if user == nil {
+			s.authenticator.Challenge(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// isPublicPath tells whether a path is served without authentication
+func isPublicPath(path string) bool {
+	for _, prefix := range publicPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// BasicAuthenticator checks static user names and passwords with HTTP basic authentication
+type BasicAuthenticator struct {
+	// Realm shown by browsers in the login prompt
+	Realm string
+
+	users map[string]basicUser
+}
+
+// basicUser is a user of the basic authenticator
+type basicUser struct {
+	// SHA-256 of the password, compared in constant time
+	password [sha256.Size]byte
+	groups   []string
+}
+
+// LoadBasicAuthenticator reads the users of a basic authenticator from a file, such as a mounted Secret.
+// Each line holds "name:password" or "name:password:group1,group2"; empty lines and lines starting with # are ignored.
+func LoadBasicAuthenticator(path string) (*BasicAuthenticator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read basic auth users: %v", err)
+	}
+	defer file.Close()
+
+	authenticator := &BasicAuthenticator{Realm: "redokube", users: make(map[string]basicUser)}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, ":", 3)
+		if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+			return nil, fmt.Errorf("invalid basic auth user on line %d", line)
+		}
+		user := basicUser{password: sha256.Sum256([]byte(fields[1]))}
+		if len(fields) == 3 {
+			user.groups = splitList(fields[2])
+		}
+		authenticator.users[fields[0]] = user
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read basic auth users: %v", err)
+	}
+	return authenticator, nil
+}
+
+// Authenticate checks the basic credentials of a request. The credentials are removed from the request
+// so that they are not forwarded to the proxied services.
+func (a *BasicAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	name, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+	r.Header.Del("Authorization")
+
+	user, known := a.users[name]
+	hash := sha256.Sum256([]byte(password))
+	if subtle.ConstantTimeCompare(hash[:], user.password[:]) != 1 || !known {
+		return nil, fmt.Errorf("invalid password for user %q", name)
+	}
+	return &User{Name: name, Groups: user.groups}, nil
+}
+
+// Challenge asks the browser for credentials
+func (a *BasicAuthenticator) Challenge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", a.Realm))
+	writeJSONError(w, http.StatusUnauthorized, "authentication required")
+}
+
+// ProxyAuthenticator trusts the user and groups set in headers by an authenticating proxy,
+// such as oauth2-proxy in front of the server
+type ProxyAuthenticator struct {
+	// Header holding the user name, X-Forwarded-User by default
+	UserHeader string
+
+	// Header holding the comma separated groups of the user, X-Forwarded-Groups by default
+	GroupsHeader string
+
+	// Addresses of the proxies allowed to set the headers, no address when empty
+	TrustedNetworks []*net.IPNet
+}
+
+// NewProxyAuthenticator creates an authenticator trusting the headers sent from the given networks,
+// such as "10.0.0.0/8". At least one network is required, as any client could set the headers otherwise.
+func NewProxyAuthenticator(userHeader, groupsHeader string, trustedNetworks []string) (*ProxyAuthenticator, error) {
+	if len(trustedNetworks) == 0 {
+		return nil, fmt.Errorf("the networks of the authenticating proxy are required")
+	}
+	authenticator := &ProxyAuthenticator{UserHeader: userHeader, GroupsHeader: groupsHeader}
+	if authenticator.UserHeader == "" {
+		authenticator.UserHeader = "X-Forwarded-User"
+	}
+	if authenticator.GroupsHeader == "" {
+		authenticator.GroupsHeader = "X-Forwarded-Groups"
+	}
+	for _, network := range trustedNetworks {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy network %q: %v", network, err)
+		}
+		authenticator.TrustedNetworks = append(authenticator.TrustedNetworks, ipNet)
+	}
+	return authenticator, nil
+}
+
+// Authenticate reads the user set by the proxy
+func (a *ProxyAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	name := r.Header.Get(a.UserHeader)
+	if name == "" {
+		return nil, nil
+	}
+	if !a.trusted(r.RemoteAddr) {
+		return nil, fmt.Errorf("user header sent by untrusted address %s", r.RemoteAddr)
+	}
+
+	user := &User{Name: name}
+	for _, value := range r.Header.Values(a.GroupsHeader) {
+		user.Groups = append(user.Groups, splitList(value)...)
+	}
+	r.Header.Del(a.UserHeader)
+	r.Header.Del(a.GroupsHeader)
+	return user, nil
+}
+
+// Challenge rejects the request, as logging in is the job of the proxy
+func (a *ProxyAuthenticator) Challenge(w http.ResponseWriter, r *http.Request) {
+	writeJSONError(w, http.StatusUnauthorized, "authentication required")
+}
+
+// trusted tells whether an address belongs to the trusted proxy networks
+func (a *ProxyAuthenticator) trusted(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	for _, network := range a.TrustedNetworks {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma separated list, dropping the empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,52 @@
+package redoc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewProxyAuthenticatorRequiresTrustedNetworks(t *testing.T) {
+	if _, err := NewProxyAuthenticator("", "", nil); err == nil {
+		t.Error("expected an error without trusted networks")
+	}
+}
+
+func TestProxyAuthenticatorRejectsForgedHeaders(t *testing.T) {
+	authenticator, err := NewProxyAuthenticator("", "", []string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(t, WithAuthenticator(authenticator))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantStatus int
+	}{
+		{"trusted proxy", "10.0.0.2:4321", http.StatusOK},
+		{"untrusted address", "203.0.113.7:4321", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/specs", nil)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("X-Forwarded-User", "admin")
+			r.Header.Set("X-Forwarded-Groups", "system:masters")
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	// Without trusted networks no address may set the headers
+	untrusted := &ProxyAuthenticator{UserHeader: "X-Forwarded-User", GroupsHeader: "X-Forwarded-Groups"}
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/specs", nil)
+	r.RemoteAddr = "10.0.0.2:4321"
+	r.Header.Set("X-Forwarded-User", "admin")
+	if user, err := untrusted.Authenticate(r); user != nil || err == nil {
+		t.Errorf("user = %+v, error = %v, want the headers rejected", user, err)
+	}
+}
@@ -0,0 +1,203 @@
+package redoc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+// Lifetimes of the cached authorization decisions
+const (
+	defaultAllowedTTL = time.Minute
+	defaultDeniedTTL  = 10 * time.Second
+	maxDecisions      = 10000
+)
+
+// anonymous is the user of the requests when authentication is disabled, as named by Kubernetes
+var anonymous = &User{Name: "system:anonymous", Groups: []string{"system:unauthenticated"}}
+
+// authenticatedGroup is the group Kubernetes gives to every authenticated user
+const authenticatedGroup = "system:authenticated"
+
+// Authorizer decides whether a user may act on the specs of a namespace, with the Kubernetes verb
+// "get" for reads, "create" for requests producing data of a spec, such as webhook deliveries,
+// and "delete" for requests dropping it, such as its recordings
+type Authorizer interface {
+	Authorize(ctx context.Context, user *User, namespace, verb string) (bool, error)
+}
+
+// WithAuthorizer restricts the docs, specs, mocks and APIs of each spec to the users allowed to read its namespace.
+// The index and catalog only list the specs the caller may read.
+func WithAuthorizer(authorizer Authorizer) ServerOption {
+	return func(s *Server) {
+		s.authorizer = authorizer
+	}
+}
+
+// SubjectAccessReviewAuthorizer allows the users that Kubernetes RBAC allows to get, create or delete the OpenAPISpecs of a namespace.
+// Decisions are cached so that browsing the portal does not flood the API server.
+type SubjectAccessReviewAuthorizer struct {
+	client client.Client
+
+	// Lifetimes of the cached decisions
+	AllowedTTL time.Duration
+	DeniedTTL  time.Duration
+
+	mutex     sync.Mutex
+	decisions map[string]decision
+}
+
+// decision is a cached authorization decision
+type decision struct {
+	allowed bool
+	expires time.Time
+}
+
+// NewSubjectAccessReviewAuthorizer creates an authorizer sending SubjectAccessReviews with a client
+func NewSubjectAccessReviewAuthorizer(c client.Client) *SubjectAccessReviewAuthorizer {
+	return &SubjectAccessReviewAuthorizer{
+		client:     c,
+		AllowedTTL: defaultAllowedTTL,
+		DeniedTTL:  defaultDeniedTTL,
+		decisions:  make(map[string]decision),
+	}
+}
+
+// Authorize checks whether a user may apply a verb to the OpenAPISpecs of a namespace
+func (a *SubjectAccessReviewAuthorizer) Authorize(ctx context.Context, user *User, namespace, verb string) (bool, error) {
+	groups := reviewGroups(user)
+	key := strings.Join([]string{user.Name, strings.Join(groups, ","), namespace, verb}, "\x00")
+
+	a.mutex.Lock()
+	cached, ok := a.decisions[key]
+	a.mutex.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.allowed, nil
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Name,
+			Groups: groups,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     docsv1.GroupVersion.Group,
+				Resource:  "openapispecs",
+			},
+		},
+	}
+	if err := a.client.Create(ctx, review); err != nil {
+		return false, fmt.Errorf("failed to review whether %q may %s in namespace %s: %v", user.Name, verb, namespace, err)
+	}
+
+	ttl := a.AllowedTTL
+	if !review.Status.Allowed {
+		ttl = a.DeniedTTL
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if len(a.decisions) >= maxDecisions {
+		a.evict()
+	}
+	a.decisions[key] = decision{allowed: review.Status.Allowed, expires: time.Now().Add(ttl)}
+	return review.Status.Allowed, nil
+}
+
+// reviewGroups returns the sorted groups of a user, with system:authenticated for every user but the anonymous one
+// as the API server adds it to authenticated requests, so RBAC bindings to that group apply to portal users too
+func reviewGroups(user *User) []string {
+	groups := append([]string(nil), user.Groups...)
+	if user.Name != anonymous.Name && !slices.Contains(groups, authenticatedGroup) {
+		groups = append(groups, authenticatedGroup)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// evict drops the expired decisions, and all of them when none expired
+func (a *SubjectAccessReviewAuthorizer) evict() {
+	now := time.Now()
+	for key, cached := range a.decisions {
+		if now.After(cached.expires) {
+			delete(a.decisions, key)
+		}
+	}
+	if len(a.decisions) >= maxDecisions {
+		a.decisions = make(map[string]decision)
+	}
+}
+
+// authorize rejects the requests for a spec, or the specs of a namespace, that the caller may not make,
+// with the verb of requestVerb
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		namespace, ok := s.requestNamespace(r)
+		if s.authorizer == nil || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		verb := requestVerb(r)
+		allowed, err := s.allowed(r, namespace, verb)
+		if err != nil {
+			klog.Errorf("Failed to authorize %s %s: %v", r.Method, r.URL.Path, err)
+			writeJSONError(w, http.StatusServiceUnavailable, "authorization unavailable")
+			return
+		}
+		if !allowed {
+			writeJSONError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestVerb returns the Kubernetes verb checked for a request: "delete" for DELETE requests, "create" for
+// POST requests to the API of a spec such as webhook triggers, and "get" for the rest. Calls to the mock,
+// recording and try it out routes stand for calls to the described API and only need to read the spec.
+func requestVerb(r *http.Request) string {
+	switch {
+	case r.Method == http.MethodDelete:
+		return "delete"
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/v1/specs/"):
+		return "create"
+	}
+	return "get"
+}
+
+// requestNamespace returns the namespace of the spec targeted by a request, false for requests
+// that do not target a spec or target an unknown one
+func (s *Server) requestNamespace(r *http.Request) (string, bool) {
+	if namespace, ok := mux.Vars(r)["namespace"]; ok {
+		return namespace, true
+	}
+	if _, specInfo := s.requestedSpec(r); specInfo != nil {
+		return specInfo.Namespace, true
+	}
+	return "", false
+}
+
+// allowed tells whether the caller of a request may apply a verb to the specs of a namespace
+func (s *Server) allowed(r *http.Request, namespace, verb string) (bool, error) {
+	if s.authorizer == nil {
+		return true, nil
+	}
+	user := UserFromContext(r.Context())
+	if user == nil {
+		user = anonymous
+	}
+	return s.authorizer.Authorize(r.Context(), user, namespace, verb)
+}
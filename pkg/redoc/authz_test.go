@@ -0,0 +1,159 @@
+package redoc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+const collisionSpec = `
+openapi: 3.0.3
+info: {title: Collision, version: "1"}
+paths:
+  /items:
+    get:
+      responses:
+        200:
+          description: ok
+          content:
+            application/json:
+              schema: {type: string}
+`
+
+// namespaceAuthorizer allows the users to read, but not change, a fixed set of namespaces
+type namespaceAuthorizer map[string]bool
+
+func (a namespaceAuthorizer) Authorize(_ context.Context, _ *User, namespace, verb string) (bool, error) {
+	return a[namespace] && verb == "get", nil
+}
+
+// registerCollidingSpecs registers the specs "c" of namespace "a-b" and "b-c" of namespace "a",
+// which both read "a-b-c" once their namespace and name are joined with a dash
+func registerCollidingSpecs(t *testing.T, s *Server) {
+	t.Helper()
+	registerTestSpec(t, s, "a-b", "c", collisionSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.Mock = true
+		spec.Spec.Visibility = docsv1.VisibilityInternal
+	})
+	registerTestSpec(t, s, "a", "b-c", collisionSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.Mock = true
+		spec.Spec.Visibility = docsv1.VisibilityPublic
+	})
+}
+
+func TestAuthorizeCollidingNames(t *testing.T) {
+	s := newTestServer(t, WithAuthorizer(namespaceAuthorizer{"a": true}))
+	registerCollidingSpecs(t, s)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/docs/a/b-c", http.StatusOK},
+		{"/specs/a/b-c.json", http.StatusOK},
+		{"/mock/a/b-c/items", http.StatusOK},
+		{"/docs/a-b/c", http.StatusForbidden},
+		{"/specs/a-b/c.json", http.StatusForbidden},
+		{"/mock/a-b/c/items", http.StatusForbidden},
+		{"/api/v1/specs/a-b/c/deliveries", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	catalog := s.catalog(httptest.NewRequest(http.MethodGet, "/api/v1/specs", nil))
+	if len(catalog) != 1 || catalog[0].Namespace != "a" || catalog[0].Name != "a/b-c" {
+		t.Errorf("catalog = %+v, want only a/b-c", catalog)
+	}
+}
+
+func TestSubjectAccessReviewGroups(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       *User
+		wantGroups []string
+	}{
+		{"authenticated user", &User{Name: "alice", Groups: []string{"team-b", "team-a"}}, []string{"system:authenticated", "team-a", "team-b"}},
+		{"user without groups", &User{Name: "bob"}, []string{"system:authenticated"}},
+		{"group already set", &User{Name: "carol", Groups: []string{"system:authenticated"}}, []string{"system:authenticated"}},
+		{"anonymous user", anonymous, []string{"system:unauthenticated"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reviews []authorizationv1.SubjectAccessReviewSpec
+			c := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(_ context.Context, _ client.WithWatch, obj client.Object, _ ...client.CreateOption) error {
+					review := obj.(*authorizationv1.SubjectAccessReview)
+					reviews = append(reviews, review.Spec)
+					review.Status.Allowed = true
+					return nil
+				},
+			}).Build()
+			authorizer := NewSubjectAccessReviewAuthorizer(c)
+
+			for i := 0; i < 2; i++ {
+				if allowed, err := authorizer.Authorize(context.Background(), tt.user, "ns", "get"); err != nil || !allowed {
+					t.Fatalf("Authorize = %v, %v, want allowed", allowed, err)
+				}
+			}
+			if len(reviews) != 1 {
+				t.Fatalf("%d reviews sent, want 1 with the decision cached", len(reviews))
+			}
+			if review := reviews[0]; review.User != tt.user.Name || !reflect.DeepEqual(review.Groups, tt.wantGroups) {
+				t.Errorf("review of %s with groups %v, want %s with %v", review.User, review.Groups, tt.user.Name, tt.wantGroups)
+			}
+		})
+	}
+}
+
+// verbAuthorizer allows everything and records the verbs checked
+type verbAuthorizer struct {
+	verbs *[]string
+}
+
+func (a verbAuthorizer) Authorize(_ context.Context, _ *User, _, verb string) (bool, error) {
+	*a.verbs = append(*a.verbs, verb)
+	return true, nil
+}
+
+func TestAuthorizeVerbs(t *testing.T) {
+	var verbs []string
+	s := newTestServer(t, WithAuthorizer(verbAuthorizer{&verbs}))
+	registerWebhookSpec(t, s)
+
+	tests := []struct {
+		method   string
+		path     string
+		wantVerb string
+	}{
+		{http.MethodGet, "/docs/ns/pets", "get"},
+		{http.MethodGet, "/api/v1/specs/ns/pets/deliveries", "get"},
+		{http.MethodPost, "/api/v1/specs/ns/pets/webhooks/newPet", "create"},
+		{http.MethodDelete, "/api/v1/specs/ns/pets/recordings", "delete"},
+		{http.MethodPost, "/mock/ns/pets/pets", "get"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.path, func(t *testing.T) {
+			verbs = nil
+			s.server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+			if len(verbs) != 1 || verbs[0] != tt.wantVerb {
+				t.Errorf("verbs = %v, want %s", verbs, tt.wantVerb)
+			}
+		})
+	}
+}
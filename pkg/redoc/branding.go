@@ -0,0 +1,268 @@
+package redoc
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/net/html"
+	"sigs.k8s.io/yaml"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+// BrandingConfig is the cluster-wide branding, usually mounted from a ConfigMap
+type BrandingConfig struct {
+	docsv1.Branding
+
+	// Image files served as the logo and the favicon, such as binaryData keys of the ConfigMap.
+	// They are used when no logo or favicon URL is set.
+	LogoFile    string `json:"logoFile,omitempty"`
+	FaviconFile string `json:"faviconFile,omitempty"`
+}
+
+// PageBranding is the sanitized branding rendered by the page templates
+type PageBranding struct {
+	LogoURL    template.URL
+	FaviconURL template.URL
+	CSS        template.CSS
+	Header     template.HTML
+	Footer     template.HTML
+}
+
+// LoadBranding reads the cluster-wide branding from a YAML or JSON file
+func LoadBranding(path string) (*BrandingConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read branding config: %v", err)
+	}
+
+	config := &BrandingConfig{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("failed to parse branding config: %v", err)
+	}
+	if config.LogoURL == "" && config.LogoFile != "" {
+		config.LogoURL = "/branding/logo"
+	}
+	if config.FaviconURL == "" && config.FaviconFile != "" {
+		config.FaviconURL = "/branding/favicon"
+	}
+	return config, nil
+}
+
+// WithBranding sets the cluster-wide branding of the index and documentation pages
+func WithBranding(config *BrandingConfig) ServerOption {
+	return func(s *Server) {
+		s.branding = config
+	}
+}
+
+// handleBrandingAsset serves the logo and favicon files of the cluster-wide branding
+func (s *Server) handleBrandingAsset(w http.ResponseWriter, r *http.Request) {
+	var path string
+	if s.branding != nil {
+		switch mux.Vars(r)["asset"] {
+		case "logo":
+			path = s.branding.LogoFile
+		case "favicon":
+			path = s.branding.FaviconFile
+		}
+	}
+	if path == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	http.ServeFile(w, r, path)
+}
+
+// pageBranding merges the overrides of a spec into the cluster-wide branding and sanitizes the result
+func (s *Server) pageBranding(override *docsv1.Branding) PageBranding {
+	var branding docsv1.Branding
+	if s.branding != nil {
+		branding = s.branding.Branding
+	}
+	if override != nil {
+		// Unsafe links of the spec fall back to the cluster-wide ones
+		if sanitizeURL(override.LogoURL, true) != "" {
+			branding.LogoURL = override.LogoURL
+		}
+		if sanitizeURL(override.FaviconURL, true) != "" {
+			branding.FaviconURL = override.FaviconURL
+		}
+		if override.Header != "" {
+			branding.Header = override.Header
+		}
+		if override.Footer != "" {
+			branding.Footer = override.Footer
+		}
+		// The style sheet of the spec cascades over the cluster-wide one
+		if override.CSS != "" {
+			branding.CSS = strings.TrimSpace(branding.CSS + "\n" + override.CSS)
+		}
+	}
+
+	return PageBranding{
+		LogoURL:    template.URL(sanitizeURL(branding.LogoURL, true)),
+		FaviconURL: template.URL(sanitizeURL(branding.FaviconURL, true)),
+		CSS:        template.CSS(sanitizeCSS(branding.CSS)),
+		Header:     template.HTML(sanitizeHTML(branding.Header)),
+		Footer:     template.HTML(sanitizeHTML(branding.Footer)),
+	}
+}
+
+// Elements allowed in the header and footer, with their allowed attributes
+var allowedElements = map[string][]string{
+	"a": {"href", "title", "target", "rel"}, "img": {"src", "alt", "title", "width", "height"},
+	"div": nil, "span": nil, "p": nil, "br": nil, "hr": nil, "nav": nil, "section": nil,
+	"b": nil, "strong": nil, "i": nil, "em": nil, "u": nil, "small": nil, "code": nil, "pre": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"ul": nil, "ol": nil, "li": nil,
+	"table": nil, "thead": nil, "tbody": nil, "tr": nil, "th": nil, "td": nil,
+}
+
+// Attributes allowed on every element
+var globalAttributes = []string{"class", "id", "title"}
+
+// Elements removed along with their content
+var droppedElements = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"noscript": true, "template": true, "svg": true, "math": true, "frameset": true,
+}
+
+// sanitizeHTML keeps the allowed elements and attributes of an HTML fragment and drops everything else,
+// so that branding cannot run scripts in the pages
+func sanitizeHTML(fragment string) string {
+	var out strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	skipped := 0
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return out.String()
+		case html.TextToken:
+			if skipped == 0 {
+				out.WriteString(html.EscapeString(string(tokenizer.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if droppedElements[token.Data] {
+				if token.Type == html.StartTagToken {
+					skipped++
+				}
+				continue
+			}
+			attributes, ok := allowedElements[token.Data]
+			if !ok || skipped > 0 {
+				continue
+			}
+			out.WriteString("<" + token.Data)
+			for _, attr := range token.Attr {
+				if !slices.Contains(attributes, attr.Key) && !slices.Contains(globalAttributes, attr.Key) {
+					continue
+				}
+				value := attr.Val
+				if attr.Key == "href" || attr.Key == "src" {
+					if value = sanitizeURL(value, attr.Key == "src"); value == "" {
+						continue
+					}
+				}
+				fmt.Fprintf(&out, ` %s="%s"`, attr.Key, html.EscapeString(value))
+			}
+			if token.Data == "a" && !hasAttribute(token, "rel") {
+				out.WriteString(` rel="noopener noreferrer"`)
+			}
+			out.WriteString(">")
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			if droppedElements[token.Data] {
+				if skipped > 0 {
+					skipped--
+				}
+				continue
+			}
+			if _, ok := allowedElements[token.Data]; ok && skipped == 0 {
+				out.WriteString("</" + token.Data + ">")
+			}
+		}
+	}
+}
+
+// sanitizeURL returns a link when it uses a safe scheme, an empty string otherwise.
+// Embedded data:image URIs are allowed for images only.
+func sanitizeURL(link string, image bool) string {
+	// Browsers decode the character references of attributes, such as "&#106;avascript:"
+	link = strings.TrimSpace(html.UnescapeString(link))
+	if link == "" {
+		return ""
+	}
+	if image && strings.HasPrefix(strings.ToLower(link), "data:image/") {
+		return link
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "", "http", "https", "mailto":
+		return link
+	}
+	return ""
+}
+
+// unsafeCSS matches the constructs of a style sheet that can load or run code
+var unsafeCSS = regexp.MustCompile(`(?i)expression\s*\(|javascript:|vbscript:|behavior\s*:|-moz-binding|@import`)
+
+// Comments and escapes of letters can hide the unsafe constructs, as in "expr/**/ession(" or "j\61vascript:"
+var (
+	cssComment      = regexp.MustCompile(`/\*[\s\S]*?(\*/|$)`)
+	cssLetterEscape = regexp.MustCompile(`\\([0-9a-fA-F]{1,6})[ \t\r\n\f]?|\\([g-zG-Z])`)
+)
+
+// sanitizeCSS removes the constructs of a style sheet that can run code or escape the style element
+func sanitizeCSS(css string) string {
+	// Repeat until nothing changes, as removing a construct can form another one
+	for {
+		cleaned := strings.ReplaceAll(css, "<", "")
+		cleaned = cssComment.ReplaceAllString(cleaned, "")
+		cleaned = cssLetterEscape.ReplaceAllStringFunc(cleaned, unescapeCSSLetter)
+		cleaned = unsafeCSS.ReplaceAllString(cleaned, "")
+		if cleaned == css {
+			return css
+		}
+		css = cleaned
+	}
+}
+
+// unescapeCSSLetter decodes the escape of a letter or digit, other escapes are kept
+func unescapeCSSLetter(escape string) string {
+	match := cssLetterEscape.FindStringSubmatch(escape)
+	if match[2] != "" {
+		return match[2]
+	}
+	code, err := strconv.ParseUint(match[1], 16, 32)
+	if err != nil || code > unicode.MaxASCII || !unicode.IsLetter(rune(code)) && !unicode.IsDigit(rune(code)) {
+		return escape
+	}
+	return string(rune(code))
+}
+
+// hasAttribute tells whether a token sets an attribute
+func hasAttribute(token html.Token, key string) bool {
+	for _, attr := range token.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
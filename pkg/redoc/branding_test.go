@@ -0,0 +1,109 @@
+package redoc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{"allowed markup", `<p class="note">Hello <b>world</b></p>`, `<p class="note">Hello <b>world</b></p>`},
+		{"script", `<p>a<script>alert(1)</script>b</p>`, `<p>ab</p>`},
+		{"uppercase script", `<SCRIPT>alert(1)</SCRIPT>ok`, `ok`},
+		{"unclosed script", `ok<script>alert(1)`, `ok`},
+		{"nested dropped elements", `<svg><script>alert(1)</script><a href="https://x">x</a></svg>ok`, `ok`},
+		{"style element", `<style>body{display:none}</style>ok`, `ok`},
+		{"iframe", `<iframe src="https://evil.example.com"></iframe>ok`, `ok`},
+		{"event handler", `<img src="logo.png" onerror="alert(1)">`, `<img src="logo.png">`},
+		{"uppercase event handler", `<div OnClick="alert(1)" id="x">y</div>`, `<div id="x">y</div>`},
+		{"style attribute", `<span style="background:url(javascript:alert(1))">x</span>`, `<span>x</span>`},
+		{"unknown element", `<form action="/x"><input name="q"></form>text`, `text`},
+		{"text is escaped", `1 &lt; 2 & "quotes"`, `1 &lt; 2 &amp; &#34;quotes&#34;`},
+		{"attribute is escaped", `<span title='a"><script>'>x</span>`, `<span title="a&#34;&gt;&lt;script&gt;">x</span>`},
+		{"link gets rel", `<a href="https://example.com">x</a>`, `<a href="https://example.com" rel="noopener noreferrer">x</a>`},
+		{"javascript link", `<a href="javascript:alert(1)">x</a>`, `<a rel="noopener noreferrer">x</a>`},
+		{"mixed case javascript link", `<a href="JaVaScRiPt:alert(1)">x</a>`, `<a rel="noopener noreferrer">x</a>`},
+		{"entity encoded javascript link", `<a href="&#x6A;avascript&colon;alert(1)">x</a>`, `<a rel="noopener noreferrer">x</a>`},
+		{"double encoded javascript link", `<a href="&amp;#106;avascript:alert(1)">x</a>`, `<a rel="noopener noreferrer">x</a>`},
+		{"javascript link with a tab", `<a href="jav&#x09;ascript:alert(1)">x</a>`, `<a rel="noopener noreferrer">x</a>`},
+		{"data link", `<a href="data:text/html,<script>alert(1)</script>">x</a>`, `<a rel="noopener noreferrer">x</a>`},
+		{"data image link", `<a href="data:image/png;base64,AAAA">x</a>`, `<a rel="noopener noreferrer">x</a>`},
+		{"data image", `<img src="data:image/png;base64,AAAA">`, `<img src="data:image/png;base64,AAAA">`},
+		{"data html image", `<img src="data:text/html;base64,AAAA">`, `<img>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeHTML(tt.fragment); got != tt.want {
+				t.Errorf("sanitizeHTML(%q) = %q, want %q", tt.fragment, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		link  string
+		image bool
+		want  string
+	}{
+		{"https", "https://example.com/logo.png", true, "https://example.com/logo.png"},
+		{"relative", "/branding/logo.png", true, "/branding/logo.png"},
+		{"mailto", "mailto:team@example.com", false, "mailto:team@example.com"},
+		{"javascript", "javascript:alert(1)", false, ""},
+		{"mixed case javascript", "JavaScript:alert(1)", false, ""},
+		{"spaced javascript", "  javascript:alert(1)", false, ""},
+		{"entity encoded javascript", "&#106;avascript:alert(1)", true, ""},
+		{"named entity javascript", "javascript&colon;alert(1)", true, ""},
+		{"control character", "\x01javascript:alert(1)", false, ""},
+		{"vbscript", "vbscript:msgbox(1)", false, ""},
+		{"data image", "data:image/svg+xml;base64,AAAA", true, "data:image/svg+xml;base64,AAAA"},
+		{"uppercase data image", "DATA:image/png;base64,AAAA", true, "DATA:image/png;base64,AAAA"},
+		{"data image outside of images", "data:image/png;base64,AAAA", false, ""},
+		{"data html", "data:text/html;base64,AAAA", true, ""},
+		{"empty", "", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeURL(tt.link, tt.image); got != tt.want {
+				t.Errorf("sanitizeURL(%q, %v) = %q, want %q", tt.link, tt.image, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeCSS(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		want string
+	}{
+		{"plain rules", `.header { color: #333; content: "\201C"; }`, `.header { color: #333; content: "\201C"; }`},
+		{"closing the style element", `a{color:red}</style><script>alert(1)</script>`, `a{color:red}/style>script>alert(1)/script>`},
+		{"expression", `a{width:expression(alert(1))}`, `a{width:alert(1))}`},
+		{"spaced expression", `a{width:EXPRESSION (alert(1))}`, `a{width:alert(1))}`},
+		{"expression split by a comment", `a{width:expr/**/ession(alert(1))}`, `a{width:alert(1))}`},
+		{"escaped expression", `a{width:\65 xpression(alert(1))}`, `a{width:alert(1))}`},
+		{"javascript url", `a{background:url(javascript:alert(1))}`, `a{background:url(alert(1))}`},
+		{"mixed case javascript url", `a{background:url("JavaScript:alert(1)")}`, `a{background:url("alert(1)")}`},
+		{"escaped javascript url", `a{background:url(j\61v\41 \script:alert(1))}`, `a{background:url(alert(1))}`},
+		{"nested constructs", `a{width:expexpression(ression(1)}`, `a{width:1)}`},
+		{"import", `@import url(https://evil.example.com/x.css);`, ` url(https://evil.example.com/x.css);`},
+		{"behavior", `a{behavior: url(x.htc); -moz-binding: url(x.xml)}`, `a{ url(x.htc); : url(x.xml)}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeCSS(tt.css)
+			if got != tt.want {
+				t.Errorf("sanitizeCSS(%q) = %q, want %q", tt.css, got, tt.want)
+			}
+			if strings.Contains(got, "<") {
+				t.Errorf("sanitizeCSS(%q) keeps a <", tt.css)
+			}
+		})
+	}
+}
@@ -0,0 +1,533 @@
+package redoc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"k8s.io/klog/v2"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+	"github.com/BombartSimon/redokube/pkg/mockers"
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// Defaults of the callback and webhook delivery
+const (
+	defaultCallbackRetries = 3
+	defaultSignatureHeader = "X-Redokube-Signature"
+	initialCallbackBackoff = time.Second
+	maxCallbackBackoff     = time.Minute
+	maxDeliveryLogSize     = 100
+	maxLoggedPayloadSize   = 4096
+	// Deliveries sent at the same time by the server, the others fail at once
+	maxConcurrentDeliveries = 32
+)
+
+// errSubscriberNotAllowed rejects the subscribers outside of the callback allowlist
+var errSubscriberNotAllowed = errors.New("subscriber address is not allowed")
+
+// Status of a delivery
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// callbackOptions holds the delivery settings of a spec, with its signing secret resolved
+type callbackOptions struct {
+	delay           time.Duration
+	backoff         time.Duration
+	maxRetries      int
+	signatureHeader string
+	secret          []byte
+}
+
+// Delivery is an entry of the delivery log of a spec
+type Delivery struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	Status     string    `json:"status"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Payload    string    `json:"payload,omitempty"`
+}
+
+// deliveryLog keeps the latest deliveries of a spec, newest first
+type deliveryLog struct {
+	mutex   sync.Mutex
+	entries []*Delivery
+}
+
+// add records a delivery, dropping the oldest ones beyond the log size
+func (l *deliveryLog) add(delivery *Delivery) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.entries = append([]*Delivery{delivery}, l.entries...)
+	if len(l.entries) > maxDeliveryLogSize {
+		l.entries = l.entries[:maxDeliveryLogSize]
+	}
+}
+
+// update modifies a recorded delivery under the log lock
+func (l *deliveryLog) update(change func()) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	change()
+}
+
+// list returns a copy of the recorded deliveries
+func (l *deliveryLog) list() []Delivery {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	out := make([]Delivery, len(l.entries))
+	for i, entry := range l.entries {
+		out[i] = *entry
+	}
+	return out
+}
+
+// callbackSettings resolves the callback options of an OpenAPISpec, nil when delivery is disabled
+func (s *Server) callbackSettings(openAPISpec *docsv1.OpenAPISpec) (*callbackOptions, error) {
+	mockOptions := openAPISpec.Spec.MockOptions
+	if !openAPISpec.Spec.Mock || mockOptions == nil || mockOptions.Callbacks == nil {
+		return nil, nil
+	}
+	spec := mockOptions.Callbacks
+
+	opts := &callbackOptions{
+		backoff:         initialCallbackBackoff,
+		maxRetries:      defaultCallbackRetries,
+		signatureHeader: defaultSignatureHeader,
+	}
+	if spec.Delay != nil {
+		opts.delay = spec.Delay.Duration
+	}
+	if spec.MaxRetries != nil {
+		opts.maxRetries = int(*spec.MaxRetries)
+	}
+	if spec.SignatureHeader != "" {
+		opts.signatureHeader = spec.SignatureHeader
+	}
+	if spec.SecretRef != nil {
+		secret, err := s.secretValue(openAPISpec.Namespace, spec.SecretRef)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve the callback signing secret: %v", err)
+		}
+		opts.secret = secret
+	}
+	return opts, nil
+}
+
+// scheduleCallbacks sends the callbacks of an operation answered by the mock server.
+// Callback URLs are runtime expressions evaluated against the mock request and response.
+func (s *Server) scheduleCallbacks(name string, specInfo *SpecInfo, operation map[string]interface{}, runtime *openapi.RuntimeContext) {
+	callbacks, _ := operation["callbacks"].(map[string]interface{})
+	for _, event := range sortedKeys(callbacks) {
+		callback, _ := callbacks[event].(map[string]interface{})
+		if ref, ok := callback["$ref"].(string); ok {
+			callback, _ = openapi.Resolve(specInfo.OpenAPI, ref)
+		}
+
+		for _, expression := range sortedKeys(callback) {
+			pathItem, ok := callback[expression].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			target, err := runtime.Evaluate(expression)
+			for _, method := range openapi.Methods {
+				callbackOperation, ok := pathItem[method].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				location := fmt.Sprintf("callback %s %s", event, strings.ToUpper(method))
+				if err != nil {
+					specInfo.deliveries.add(&Delivery{
+						Time:   time.Now(),
+						Event:  event,
+						Method: strings.ToUpper(method),
+						URL:    expression,
+						Status: DeliveryFailed,
+						Error:  err.Error(),
+					})
+					continue
+				}
+				s.deliver(name, specInfo, event, method, target, callbackOperation, location)
+			}
+		}
+	}
+}
+
+// deliver generates the payload of a callback or webhook operation and sends it in the background.
+// It returns the delivery as recorded before the first attempt.
+func (s *Server) deliver(name string, specInfo *SpecInfo, event, method, target string, operation map[string]interface{}, location string) Delivery {
+	delivery := &Delivery{
+		Time:   time.Now(),
+		Event:  event,
+		Method: strings.ToUpper(method),
+		URL:    target,
+		Status: DeliveryPending,
+	}
+
+	payload, err := mockers.GenerateRequest(specInfo.OpenAPI, operation, location, specInfo.MockOptions)
+	if err == nil {
+		err = s.callbackAllowlist.validate(target)
+	}
+	if err != nil {
+		delivery.Status, delivery.Error = DeliveryFailed, err.Error()
+		specInfo.deliveries.add(delivery)
+		return *delivery
+	}
+
+	delivery.Payload = string(payload.Body)
+	if len(delivery.Payload) > maxLoggedPayloadSize {
+		delivery.Payload = delivery.Payload[:maxLoggedPayloadSize] + "..."
+	}
+	select {
+	case s.deliverySlots <- struct{}{}:
+	default:
+		delivery.Status, delivery.Error = DeliveryFailed, "too many deliveries in progress"
+		specInfo.deliveries.add(delivery)
+		return *delivery
+	}
+	specInfo.deliveries.add(delivery)
+	recorded := *delivery
+
+	go func() {
+		defer func() { <-s.deliverySlots }()
+		s.send(name, specInfo.deliveries, delivery, specInfo.callbacks, payload)
+	}()
+	return recorded
+}
+
+// send delivers a payload after the configured delay, retrying with an exponential backoff.
+// Subscribers outside of the allowlist are not retried.
+func (s *Server) send(name string, log *deliveryLog, delivery *Delivery, opts *callbackOptions, payload *mockers.RequestExample) {
+	time.Sleep(opts.delay)
+
+	backoff := opts.backoff
+	for attempt := 1; ; attempt++ {
+		statusCode, err := s.post(delivery, opts, payload)
+		log.update(func() {
+			delivery.Attempts, delivery.StatusCode, delivery.Error = attempt, statusCode, ""
+			if err != nil {
+				delivery.Error = err.Error()
+			}
+		})
+		if err == nil {
+			log.update(func() { delivery.Status = DeliveryDelivered })
+			return
+		}
+		if attempt > opts.maxRetries || errors.Is(err, errSubscriberNotAllowed) {
+			break
+		}
+		time.Sleep(backoff)
+		backoff = min(2*backoff, maxCallbackBackoff)
+	}
+
+	log.update(func() { delivery.Status = DeliveryFailed })
+	klog.Warningf("Failed to deliver %s of %s to %s: %s", delivery.Event, name, delivery.URL, delivery.Error)
+}
+
+// post sends a single delivery attempt, signing the payload when a secret is configured
+func (s *Server) post(delivery *Delivery, opts *callbackOptions, payload *mockers.RequestExample) (int, error) {
+	req, err := http.NewRequest(delivery.Method, delivery.URL, bytes.NewReader(payload.Body))
+	if err != nil {
+		return 0, err
+	}
+	if payload.Body != nil {
+		req.Header.Set("Content-Type", payload.MediaType)
+	}
+	req.Header.Set("User-Agent", "redokube")
+	req.Header.Set("X-Redokube-Event", delivery.Event)
+	if len(opts.secret) > 0 {
+		mac := hmac.New(sha256.New, opts.secret)
+		mac.Write(payload.Body)
+		req.Header.Set(opts.signatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.callbackClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxMockBodySize))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// CallbackAllowlist restricts the subscribers of callbacks and webhooks to host names and networks.
+// Without an allowlist, subscribers may be any public address: loopback, link-local and private
+// addresses such as the cluster services or the cloud metadata endpoint are refused.
+type CallbackAllowlist struct {
+	Hosts    []string
+	Networks []*net.IPNet
+}
+
+// ParseCallbackAllowlist reads host names and networks such as "hooks.example.com" or "10.0.0.0/8".
+// Listed hosts and networks may be private.
+func ParseCallbackAllowlist(entries []string) (*CallbackAllowlist, error) {
+	allowlist := &CallbackAllowlist{}
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			allowlist.Hosts = append(allowlist.Hosts, strings.ToLower(entry))
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid callback network %q: %v", entry, err)
+		}
+		allowlist.Networks = append(allowlist.Networks, network)
+	}
+	return allowlist, nil
+}
+
+// WithCallbackAllowlist sets the subscribers allowed to receive callbacks and webhooks
+func WithCallbackAllowlist(allowlist *CallbackAllowlist) ServerOption {
+	return func(s *Server) {
+		s.callbackAllowlist = allowlist
+	}
+}
+
+// listsHost tells whether a host name or address is listed by name
+func (l *CallbackAllowlist) listsHost(host string) bool {
+	return l != nil && slices.Contains(l.Hosts, strings.ToLower(host))
+}
+
+// allows tells whether an address may be reached, listed being true when its host name is listed
+func (l *CallbackAllowlist) allows(listed bool, ip net.IP) bool {
+	if listed {
+		return true
+	}
+	if l != nil {
+		for _, network := range l.Networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		if len(l.Hosts) > 0 || len(l.Networks) > 0 {
+			return false
+		}
+	}
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsInterfaceLocalMulticast()
+}
+
+// validate checks that a subscriber URL is an absolute HTTP URL, and that its address
+// is allowed when it is written as an IP address
+func (l *CallbackAllowlist) validate(target string) error {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("invalid callback URL %q: %v", target, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("callback URL %q must be an absolute http or https URL", target)
+	}
+	if ip := net.ParseIP(parsed.Hostname()); ip != nil && !l.allows(l.listsHost(parsed.Hostname()), ip) {
+		return fmt.Errorf("%w: %s", errSubscriberNotAllowed, parsed.Hostname())
+	}
+	return nil
+}
+
+// client returns the client sending the deliveries. Addresses are checked once resolved, right
+// before connecting, so that host names and redirects cannot lead to a refused address.
+func (l *CallbackAllowlist) client() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would hide the address of the subscriber
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		listed := l.listsHost(host)
+		dialer := &net.Dialer{
+			Timeout: outboundTimeout,
+			Control: func(_, resolved string, _ syscall.RawConn) error {
+				ip, _, err := net.SplitHostPort(resolved)
+				if err != nil {
+					return err
+				}
+				if !l.allows(listed, net.ParseIP(ip)) {
+					return fmt.Errorf("%w: %s resolves to %s", errSubscriberNotAllowed, host, ip)
+				}
+				return nil
+			},
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+	return &http.Client{Timeout: outboundTimeout, Transport: transport}
+}
+
+// handleWebhook triggers a webhook declared by an OpenAPI 3.1 spec, such as
+// POST /api/v1/specs/{namespace}/{name}/webhooks/newPet?url=http://subscriber/hook.
+// The subscriber URL can also be sent as {"url": "..."}.
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	key, specInfo := s.requestedSpec(r)
+	name, webhook := key.String(), mux.Vars(r)["webhook"]
+
+	if specInfo == nil || !specInfo.Mock || specInfo.OpenAPI == nil || specInfo.callbacks == nil {
+		writeJSONError(w, http.StatusNotFound, "callback delivery is not enabled for this API")
+		return
+	}
+
+	webhooks, _ := specInfo.OpenAPI["webhooks"].(map[string]interface{})
+	pathItem, ok := webhooks[webhook].(map[string]interface{})
+	if ref, isRef := pathItem["$ref"].(string); isRef {
+		pathItem, ok = openapi.Resolve(specInfo.OpenAPI, ref)
+	}
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("webhook %s is not declared", webhook))
+		return
+	}
+
+	target := r.URL.Query().Get("url")
+	if target == "" {
+		var body struct {
+			URL string `json:"url"`
+		}
+		json.NewDecoder(io.LimitReader(r.Body, maxMockBodySize)).Decode(&body)
+		target = body.URL
+	}
+	if target == "" {
+		writeJSONError(w, http.StatusBadRequest, "the subscriber url is required")
+		return
+	}
+
+	var deliveries []Delivery
+	for _, method := range openapi.Methods {
+		operation, ok := pathItem[method].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		location := fmt.Sprintf("webhook %s %s", webhook, strings.ToUpper(method))
+		deliveries = append(deliveries, s.deliver(name, specInfo, webhook, method, target, operation, location))
+	}
+	if len(deliveries) == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("webhook %s declares no operation", webhook))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// handleDeliveries returns the delivery log of a spec as JSON
+func (s *Server) handleDeliveries(w http.ResponseWriter, r *http.Request) {
+	key, specInfo := s.requestedSpec(r)
+	name, ok := key.String(), specInfo != nil
+
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("API %s not found", name))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(specInfo.deliveries.list())
+}
+
+const deliveriesHTML = `<!DOCTYPE html>
+<html>
+  <head>
+    <title>{{ .Title }} - Deliveries</title>
+    <meta charset="utf-8"/>
+    <style>
+      body { font-family: sans-serif; margin: 2em; }
+      table { border-collapse: collapse; width: 100%; }
+      th, td { border: 1px solid #ddd; padding: 6px; text-align: left; vertical-align: top; }
+      pre { margin: 0; max-height: 12em; overflow: auto; }
+      .delivered { color: #2e7d32; }
+      .failed { color: #c62828; }
+    </style>
+  </head>
+  <body>
+    <h1>{{ .Title }}</h1>
+    <p><a href="/docs/{{ .Name }}">Documentation</a></p>
+    <h2>Callback and webhook deliveries</h2>
+    {{ if .Deliveries }}
+    <table>
+      <tr><th>Time</th><th>Event</th><th>Request</th><th>Status</th><th>Attempts</th><th>Payload</th></tr>
+      {{ range .Deliveries }}
+      <tr>
+        <td>{{ .Time.Format "2006-01-02 15:04:05" }}</td>
+        <td>{{ .Event }}</td>
+        <td>{{ .Method }} {{ .URL }}</td>
+        <td class="{{ .Status }}">{{ .Status }}{{ if .StatusCode }} ({{ .StatusCode }}){{ end }}{{ if .Error }}<br>{{ .Error }}{{ end }}</td>
+        <td>{{ .Attempts }}</td>
+        <td><pre>{{ .Payload }}</pre></td>
+      </tr>
+      {{ end }}
+    </table>
+    {{ else }}
+    <p>No callback or webhook has been delivered yet.</p>
+    {{ end }}
+  </body>
+</html>`
+
+// handleDeliveriesPage renders the delivery log of a spec on the documentation portal
+func (s *Server) handleDeliveriesPage(w http.ResponseWriter, r *http.Request) {
+	key, specInfo := s.requestedSpec(r)
+	name, ok := key.String(), specInfo != nil
+
+	if !ok {
+		http.Error(w, "API documentation not found", http.StatusNotFound)
+		return
+	}
+
+	tmpl, err := template.New("deliveries").Parse(deliveriesHTML)
+	if err != nil {
+		http.Error(w, "Failed to parse template", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Title      string
+		Name       string
+		Deliveries []Delivery
+	}{
+		Title:      specInfo.Title,
+		Name:       name,
+		Deliveries: specInfo.deliveries.list(),
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+	}
+}
+
+// sortedKeys returns the keys of a map in a stable order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
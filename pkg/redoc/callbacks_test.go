@@ -0,0 +1,255 @@
+package redoc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+	"github.com/BombartSimon/redokube/pkg/mockers"
+)
+
+const webhookSpec = `
+openapi: 3.1.0
+info: {title: Pets, version: "1"}
+paths: {}
+webhooks:
+  newPet:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [id]
+              properties:
+                id: {type: integer}
+      responses:
+        200: {description: ok}
+`
+
+// subscriber records the requests it receives, failing the first ones
+type subscriber struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	failures int
+}
+
+func newSubscriber(t *testing.T, failures int) *subscriber {
+	t.Helper()
+	sub := &subscriber{failures: failures}
+	sub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sub.mutex.Lock()
+		defer sub.mutex.Unlock()
+		sub.requests = append(sub.requests, r)
+		sub.bodies = append(sub.bodies, body)
+		if len(sub.requests) <= sub.failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(sub.Close)
+	return sub
+}
+
+func (sub *subscriber) received() int {
+	sub.mutex.Lock()
+	defer sub.mutex.Unlock()
+	return len(sub.requests)
+}
+
+// waitForDelivery waits until the latest delivery of a spec is no longer pending
+func waitForDelivery(t *testing.T, s *Server, namespace, name string) Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.specsMutex.RLock()
+		deliveries := s.specs[types.NamespacedName{Namespace: namespace, Name: name}].deliveries.list()
+		s.specsMutex.RUnlock()
+		if len(deliveries) > 0 && deliveries[0].Status != DeliveryPending {
+			return deliveries[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("the delivery did not complete")
+	return Delivery{}
+}
+
+func registerWebhookSpec(t *testing.T, s *Server) {
+	t.Helper()
+	registerTestSpec(t, s, "ns", "pets", webhookSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.Mock = true
+		spec.Spec.MockOptions = &docsv1.MockOptions{Callbacks: &docsv1.CallbackOptions{}}
+	})
+}
+
+func triggerWebhook(t *testing.T, s *Server, target string) []Delivery {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/specs/ns/pets/webhooks/newPet?url="+url.QueryEscape(target), nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("webhook status = %d: %s", w.Code, w.Body)
+	}
+	var deliveries []Delivery
+	if err := json.NewDecoder(w.Body).Decode(&deliveries); err != nil {
+		t.Fatal(err)
+	}
+	return deliveries
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	allowlist, _ := ParseCallbackAllowlist([]string{"127.0.0.0/8"})
+	s := newTestServer(t, WithCallbackAllowlist(allowlist))
+	registerWebhookSpec(t, s)
+	sub := newSubscriber(t, 0)
+
+	deliveries := triggerWebhook(t, s, sub.URL+"/hook")
+	if len(deliveries) != 1 || deliveries[0].Status != DeliveryPending || deliveries[0].Method != http.MethodPost {
+		t.Fatalf("deliveries = %+v", deliveries)
+	}
+	delivery := waitForDelivery(t, s, "ns", "pets")
+	if delivery.Status != DeliveryDelivered || delivery.Attempts != 1 || delivery.StatusCode != http.StatusOK {
+		t.Errorf("delivery = %+v", delivery)
+	}
+
+	r := sub.requests[0]
+	if r.URL.Path != "/hook" || r.Header.Get("X-Redokube-Event") != "newPet" || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("request = %s %s, headers %v", r.Method, r.URL, r.Header)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(sub.bodies[0], &payload); err != nil || payload["id"] == nil {
+		t.Errorf("payload = %s", sub.bodies[0])
+	}
+	if r.Header.Get(defaultSignatureHeader) != "" {
+		t.Error("unsigned payloads must not carry a signature")
+	}
+}
+
+func TestWebhookRefusesPrivateSubscribers(t *testing.T) {
+	s := newTestServer(t)
+	registerWebhookSpec(t, s)
+	sub := newSubscriber(t, 0)
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(sub.URL, "http://"))
+
+	// Addresses are refused before sending
+	for _, target := range []string{sub.URL, "http://169.254.169.254/latest/meta-data", "http://[::1]:" + port} {
+		deliveries := triggerWebhook(t, s, target)
+		if deliveries[0].Status != DeliveryFailed || !strings.Contains(deliveries[0].Error, "not allowed") {
+			t.Errorf("delivery to %s = %+v", target, deliveries[0])
+		}
+	}
+
+	// Host names are checked once resolved, and not retried
+	triggerWebhook(t, s, "http://localhost:"+port)
+	delivery := waitForDelivery(t, s, "ns", "pets")
+	if delivery.Status != DeliveryFailed || delivery.Attempts != 1 || !strings.Contains(delivery.Error, "not allowed") {
+		t.Errorf("delivery = %+v", delivery)
+	}
+	if sub.received() != 0 {
+		t.Errorf("the subscriber received %d requests", sub.received())
+	}
+}
+
+func TestWebhookBoundsConcurrentDeliveries(t *testing.T) {
+	allowlist, _ := ParseCallbackAllowlist([]string{"127.0.0.0/8"})
+	s := newTestServer(t, WithCallbackAllowlist(allowlist))
+	registerWebhookSpec(t, s)
+	for range maxConcurrentDeliveries {
+		s.deliverySlots <- struct{}{}
+	}
+
+	deliveries := triggerWebhook(t, s, newSubscriber(t, 0).URL)
+	if deliveries[0].Status != DeliveryFailed || deliveries[0].Error != "too many deliveries in progress" {
+		t.Errorf("delivery = %+v", deliveries[0])
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	allowlist, _ := ParseCallbackAllowlist([]string{"127.0.0.1"})
+	s := newTestServer(t, WithCallbackAllowlist(allowlist))
+	secret := []byte("signing secret")
+	payload := &mockers.RequestExample{MediaType: "application/json", Body: []byte(`{"id":1}`)}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload.Body)
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name         string
+		failures     int
+		wantStatus   string
+		wantAttempts int
+	}{
+		{"first attempt", 0, DeliveryDelivered, 1},
+		{"after retries", 2, DeliveryDelivered, 3},
+		{"retries exhausted", 5, DeliveryFailed, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := newSubscriber(t, tt.failures)
+			opts := &callbackOptions{backoff: time.Millisecond, maxRetries: 2, signatureHeader: "X-Signature", secret: secret}
+			delivery := &Delivery{Event: "newPet", Method: http.MethodPost, URL: sub.URL, Status: DeliveryPending}
+			s.send("ns/pets", &deliveryLog{}, delivery, opts, payload)
+
+			if delivery.Status != tt.wantStatus || delivery.Attempts != tt.wantAttempts {
+				t.Errorf("delivery = %+v, want %s after %d attempts", delivery, tt.wantStatus, tt.wantAttempts)
+			}
+			if sub.received() != tt.wantAttempts {
+				t.Errorf("the subscriber received %d requests, want %d", sub.received(), tt.wantAttempts)
+			}
+			for _, r := range sub.requests {
+				if got := r.Header.Get("X-Signature"); got != signature {
+					t.Errorf("signature = %q, want %q", got, signature)
+				}
+			}
+		})
+	}
+}
+
+func TestCallbackAllowlist(t *testing.T) {
+	listed, err := ParseCallbackAllowlist([]string{"hooks.example.com", "10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseCallbackAllowlist([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected an error for an invalid network")
+	}
+
+	tests := []struct {
+		name      string
+		allowlist *CallbackAllowlist
+		host      string
+		ip        string
+		want      bool
+	}{
+		{"public address", nil, "example.com", "93.184.215.14", true},
+		{"loopback", nil, "localhost", "127.0.0.1", false},
+		{"loopback v6", nil, "localhost", "::1", false},
+		{"private", nil, "api.ns.svc", "10.96.0.12", false},
+		{"metadata endpoint", nil, "metadata", "169.254.169.254", false},
+		{"unspecified", nil, "0.0.0.0", "0.0.0.0", false},
+		{"listed host resolving privately", listed, "Hooks.Example.com", "192.168.1.5", true},
+		{"listed network", listed, "api.ns.svc", "10.96.0.12", true},
+		{"public address outside of the allowlist", listed, "example.com", "93.184.215.14", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.allowlist.allows(tt.allowlist.listsHost(tt.host), net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("allows(%s, %s) = %v, want %v", tt.host, tt.ip, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package redoc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+	"github.com/BombartSimon/redokube/pkg/mockers"
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// SafeExtension flags the operations contract tests may call, or must never call when false
+const SafeExtension = "x-redokube-safe"
+
+// Bounds of the contract tests
+const (
+	defaultContractTestInterval = time.Hour
+	maxContractTestDuration     = 5 * time.Minute
+	maxContractBodySize         = 1 << 20
+	maxReportedViolations       = 5
+)
+
+// ContractTestInterval returns the time between two contract test runs
+func ContractTestInterval(options *docsv1.ContractTestOptions) time.Duration {
+	if options.Interval != nil && options.Interval.Duration > 0 {
+		return options.Interval.Duration
+	}
+	return defaultContractTestInterval
+}
+
+// RunContractTest calls the safe operations of a registered spec on its target Service
+// and checks the responses against the spec. A run lasts 5 minutes at most, the operations
+// left when it times out fail.
+func (s *Server) RunContractTest(ctx context.Context, openAPISpec *docsv1.OpenAPISpec) (*docsv1.ContractTestStatus, error) {
+	key := types.NamespacedName{Namespace: openAPISpec.Namespace, Name: openAPISpec.Name}
+	name := key.String()
+	options := openAPISpec.Spec.ContractTest
+
+	s.specsMutex.RLock()
+	specInfo, ok := s.specs[key]
+	s.specsMutex.RUnlock()
+
+	if !ok || specInfo.OpenAPI == nil {
+		return nil, fmt.Errorf("spec %s is not registered", name)
+	}
+
+	base := serviceURL(openAPISpec.Namespace, options.Target)
+	prefix := strings.TrimSuffix("/"+strings.Trim(options.PathPrefix, "/"), "/")
+
+	ctx, cancel := context.WithTimeout(ctx, maxContractTestDuration)
+	defer cancel()
+
+	status := &docsv1.ContractTestStatus{LastRun: metav1.Now()}
+	paths, _ := specInfo.OpenAPI["paths"].(map[string]interface{})
+	for _, pathKey := range sortedKeys(paths) {
+		pathItem, ok := paths[pathKey].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, method := range openapi.Methods {
+			operation, ok := pathItem[method].(map[string]interface{})
+			if !ok || !safeOperation(method, operation) {
+				continue
+			}
+			result := s.testOperation(ctx, specInfo, base, prefix, pathKey, method, operation, options.Headers)
+			if result.Passed {
+				status.Passed++
+			} else {
+				status.Failed++
+			}
+			status.Operations = append(status.Operations, result)
+		}
+	}
+
+	s.specsMutex.Lock()
+	if current, ok := s.specs[key]; ok {
+		current.contractTest = status
+	}
+	s.specsMutex.Unlock()
+	return status, nil
+}
+
+// safeOperation reports whether contract tests may call an operation
+func safeOperation(method string, operation map[string]interface{}) bool {
+	if safe, ok := operation[SafeExtension].(bool); ok {
+		return safe
+	}
+	return method == "get" || method == "head"
+}
+
+// testOperation sends a request built from the spec and checks the response
+func (s *Server) testOperation(ctx context.Context, specInfo *SpecInfo, base *url.URL, prefix, pathKey, method string, operation map[string]interface{}, headers map[string]string) docsv1.OperationResult {
+	result := docsv1.OperationResult{Operation: strings.ToUpper(method) + " " + pathKey}
+	location := "contract " + result.Operation
+	params := mockers.GenerateParameters(specInfo.OpenAPI, pathKey, operation, location, specInfo.MockOptions)
+
+	target := *base
+	target.Path = prefix + expandPath(pathKey, params["path"])
+	query := url.Values{}
+	for name, value := range params["query"] {
+		for _, item := range parameterValues(value) {
+			query.Add(name, item)
+		}
+	}
+	target.RawQuery = query.Encode()
+
+	var body []byte
+	contentType := ""
+	if _, ok := operation["requestBody"]; ok {
+		payload, err := mockers.GenerateRequest(specInfo.OpenAPI, operation, location, specInfo.MockOptions)
+		if err != nil {
+			result.Message = err.Error()
+			return result
+		}
+		body, contentType = payload.Body, payload.MediaType
+	}
+
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), target.String(), bytes.NewReader(body))
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	for name, value := range params["header"] {
+		req.Header.Set(name, strings.Join(parameterValues(value), ","))
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("User-Agent", "redokube")
+	for header, value := range headers {
+		req.Header.Set(header, value)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxContractBodySize))
+	if err != nil {
+		result.Message = fmt.Sprintf("cannot read response: %v", err)
+		return result
+	}
+
+	result.StatusCode = int32(resp.StatusCode)
+	problems := checkResponse(specInfo.OpenAPI, method, operation, resp, content)
+	result.Passed = len(problems) == 0
+	result.Message = strings.Join(problems, "; ")
+	return result
+}
+
+// expandPath replaces the parameters of a path template with their values
+func expandPath(pathKey string, values map[string]interface{}) string {
+	for name, value := range values {
+		pathKey = strings.ReplaceAll(pathKey, "{"+name+"}", url.PathEscape(strings.Join(parameterValues(value), ",")))
+	}
+	return pathKey
+}
+
+// parameterValues formats a parameter value, one string per item for arrays
+func parameterValues(value interface{}) []string {
+	if items, ok := value.([]interface{}); ok {
+		out := make([]string, len(items))
+		for i, item := range items {
+			out[i] = fmt.Sprint(item)
+		}
+		return out
+	}
+	return []string{fmt.Sprint(value)}
+}
+
+// checkResponse compares a response with the responses declared by an operation
+func checkResponse(doc openapi.Document, method string, operation map[string]interface{}, resp *http.Response, body []byte) []string {
+	responses, _ := operation["responses"].(map[string]interface{})
+	response := declaredResponse(responses, resp.StatusCode)
+	if ref, ok := response["$ref"].(string); ok {
+		response, _ = openapi.Resolve(doc, ref)
+	}
+	if response == nil {
+		return []string{fmt.Sprintf("status code %d is not declared", resp.StatusCode)}
+	}
+
+	var problems []string
+	if resp.StatusCode >= 500 {
+		problems = append(problems, fmt.Sprintf("server error %d", resp.StatusCode))
+	}
+
+	responseHeaders, _ := response["headers"].(map[string]interface{})
+	for _, name := range sortedKeys(responseHeaders) {
+		header, _ := responseHeaders[name].(map[string]interface{})
+		if ref, ok := header["$ref"].(string); ok {
+			header, _ = openapi.Resolve(doc, ref)
+		}
+		if required, _ := header["required"].(bool); required && resp.Header.Get(name) == "" {
+			problems = append(problems, fmt.Sprintf("missing header %s", name))
+		}
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 || method == "head" || resp.StatusCode == http.StatusNoContent {
+		return problems
+	}
+	if len(body) == 0 {
+		return append(problems, "the response has no body")
+	}
+
+	mediaTypeName, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return append(problems, fmt.Sprintf("invalid content type %q", resp.Header.Get("Content-Type")))
+	}
+	mediaType, ok := declaredMediaType(content, mediaTypeName)
+	if !ok {
+		return append(problems, fmt.Sprintf("content type %s is not declared", mediaTypeName))
+	}
+	schema, ok := mediaType["schema"].(map[string]interface{})
+	if !ok || (mediaTypeName != "application/json" && !strings.HasSuffix(mediaTypeName, "+json")) {
+		return problems
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return append(problems, fmt.Sprintf("invalid JSON body: %v", err))
+	}
+	violations := mockers.Validate(doc, schema, value)
+	if len(violations) > maxReportedViolations {
+		violations = append(violations[:maxReportedViolations], fmt.Sprintf("and %d more", len(violations)-maxReportedViolations))
+	}
+	for _, violation := range violations {
+		problems = append(problems, "body: "+violation)
+	}
+	return problems
+}
+
+// declaredResponse returns the response declared for a status code, its range such as 2XX, or the default one
+func declaredResponse(responses map[string]interface{}, statusCode int) map[string]interface{} {
+	for _, key := range []string{strconv.Itoa(statusCode), fmt.Sprintf("%dXX", statusCode/100), "default"} {
+		if response, ok := responses[key].(map[string]interface{}); ok {
+			return response
+		}
+	}
+	return nil
+}
+
+// declaredMediaType returns the media type of a content map matching a content type, wildcards included
+func declaredMediaType(content map[string]interface{}, mediaTypeName string) (map[string]interface{}, bool) {
+	main, _, _ := strings.Cut(mediaTypeName, "/")
+	for _, candidate := range []string{mediaTypeName, main + "/*", "*/*"} {
+		for key, value := range content {
+			declared, _, err := mime.ParseMediaType(key)
+			if err != nil || !strings.EqualFold(declared, candidate) {
+				continue
+			}
+			mediaType, _ := value.(map[string]interface{})
+			return mediaType, true
+		}
+	}
+	return nil, false
+}
+
+// handleContractReport returns the latest contract test report of a spec as JSON
+func (s *Server) handleContractReport(w http.ResponseWriter, r *http.Request) {
+	key, specInfo := s.requestedSpec(r)
+	name, ok := key.String(), specInfo != nil
+
+	s.specsMutex.RLock()
+	var report *docsv1.ContractTestStatus
+	if ok {
+		report = specInfo.contractTest
+	}
+	s.specsMutex.RUnlock()
+
+	if !ok || report == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("no contract test report for API %s", name))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+const contractHTML = `<!DOCTYPE html>
+<html>
+  <head>
+    <title>{{ .Title }} - Contract tests</title>
+    <meta charset="utf-8"/>
+    <style>
+      body { font-family: sans-serif; margin: 2em; }
+      table { border-collapse: collapse; width: 100%; }
+      th, td { border: 1px solid #ddd; padding: 6px; text-align: left; vertical-align: top; }
+      .passed { color: #2e7d32; }
+      .failed { color: #c62828; }
+    </style>
+  </head>
+  <body>
+    <h1>{{ .Title }}</h1>
+    <p><a href="/docs/{{ .Name }}">Documentation</a></p>
+    <h2>Contract tests</h2>
+    {{ with .Report }}
+    <p>Last run {{ .LastRun.Format "2006-01-02 15:04:05" }}: <span class="passed">{{ .Passed }} passed</span>, <span class="failed">{{ .Failed }} failed</span></p>
+    <table>
+      <tr><th>Operation</th><th>Result</th><th>Status code</th><th>Details</th></tr>
+      {{ range .Operations }}
+      <tr>
+        <td>{{ .Operation }}</td>
+        {{ if .Passed }}<td class="passed">passed</td>{{ else }}<td class="failed">failed</td>{{ end }}
+        <td>{{ if .StatusCode }}{{ .StatusCode }}{{ end }}</td>
+        <td>{{ .Message }}</td>
+      </tr>
+      {{ end }}
+    </table>
+    {{ else }}
+    <p>No contract test has run yet.</p>
+    {{ end }}
+  </body>
+</html>`
+
+// handleContractPage renders the latest contract test report of a spec on the documentation portal
+func (s *Server) handleContractPage(w http.ResponseWriter, r *http.Request) {
+	key, specInfo := s.requestedSpec(r)
+	name, ok := key.String(), specInfo != nil
+
+	s.specsMutex.RLock()
+	var report *docsv1.ContractTestStatus
+	if ok {
+		report = specInfo.contractTest
+	}
+	s.specsMutex.RUnlock()
+
+	if !ok {
+		http.Error(w, "API documentation not found", http.StatusNotFound)
+		return
+	}
+
+	tmpl, err := template.New("contract").Parse(contractHTML)
+	if err != nil {
+		http.Error(w, "Failed to parse template", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Title  string
+		Name   string
+		Report *docsv1.ContractTestStatus
+	}{
+		Title:  specInfo.Title,
+		Name:   name,
+		Report: report,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,187 @@
+package redoc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+const contractSpec = `
+openapi: 3.0.3
+info: {title: Contract, version: "1"}
+components:
+  schemas:
+    Item:
+      type: object
+      required: [id]
+      properties:
+        id: {type: integer}
+paths:
+  /items:
+    get:
+      responses:
+        200:
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Item'}
+    post:
+      responses:
+        201:
+          description: created
+    delete:
+      x-redokube-safe: true
+      responses:
+        204:
+          description: deleted
+  /broken:
+    get:
+      responses:
+        200:
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Item'}
+  /teapot:
+    get:
+      responses:
+        200:
+          description: ok
+  /missing:
+    get:
+      responses:
+        200:
+          description: ok
+        default:
+          description: error
+          content:
+            application/json:
+              schema:
+                type: object
+                required: [message]
+                properties:
+                  message: {type: string}
+  /unsafe:
+    get:
+      x-redokube-safe: false
+      responses:
+        200:
+          description: ok
+`
+
+// newContractService starts a service answering the contract test requests, and points the
+// client of the server at it whatever the Service address of the request
+func newContractService(t *testing.T, s *Server) *[]string {
+	t.Helper()
+	var mutex sync.Mutex
+	var calls []string
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/items":
+			if r.Method == http.MethodDelete {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Write([]byte(`{"id": 1}`))
+		case "/v1/broken":
+			w.Write([]byte(`{"id": "one"}`))
+		case "/v1/teapot":
+			w.WriteHeader(http.StatusTeapot)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "not found"}`))
+		}
+	}))
+	t.Cleanup(service.Close)
+
+	s.httpClient = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, service.Listener.Addr().String())
+		},
+	}}
+	return &calls
+}
+
+func TestRunContractTest(t *testing.T) {
+	s := newTestServer(t)
+	calls := newContractService(t, s)
+	options := &docsv1.ContractTestOptions{
+		Target:     docsv1.ServiceTarget{ServiceName: "items", Port: 8080},
+		PathPrefix: "/v1",
+	}
+	registerTestSpec(t, s, "ns", "items", contractSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.ContractTest = options
+	})
+
+	spec := &docsv1.OpenAPISpec{}
+	spec.Namespace, spec.Name = "ns", "items"
+	spec.Spec.ContractTest = options
+	status, err := s.RunContractTest(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(map[string]docsv1.OperationResult)
+	for _, result := range status.Operations {
+		results[result.Operation] = result
+	}
+	tests := []struct {
+		operation   string
+		wantPassed  bool
+		wantMessage string
+	}{
+		{"GET /items", true, ""},
+		{"DELETE /items", true, ""},
+		{"GET /broken", false, "body: "},
+		{"GET /teapot", false, "status code 418 is not declared"},
+		{"GET /missing", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operation, func(t *testing.T) {
+			result, ok := results[tt.operation]
+			if !ok {
+				t.Fatalf("operation not tested, results: %+v", status.Operations)
+			}
+			if result.Passed != tt.wantPassed || !strings.Contains(result.Message, tt.wantMessage) {
+				t.Errorf("result = %+v, want passed %v with %q", result, tt.wantPassed, tt.wantMessage)
+			}
+		})
+	}
+	if status.Passed != 3 || status.Failed != 2 || len(status.Operations) != 5 {
+		t.Errorf("status = %d passed, %d failed, %d operations", status.Passed, status.Failed, len(status.Operations))
+	}
+
+	// Unsafe methods are only called when flagged safe, safe ones skipped when flagged unsafe
+	for _, call := range *calls {
+		if call == "POST /v1/items" || call == "GET /v1/unsafe" {
+			t.Errorf("contract test called %s", call)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/specs/ns/items/contract", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "GET /teapot") {
+		t.Errorf("report status = %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestRunContractTestOfUnregisteredSpec(t *testing.T) {
+	s := newTestServer(t)
+	spec := &docsv1.OpenAPISpec{}
+	spec.Namespace, spec.Name = "ns", "unknown"
+	spec.Spec.ContractTest = &docsv1.ContractTestOptions{Target: docsv1.ServiceTarget{ServiceName: "unknown", Port: 8080}}
+	if _, err := s.RunContractTest(context.Background(), spec); err == nil {
+		t.Error("expected an error for an unregistered spec")
+	}
+}
@@ -0,0 +1,213 @@
+package redoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+// Defaults of the health checks
+const (
+	defaultHealthInterval = 30 * time.Second
+	maxHealthHistory      = 100
+)
+
+// Health status of an API
+const (
+	HealthUp      = "up"
+	HealthDown    = "down"
+	HealthUnknown = "unknown"
+)
+
+// HealthProbe is the outcome of a single health check
+type HealthProbe struct {
+	Time       time.Time `json:"time"`
+	Up         bool      `json:"up"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	LatencyMS  int64     `json:"latencyMs"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// HealthSummary describes the latest probe of an API and its uptime over the history
+type HealthSummary struct {
+	Status    string     `json:"status"`
+	LatencyMS int64      `json:"latencyMs"`
+	Uptime    float64    `json:"uptime"`
+	LastCheck *time.Time `json:"lastCheck,omitempty"`
+}
+
+// healthMonitor probes a service in the background and keeps its latest probes
+type healthMonitor struct {
+	mutex    sync.Mutex
+	url      string
+	expected int
+	interval time.Duration
+	probes   []HealthProbe
+	stop     chan struct{}
+}
+
+// newHealthMonitor creates the monitor of an OpenAPISpec, nil when health checks are disabled
+func newHealthMonitor(openAPISpec *docsv1.OpenAPISpec) *healthMonitor {
+	options := openAPISpec.Spec.HealthCheck
+	if options == nil {
+		return nil
+	}
+
+	target := serviceURL(openAPISpec.Namespace, options.Target)
+	target.Path = "/" + strings.TrimPrefix(options.Path, "/")
+	monitor := &healthMonitor{
+		url:      target.String(),
+		expected: int(options.ExpectedStatus),
+		interval: defaultHealthInterval,
+		stop:     make(chan struct{}),
+	}
+	if monitor.expected == 0 {
+		monitor.expected = http.StatusOK
+	}
+	if options.Interval != nil && options.Interval.Duration > 0 {
+		monitor.interval = options.Interval.Duration
+	}
+	return monitor
+}
+
+// run probes the service until the monitor is stopped
+func (m *healthMonitor) run(client *http.Client) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.record(m.probe(client))
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe sends a health check request
+func (m *healthMonitor) probe(client *http.Client) HealthProbe {
+	probe := HealthProbe{Time: time.Now()}
+	resp, err := client.Get(m.url)
+	probe.LatencyMS = time.Since(probe.Time).Milliseconds()
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxMockBodySize))
+
+	probe.StatusCode = resp.StatusCode
+	probe.Up = resp.StatusCode == m.expected
+	if !probe.Up {
+		probe.Error = fmt.Sprintf("expected status code %d", m.expected)
+	}
+	return probe
+}
+
+// record adds a probe to the history, dropping the oldest ones beyond the history size
+func (m *healthMonitor) record(probe HealthProbe) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.probes = append(m.probes, probe)
+	if len(m.probes) > maxHealthHistory {
+		m.probes = m.probes[len(m.probes)-maxHealthHistory:]
+	}
+}
+
+// history returns a copy of the probes, oldest first
+func (m *healthMonitor) history() []HealthProbe {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]HealthProbe(nil), m.probes...)
+}
+
+// summary describes the latest probe and the share of successful ones
+func (m *healthMonitor) summary() *HealthSummary {
+	if m == nil {
+		return nil
+	}
+	probes := m.history()
+	if len(probes) == 0 {
+		return &HealthSummary{Status: HealthUnknown}
+	}
+
+	up := 0
+	for _, probe := range probes {
+		if probe.Up {
+			up++
+		}
+	}
+	last := probes[len(probes)-1]
+	summary := &HealthSummary{
+		Status:    HealthDown,
+		LatencyMS: last.LatencyMS,
+		Uptime:    float64(up*10000/len(probes)) / 100,
+		LastCheck: &last.Time,
+	}
+	if last.Up {
+		summary.Status = HealthUp
+	}
+	return summary
+}
+
+// healthBadge renders the health of an API as a small inline badge
+func healthBadge(summary *HealthSummary) string {
+	if summary == nil {
+		return ""
+	}
+	colors := map[string]string{HealthUp: "#2e7d32", HealthDown: "#c62828", HealthUnknown: "#757575"}
+	text := summary.Status
+	if summary.LastCheck != nil {
+		text = fmt.Sprintf("%s %dms, %.2f%% uptime", summary.Status, summary.LatencyMS, summary.Uptime)
+	}
+	return fmt.Sprintf(`<span class="health %s" style="color: %s">&#9679; %s</span>`, summary.Status, colors[summary.Status], text)
+}
+
+// CatalogEntry describes a registered API in the catalog and on the index page
+type CatalogEntry struct {
+	Name        string         `json:"name"`
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Version     string         `json:"version,omitempty"`
+	Namespace   string         `json:"namespace"`
+	Visibility  string         `json:"visibility"`
+	Category    string         `json:"category,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Status      string         `json:"status"`
+	LastUpdated time.Time      `json:"lastUpdated"`
+	DocsURL     string         `json:"docsUrl"`
+	SpecURL     string         `json:"specUrl"`
+	Mock        bool           `json:"mock"`
+	Health      *HealthSummary `json:"health,omitempty"`
+}
+
+// handleCatalog lists the registered APIs with their health
+func (s *Server) handleCatalog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.catalog(r))
+}
+
+// handleHealth returns the health summary and probe history of an API
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	key, specInfo := s.requestedSpec(r)
+	name, ok := key.String(), specInfo != nil
+
+	if !ok || specInfo.health == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("no health check for API %s", name))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		*HealthSummary
+		History []HealthProbe `json:"history"`
+	}{specInfo.health.summary(), specInfo.health.history()})
+}
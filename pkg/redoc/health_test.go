@@ -0,0 +1,206 @@
+package redoc
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+// roundTripFunc answers the requests of an http.Client without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestUnregisterSpecStopsHealthChecks(t *testing.T) {
+	s := newTestServer(t)
+	var probes atomic.Int32
+	s.httpClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		probes.Add(1)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+	})}
+
+	registerTestSpec(t, s, "ns", "pets", collisionSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.HealthCheck = &docsv1.HealthCheckOptions{
+			Target:   docsv1.ServiceTarget{ServiceName: "pets", Port: 8080},
+			Path:     "/healthz",
+			Interval: &metav1.Duration{Duration: 5 * time.Millisecond},
+		}
+	})
+	deadline := time.Now().Add(time.Second)
+	for probes.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if probes.Load() < 2 {
+		t.Fatal("the health monitor did not probe the service")
+	}
+
+	s.UnregisterSpec("ns", "pets")
+	stopped := probes.Load()
+	time.Sleep(50 * time.Millisecond)
+	// A probe already in flight when the monitor stopped may still complete
+	if got := probes.Load(); got > stopped+1 {
+		t.Errorf("%d probes after unregistering, want none", got-stopped)
+	}
+
+	for _, path := range []string{"/docs/ns/pets", "/specs/ns/pets.json", "/api/v1/specs/ns/pets/health"} {
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s status = %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(s.specDirectory, "ns", "pets.json")); !os.IsNotExist(err) {
+		t.Errorf("spec file still present: %v", err)
+	}
+
+	// Unregistering an unknown spec is a no-op
+	s.UnregisterSpec("ns", "pets")
+}
+
+func TestHealthProbe(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.WriteHeader(http.StatusOK)
+		case "/ready":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer upstream.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name           string
+		url            string
+		expected       int
+		wantUp         bool
+		wantStatusCode int
+		wantError      string
+	}{
+		{"expected status", upstream.URL + "/healthz", http.StatusOK, true, http.StatusOK, ""},
+		{"custom expected status", upstream.URL + "/ready", http.StatusNoContent, true, http.StatusNoContent, ""},
+		{"unexpected status", upstream.URL + "/broken", http.StatusOK, false, http.StatusServiceUnavailable, "expected status code 200"},
+		{"other success status", upstream.URL + "/ready", http.StatusOK, false, http.StatusNoContent, "expected status code 200"},
+		{"unreachable", closed.URL + "/healthz", http.StatusOK, false, 0, "connection refused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := &healthMonitor{url: tt.url, expected: tt.expected}
+			probe := monitor.probe(upstream.Client())
+			if probe.Up != tt.wantUp || probe.StatusCode != tt.wantStatusCode {
+				t.Errorf("probe = %+v, want up %v with status %d", probe, tt.wantUp, tt.wantStatusCode)
+			}
+			if !strings.Contains(probe.Error, tt.wantError) || (tt.wantError == "" && probe.Error != "") {
+				t.Errorf("error = %q, want %q", probe.Error, tt.wantError)
+			}
+			if probe.Time.IsZero() || probe.LatencyMS < 0 {
+				t.Errorf("probe time = %v and latency = %d", probe.Time, probe.LatencyMS)
+			}
+		})
+	}
+}
+
+func TestHealthHistoryTrimmed(t *testing.T) {
+	monitor := &healthMonitor{}
+	start := time.Now()
+	for i := 0; i < maxHealthHistory+10; i++ {
+		monitor.record(HealthProbe{Time: start.Add(time.Duration(i) * time.Second), LatencyMS: int64(i)})
+	}
+
+	history := monitor.history()
+	if len(history) != maxHealthHistory {
+		t.Fatalf("history of %d probes, want %d", len(history), maxHealthHistory)
+	}
+	if history[0].LatencyMS != 10 || history[len(history)-1].LatencyMS != maxHealthHistory+9 {
+		t.Errorf("history from %d to %d, want the latest probes from 10 to %d", history[0].LatencyMS, history[len(history)-1].LatencyMS, maxHealthHistory+9)
+	}
+
+	// The history is a copy
+	history[0].LatencyMS = -1
+	if monitor.history()[0].LatencyMS != 10 {
+		t.Error("changing the returned history changed the monitor")
+	}
+}
+
+func TestHealthSummary(t *testing.T) {
+	// probes builds a history from the outcomes of its probes, the last one taking 42ms
+	probes := func(outcomes ...bool) []HealthProbe {
+		history := make([]HealthProbe, len(outcomes))
+		for i, up := range outcomes {
+			history[i] = HealthProbe{Time: time.Unix(int64(i), 0), Up: up, LatencyMS: 42}
+		}
+		return history
+	}
+
+	tests := []struct {
+		name       string
+		probes     []HealthProbe
+		wantStatus string
+		wantUptime float64
+	}{
+		{"no probe", nil, HealthUnknown, 0},
+		{"always up", probes(true, true, true), HealthUp, 100},
+		{"down last", probes(true, true, true, false), HealthDown, 75},
+		{"up last", probes(false, false, true), HealthUp, 33.33},
+		{"rounded down", probes(false, true, true), HealthUp, 66.66},
+		{"always down", probes(false, false), HealthDown, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := (&healthMonitor{probes: tt.probes}).summary()
+			if summary.Status != tt.wantStatus || summary.Uptime != tt.wantUptime {
+				t.Errorf("summary = %+v, want %s with %.2f%% uptime", summary, tt.wantStatus, tt.wantUptime)
+			}
+			if len(tt.probes) == 0 {
+				if summary.LastCheck != nil {
+					t.Errorf("last check = %v, want none", summary.LastCheck)
+				}
+				return
+			}
+			if last := tt.probes[len(tt.probes)-1]; summary.LastCheck == nil || !summary.LastCheck.Equal(last.Time) || summary.LatencyMS != 42 {
+				t.Errorf("summary = %+v, want the time and latency of the last probe", summary)
+			}
+		})
+	}
+
+	var disabled *healthMonitor
+	if disabled.summary() != nil {
+		t.Error("summary of a disabled monitor, want nil")
+	}
+}
+
+func TestHealthBadge(t *testing.T) {
+	lastCheck := time.Now()
+	tests := []struct {
+		name    string
+		summary *HealthSummary
+		want    string
+	}{
+		{"disabled", nil, ""},
+		{"unknown", &HealthSummary{Status: HealthUnknown}, `<span class="health unknown" style="color: #757575">&#9679; unknown</span>`},
+		{"up", &HealthSummary{Status: HealthUp, LatencyMS: 12, Uptime: 99.5, LastCheck: &lastCheck}, `<span class="health up" style="color: #2e7d32">&#9679; up 12ms, 99.50% uptime</span>`},
+		{"down", &HealthSummary{Status: HealthDown, LatencyMS: 3, Uptime: 33.33, LastCheck: &lastCheck}, `<span class="health down" style="color: #c62828">&#9679; down 3ms, 33.33% uptime</span>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := healthBadge(tt.summary); got != tt.want {
+				t.Errorf("badge = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
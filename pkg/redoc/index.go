@@ -0,0 +1,269 @@
+package redoc
+
+import (
+	"html/template"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// CategoryLabel is the label of an OpenAPISpec used to group APIs by category on the index page
+const CategoryLabel = "category"
+
+// Groupings of the index page
+const (
+	GroupByNamespace = "namespace"
+	GroupByCategory  = "category"
+)
+
+// uncategorized names the group of the APIs without a category label
+const uncategorized = "Uncategorized"
+
+const indexHTML = `<!DOCTYPE html>
+<html>
+  <head>
+    <title>{{ .Title }}</title>
+    <meta charset="utf-8"/>
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    {{ with .Branding.FaviconURL }}<link rel="icon" href="{{ . }}">{{ end }}
+    <style>
+      body { font-family: Roboto, sans-serif; margin: 0 auto; max-width: 1100px; padding: 2em; color: #333; }
+      #filter { width: 100%; padding: 8px; font-size: 16px; box-sizing: border-box; margin-bottom: 1em; }
+      .api { border: 1px solid #e0e0e0; border-radius: 4px; padding: 12px 16px; margin-bottom: 8px; }
+      .api h3 { margin: 0 0 4px 0; display: inline; }
+      .api .meta { color: #757575; font-size: 13px; }
+      .api p { margin: 6px 0; }
+      .tag { display: inline-block; background: #eef2f7; border-radius: 3px; padding: 1px 6px; margin-right: 4px; font-size: 12px; }
+      .hidden { display: none; }
+      .redokube-header { display: flex; align-items: center; gap: 16px; margin-bottom: 1em; }
+      .redokube-header img { max-height: 48px; }
+      .redokube-footer { margin-top: 2em; }
+    </style>
+    {{ with .Branding.CSS }}<style>{{ . }}</style>{{ end }}
+  </head>
+  <body>
+    {{ if or .Branding.LogoURL .Branding.Header }}<header class="redokube-header">{{ with .Branding.LogoURL }}<img src="{{ . }}" alt="logo">{{ end }}{{ .Branding.Header }}</header>{{ end }}
+    <h1>{{ .Title }}</h1>
+    {{ if .Groups }}
+    <input id="filter" type="search" placeholder="Filter APIs by name, description, tag..." autofocus>
+    {{ range .Groups }}
+    <section class="group">
+      <h2>{{ .Name }}</h2>
+      {{ range .APIs }}
+      <div class="api" data-search="{{ .Title }} {{ .Name }} {{ .Description }} {{ .Version }} {{ .Namespace }} {{ .Category }} {{ range .Tags }}{{ . }} {{ end }}">
+        <a href="{{ .DocsURL }}"><h3>{{ .Title }}</h3></a>
+        {{ if .Version }}<span class="meta">v{{ .Version }}</span>{{ end }}
+        {{ with .Health }}{{ badge . }}{{ end }}
+        {{ if .Description }}<p>{{ .Description }}</p>{{ end }}
+        {{ if .Tags }}<div>{{ range .Tags }}<span class="tag">{{ . }}</span>{{ end }}</div>{{ end }}
+        <div class="meta">{{ .Namespace }} &middot; {{ .Status }} &middot; updated {{ .LastUpdated.Format "2006-01-02 15:04" }}{{ if .Mock }} &middot; <a href="/mock/{{ .Name }}/">mock</a>{{ end }}</div>
+      </div>
+      {{ end }}
+    </section>
+    {{ end }}
+    <script>
+      document.getElementById("filter").addEventListener("input", function (event) {
+        var terms = event.target.value.toLowerCase().split(/\s+/).filter(Boolean);
+        document.querySelectorAll(".group").forEach(function (group) {
+          var visible = 0;
+          group.querySelectorAll(".api").forEach(function (api) {
+            var text = api.getAttribute("data-search").toLowerCase();
+            var match = terms.every(function (term) { return text.indexOf(term) >= 0; });
+            api.classList.toggle("hidden", !match);
+            if (match) { visible++; }
+          });
+          group.classList.toggle("hidden", visible === 0);
+        });
+      });
+    </script>
+    {{ else }}
+    <p>No API documentation available yet.</p>
+    {{ end }}
+    {{ with .Branding.Footer }}<footer class="redokube-footer">{{ . }}</footer>{{ end }}
+  </body>
+</html>`
+
+// IndexPage is the data rendered by the index template
+type IndexPage struct {
+	Title    string
+	Groups   []IndexGroup
+	Branding PageBranding
+}
+
+// IndexGroup is a group of APIs on the index page, such as a namespace
+type IndexGroup struct {
+	Name string
+	APIs []CatalogEntry
+}
+
+// WithIndexGrouping groups the APIs of the index page by namespace or category
+func WithIndexGrouping(groupBy string) ServerOption {
+	return func(s *Server) {
+		s.indexGroupBy = groupBy
+	}
+}
+
+// WithIndexTemplate renders the index page with a custom html/template file, such as one
+// mounted from a ConfigMap. The file is read on every request so updates apply without a restart,
+// and the default page is rendered while it is missing or invalid.
+func WithIndexTemplate(path string) ServerOption {
+	return func(s *Server) {
+		s.indexTemplate = path
+	}
+}
+
+// handleIndex renders the portal page listing the registered APIs.
+// The grouping can be chosen per request with ?groupBy=namespace or ?groupBy=category.
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	groupBy := r.URL.Query().Get("groupBy")
+	if groupBy != GroupByNamespace && groupBy != GroupByCategory {
+		groupBy = s.indexGroupBy
+	}
+
+	tmpl, err := s.parseIndexTemplate()
+	if err != nil {
+		// Keep the portal usable while the custom template is broken
+		klog.Errorf("Failed to load index template %s, rendering the default one: %v", s.indexTemplate, err)
+		tmpl, err = parseIndexTemplate(indexHTML)
+	}
+	if err != nil {
+		klog.Errorf("Failed to parse index template: %v", err)
+		http.Error(w, "Failed to parse template", http.StatusInternalServerError)
+		return
+	}
+
+	page := IndexPage{
+		Title:    "Redokube Documentation",
+		Groups:   groupCatalog(s.catalog(r), groupBy),
+		Branding: s.pageBranding(nil),
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, page); err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		return
+	}
+}
+
+// parseIndexTemplate parses the custom index template when configured, the default one otherwise
+func (s *Server) parseIndexTemplate() (*template.Template, error) {
+	if s.indexTemplate == "" {
+		return parseIndexTemplate(indexHTML)
+	}
+	custom, err := os.ReadFile(s.indexTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return parseIndexTemplate(string(custom))
+}
+
+// parseIndexTemplate parses an index template with the functions available to it
+func parseIndexTemplate(content string) (*template.Template, error) {
+	return template.New("index").Funcs(template.FuncMap{
+		"badge": func(summary *HealthSummary) template.HTML {
+			return template.HTML(healthBadge(summary))
+		},
+	}).Parse(content)
+}
+
+// catalog describes the registered APIs listed by the portal that the caller of a request may read, sorted by name
+func (s *Server) catalog(r *http.Request) []CatalogEntry {
+	s.specsMutex.RLock()
+	catalog := make([]CatalogEntry, 0, len(s.specs))
+	for key, specInfo := range s.specs {
+		name := key.String()
+		entry := CatalogEntry{
+			Name:        name,
+			Title:       specInfo.Title,
+			Description: specInfo.Description,
+			Version:     specInfo.Version,
+			Namespace:   specInfo.Namespace,
+			Visibility:  specInfo.Visibility,
+			Category:    specInfo.Category,
+			Tags:        specInfo.Tags,
+			Status:      "available",
+			LastUpdated: specInfo.LastUpdated,
+			DocsURL:     "/docs/" + name,
+			SpecURL:     specInfo.SpecURL,
+			Mock:        specInfo.Mock,
+			Health:      specInfo.health.summary(),
+		}
+		if entry.Health != nil {
+			entry.Status = entry.Health.Status
+		}
+		catalog = append(catalog, entry)
+	}
+	s.specsMutex.RUnlock()
+
+	listed := catalog[:0]
+	for _, entry := range catalog {
+		if !visible(r, entry.Visibility) {
+			continue
+		}
+		allowed, err := s.allowed(r, entry.Namespace, "get")
+		if err != nil {
+			klog.Errorf("Failed to authorize the listing of %s: %v", entry.Name, err)
+		}
+		if allowed {
+			listed = append(listed, entry)
+		}
+	}
+	catalog = listed
+
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Name < catalog[j].Name })
+	return catalog
+}
+
+// groupCatalog splits the catalog into groups sorted by name, with the APIs sorted by title
+func groupCatalog(catalog []CatalogEntry, groupBy string) []IndexGroup {
+	groups := make(map[string][]CatalogEntry)
+	for _, entry := range catalog {
+		key := entry.Namespace
+		if groupBy == GroupByCategory {
+			key = entry.Category
+			if key == "" {
+				key = uncategorized
+			}
+		}
+		groups[key] = append(groups[key], entry)
+	}
+
+	out := make([]IndexGroup, 0, len(groups))
+	for name, apis := range groups {
+		sort.SliceStable(apis, func(i, j int) bool {
+			return strings.ToLower(apis[i].Title) < strings.ToLower(apis[j].Title)
+		})
+		out = append(out, IndexGroup{Name: name, APIs: apis})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		// The uncategorized APIs come last
+		if (out[i].Name == uncategorized) != (out[j].Name == uncategorized) {
+			return out[j].Name == uncategorized
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// documentTags returns the names of the tags declared by a document
+func documentTags(doc map[string]interface{}) []string {
+	var tags []string
+	list, _ := doc["tags"].([]interface{})
+	for _, raw := range list {
+		tag, _ := raw.(map[string]interface{})
+		if name, ok := tag["name"].(string); ok && name != "" {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// documentInfo returns a field of the info object of a document
+func documentInfo(doc map[string]interface{}, field string) string {
+	info, _ := doc["info"].(map[string]interface{})
+	value, _ := info[field].(string)
+	return value
+}
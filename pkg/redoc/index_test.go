@@ -0,0 +1,121 @@
+package redoc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+func TestGroupCatalog(t *testing.T) {
+	catalog := []CatalogEntry{
+		{Name: "shop/orders", Title: "orders", Namespace: "shop", Category: "Sales"},
+		{Name: "billing/invoices", Title: "Invoices", Namespace: "billing", Category: "Sales"},
+		{Name: "shop/carts", Title: "Carts", Namespace: "shop"},
+		{Name: "auth/users", Title: "Users", Namespace: "auth", Category: "Identity"},
+	}
+	tests := []struct {
+		groupBy string
+		want    []string
+	}{
+		{GroupByNamespace, []string{"auth: Users", "billing: Invoices", "shop: Carts, orders"}},
+		// The APIs without a category come last
+		{GroupByCategory, []string{"Identity: Users", "Sales: Invoices, orders", "Uncategorized: Carts"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.groupBy, func(t *testing.T) {
+			var got []string
+			for _, group := range groupCatalog(catalog, tt.groupBy) {
+				var titles []string
+				for _, api := range group.APIs {
+					titles = append(titles, api.Title)
+				}
+				got = append(got, group.Name+": "+strings.Join(titles, ", "))
+			}
+			if strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("groups = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexTemplate(t *testing.T) {
+	directory := t.TempDir()
+	custom := filepath.Join(directory, "custom.html")
+	if err := os.WriteFile(custom, []byte(`{{ range .Groups }}{{ .Name }}{{ end }} custom`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := filepath.Join(directory, "broken.html")
+	if err := os.WriteFile(broken, []byte(`{{ range .Groups }}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"custom", custom, "ns custom"},
+		{"missing file", filepath.Join(directory, "missing.html"), "Redokube Documentation"},
+		{"invalid template", broken, "Redokube Documentation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, WithIndexTemplate(tt.template))
+			registerTestSpec(t, s, "ns", "pets", collisionSpec, nil)
+
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("status = %d, want %q in %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestIndexListsReadableSpecsOfThePortal(t *testing.T) {
+	s := newTestServer(t,
+		WithAuthorizer(namespaceAuthorizer{"shop": true}),
+		WithPortals(Portal{Host: "public.example.com", Visibilities: []string{docsv1.VisibilityPublic}}),
+	)
+	register := func(namespace, name, visibility string) {
+		registerTestSpec(t, s, namespace, name, collisionSpec, func(spec *docsv1.OpenAPISpec) {
+			spec.Spec.Title = name + "-title"
+			spec.Spec.Visibility = visibility
+		})
+	}
+	register("shop", "orders", docsv1.VisibilityPublic)
+	register("shop", "stock", docsv1.VisibilityInternal)
+	register("billing", "invoices", docsv1.VisibilityPublic)
+
+	tests := []struct {
+		host string
+		want []string
+	}{
+		{"public.example.com", []string{"orders-title"}},
+		{"internal.example.com", []string{"orders-title", "stock-title"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, r)
+			body := w.Body.String()
+
+			for _, title := range []string{"orders-title", "stock-title", "invoices-title"} {
+				want := false
+				for _, listed := range tt.want {
+					want = want || listed == title
+				}
+				if strings.Contains(body, title) != want {
+					t.Errorf("%s listed = %v, want %v", title, !want, want)
+				}
+			}
+		})
+	}
+}
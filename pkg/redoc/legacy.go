@@ -0,0 +1,101 @@
+package redoc
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// legacyPrefixes are the routes that addressed a spec by its namespace and name joined with a dash,
+// such as /docs/shop-orders, before they were addressed as /docs/shop/orders
+var legacyPrefixes = []string{"/api/v1/specs/", "/docs/", "/specs/", "/mock/", "/record/"}
+
+// redirectLegacy redirects the requests using a dash-joined spec name to the namespace and name routes,
+// so that bookmarks, embeds and scripts written before keep working. Names joined with a dash are
+// ambiguous when namespaces or names contain dashes, so only the names matching a single spec are redirected.
+func (s *Server) redirectLegacy(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if location, ok := s.legacyLocation(r); ok {
+			if r.URL.RawQuery != "" {
+				location += "?" + r.URL.RawQuery
+			}
+			// 308 keeps the method and body of webhook triggers and recording deletions
+			http.Redirect(w, r, location, http.StatusPermanentRedirect)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// legacyLocation returns the current URL of a request using a dash-joined spec name, false when the
+// request uses the current routes or no visible spec readable by the caller has that name
+func (s *Server) legacyLocation(r *http.Request) (string, bool) {
+	for _, prefix := range legacyPrefixes {
+		rest, ok := strings.CutPrefix(r.URL.Path, prefix)
+		if !ok {
+			continue
+		}
+		segment, suffix, _ := strings.Cut(rest, "/")
+		if suffix != "" {
+			suffix = "/" + suffix
+		}
+		if prefix == "/specs/" {
+			// Spec files were served as /specs/{namespace}-{name}.json
+			if suffix != "" || !strings.HasSuffix(segment, ".json") {
+				return "", false
+			}
+			segment, suffix = strings.TrimSuffix(segment, ".json"), ".json"
+		} else if s.currentSpec(rest) {
+			return "", false
+		}
+		if segment == "" {
+			return "", false
+		}
+
+		key, ok := s.legacySpec(r, segment)
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf("%s%s/%s%s", prefix, key.Namespace, key.Name, suffix), true
+	}
+	return "", false
+}
+
+// currentSpec tells whether the path after a route prefix starts with the namespace and name of a registered spec
+func (s *Server) currentSpec(rest string) bool {
+	segments := strings.SplitN(rest, "/", 3)
+	if len(segments) < 2 {
+		return false
+	}
+	s.specsMutex.RLock()
+	defer s.specsMutex.RUnlock()
+	_, ok := s.specs[types.NamespacedName{Namespace: segments[0], Name: segments[1]}]
+	return ok
+}
+
+// legacySpec returns the only spec whose namespace and name joined with a dash give a legacy name,
+// when the portal of the request lists it and the caller may read it
+func (s *Server) legacySpec(r *http.Request, legacyName string) (types.NamespacedName, bool) {
+	var matches []types.NamespacedName
+	var visibility string
+	s.specsMutex.RLock()
+	for key, specInfo := range s.specs {
+		if key.Namespace+"-"+key.Name == legacyName {
+			matches = append(matches, key)
+			visibility = specInfo.Visibility
+		}
+	}
+	s.specsMutex.RUnlock()
+
+	if len(matches) != 1 || !visible(r, visibility) {
+		return types.NamespacedName{}, false
+	}
+	allowed, err := s.allowed(r, matches[0].Namespace, "get")
+	if err != nil {
+		klog.Errorf("Failed to authorize the redirect of %s: %v", r.URL.Path, err)
+	}
+	return matches[0], allowed
+}
@@ -0,0 +1,81 @@
+package redoc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+func TestRedirectLegacy(t *testing.T) {
+	s := newTestServer(t)
+	registerTestSpec(t, s, "ns", "pets", mockSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.Mock = true
+	})
+	registerCollidingSpecs(t, s)
+
+	tests := []struct {
+		method       string
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{http.MethodGet, "/docs/ns-pets", http.StatusPermanentRedirect, "/docs/ns/pets"},
+		{http.MethodGet, "/docs/ns-pets/deliveries", http.StatusPermanentRedirect, "/docs/ns/pets/deliveries"},
+		{http.MethodGet, "/specs/ns-pets.json", http.StatusPermanentRedirect, "/specs/ns/pets.json"},
+		{http.MethodGet, "/mock/ns-pets/pets/1?status=404", http.StatusPermanentRedirect, "/mock/ns/pets/pets/1?status=404"},
+		{http.MethodPost, "/record/ns-pets/pets", http.StatusPermanentRedirect, "/record/ns/pets/pets"},
+		{http.MethodGet, "/api/v1/specs/ns-pets/fixtures?schema=Pet", http.StatusPermanentRedirect, "/api/v1/specs/ns/pets/fixtures?schema=Pet"},
+		{http.MethodDelete, "/api/v1/specs/ns-pets/recordings", http.StatusPermanentRedirect, "/api/v1/specs/ns/pets/recordings"},
+		{http.MethodGet, "/docs/ns/pets", http.StatusOK, ""},
+		{http.MethodGet, "/docs/a-b/c", http.StatusOK, ""},
+		{http.MethodGet, "/docs/a-b-c", http.StatusNotFound, ""},
+		{http.MethodGet, "/specs/a-b-c.json", http.StatusNotFound, ""},
+		{http.MethodGet, "/docs/unknown-pets", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if location := w.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", location, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestRedirectLegacyHiddenSpecs(t *testing.T) {
+	s := newTestServer(t,
+		WithPortals(Portal{Host: "public.example.com", Visibilities: []string{"public"}}),
+		WithAuthorizer(namespaceAuthorizer{"ns": true}),
+	)
+	registerTestSpec(t, s, "ns", "pets", mockSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.Visibility = docsv1.VisibilityInternal
+	})
+	registerTestSpec(t, s, "other", "pets", mockSpec, nil)
+
+	tests := []struct {
+		host       string
+		path       string
+		wantStatus int
+	}{
+		{"internal.example.com", "/docs/ns-pets", http.StatusPermanentRedirect},
+		{"public.example.com", "/docs/ns-pets", http.StatusNotFound},
+		{"internal.example.com", "/docs/other-pets", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package redoc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/BombartSimon/redokube/pkg/mockers"
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// handleMock answers API calls under /mock/{namespace}/{name}/ with responses generated from the spec.
+// The Accept header selects the media type of the body and Accept-Language the locale of the generated data.
+func (s *Server) handleMock(w http.ResponseWriter, r *http.Request) {
+	key, specInfo := s.requestedSpec(r)
+	name, ok := key.String(), specInfo != nil
+
+	replay := ok && specInfo.recording != nil && specInfo.recording.Replay
+	if !ok || (!specInfo.Mock && !replay) || specInfo.OpenAPI == nil {
+		writeJSONError(w, http.StatusNotFound, "no mock server is enabled for this API")
+		return
+	}
+
+	requestPath := strings.TrimPrefix(r.URL.Path, "/mock/"+name)
+	pathKey, pathParams, ok := openapi.MatchPath(specInfo.OpenAPI, requestPath)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("no operation matches %s", requestPath))
+		return
+	}
+
+	// Recorded traffic wins over generated responses
+	if replay {
+		requestURI := requestPath
+		if r.URL.RawQuery != "" {
+			requestURI += "?" + r.URL.RawQuery
+		}
+		if recording, ok := specInfo.recordings.match(r.Method, pathKey, requestURI); ok {
+			writeRecording(w, recording)
+			return
+		}
+	}
+
+	opts := specInfo.MockOptions
+	if locale, ok := mockers.MatchAcceptLanguage(r.Header.Get("Accept-Language")); ok {
+		opts.Locale = locale
+	}
+
+	body := decodeMockBody(r)
+	request := mockers.MockRequest{
+		PathKey:    pathKey,
+		Method:     r.Method,
+		Accept:     r.Header.Get("Accept"),
+		PathParams: pathParams,
+		Body:       body,
+	}
+
+	example, err := mockers.GenerateResponse(specInfo.OpenAPI, request, opts)
+	switch {
+	case errors.Is(err, mockers.ErrPathNotDeclared):
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, mockers.ErrMethodNotDeclared):
+		writeJSONError(w, http.StatusMethodNotAllowed, err.Error())
+		return
+	case err != nil:
+		klog.Errorf("Failed to generate mock response for %s %s of %s: %v", r.Method, pathKey, name, err)
+		writeJSONError(w, http.StatusInternalServerError, "failed to generate the mock response")
+		return
+	}
+
+	for header, value := range example.Headers {
+		w.Header().Set(header, fmt.Sprint(value))
+	}
+	locale, ok := mockers.NormalizeLocale(opts.Locale)
+	if !ok {
+		locale = mockers.DefaultLocale
+	}
+	w.Header().Set("Content-Language", locale)
+
+	if example.Body != nil {
+		w.Header().Set("Content-Type", example.MediaType)
+	}
+	w.WriteHeader(example.StatusCode)
+	if example.Body != nil {
+		if _, err := w.Write(example.Body); err != nil {
+			klog.Warningf("Failed to write mock response for %s: %v", name, err)
+		}
+	}
+
+	// Callbacks follow successful calls only
+	if specInfo.callbacks == nil || example.StatusCode < 200 || example.StatusCode >= 300 {
+		return
+	}
+	paths, _ := specInfo.OpenAPI["paths"].(map[string]interface{})
+	pathItem, _ := paths[pathKey].(map[string]interface{})
+	operation, ok := pathItem[strings.ToLower(r.Method)].(map[string]interface{})
+	if !ok {
+		return
+	}
+	s.scheduleCallbacks(name, specInfo, operation, &openapi.RuntimeContext{
+		URL:             requestURL(r),
+		Method:          r.Method,
+		StatusCode:      example.StatusCode,
+		RequestHeaders:  r.Header,
+		Query:           r.URL.Query(),
+		PathParams:      pathParams,
+		RequestBody:     body,
+		ResponseHeaders: w.Header(),
+		ResponseBody:    example.Value,
+	})
+}
+
+// requestURL rebuilds the absolute URL of a request, used by the $url runtime expression
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.RequestURI())
+}
+
+// maxMockBodySize bounds the request bodies decoded by the mock server
+const maxMockBodySize = 1 << 20
+
+// decodeMockBody returns the JSON body of a mock request, nil when it has none or it cannot be decoded
+func decodeMockBody(r *http.Request) interface{} {
+	if r.Body == nil || !strings.Contains(r.Header.Get("Content-Type"), "json") {
+		return nil
+	}
+	var body interface{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxMockBodySize)).Decode(&body); err != nil {
+		return nil
+	}
+	return body
+}
+
+// writeJSONError reports an error as a JSON object
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,93 @@
+package redoc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+const mockSpec = `
+openapi: 3.0.3
+info: {title: Pets, version: "1"}
+paths:
+  /pets:
+    get:
+      responses:
+        200:
+          description: ok
+          content:
+            application/json:
+              schema: {type: array, items: {type: string}}
+  /broken:
+    get:
+      responses:
+        200:
+          description: An example that cannot be encoded as JSON
+          content:
+            application/json:
+              schema: {type: number}
+              example: .nan
+`
+
+// newTestServer creates a server storing its specs in a temporary directory
+func newTestServer(t *testing.T, options ...ServerOption) *Server {
+	t.Helper()
+	return NewServer(append([]ServerOption{WithSpecDirectory(t.TempDir())}, options...)...)
+}
+
+// registerTestSpec registers a spec given inline
+func registerTestSpec(t *testing.T, s *Server, namespace, name, content string, edit func(*docsv1.OpenAPISpec)) {
+	t.Helper()
+	spec := &docsv1.OpenAPISpec{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	spec.Spec.Title = name
+	spec.Spec.SpecContent = content
+	if edit != nil {
+		edit(spec)
+	}
+	if _, err := s.RegisterSpec(spec); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandleMockStatus(t *testing.T) {
+	s := newTestServer(t)
+	registerTestSpec(t, s, "ns", "pets", mockSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.Mock = true
+	})
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		header       string
+		wantStatus   int
+		wantLanguage string
+	}{
+		{"generated", http.MethodGet, "/mock/ns/pets/pets", "", http.StatusOK, "en"},
+		{"negotiated locale", http.MethodGet, "/mock/ns/pets/pets", "fr-FR,fr;q=0.9", http.StatusOK, "fr"},
+		{"unknown path", http.MethodGet, "/mock/ns/pets/owners", "", http.StatusNotFound, ""},
+		{"undeclared method", http.MethodDelete, "/mock/ns/pets/pets", "", http.StatusMethodNotAllowed, ""},
+		{"encoding failure", http.MethodGet, "/mock/ns/pets/broken", "", http.StatusInternalServerError, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				r.Header.Set("Accept-Language", tt.header)
+			}
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Language"); got != tt.wantLanguage {
+				t.Errorf("Content-Language = %q, want %q", got, tt.wantLanguage)
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package redoc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"k8s.io/klog/v2"
+)
+
+// Defaults of the OIDC authenticator
+const (
+	defaultSessionTTL = 8 * time.Hour
+	loginTimeout      = 10 * time.Minute
+	sessionCookie     = "redokube_session"
+	loginCookie       = "redokube_login"
+)
+
+// OIDCConfig configures the login with an OpenID Connect provider
+type OIDCConfig struct {
+	// URL of the provider, such as "https://accounts.example.com", serving /.well-known/openid-configuration
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+
+	// URL of the /auth/callback route as seen by the browser, such as "https://docs.example.com/auth/callback"
+	RedirectURL string
+
+	// Scopes requested in addition to "openid"
+	Scopes []string
+
+	// Claims of the ID token holding the user name and groups, "sub" and "groups" by default
+	UsernameClaim string
+	GroupsClaim   string
+
+	// Key signing the session cookies. Sessions do not survive a restart and are not shared between replicas when empty.
+	SessionKey []byte
+
+	// Lifetime of a session, 8 hours by default
+	SessionTTL time.Duration
+
+	// Client used to reach the provider, such as one trusting a local test provider
+	HTTPClient *http.Client
+}
+
+// OIDCAuthenticator logs users in with an OpenID Connect provider and keeps them in signed session cookies.
+// It serves /auth/login, /auth/callback and /auth/logout, the latter with POST only.
+type OIDCAuthenticator struct {
+	config OIDCConfig
+
+	// Discovered lazily so that the server starts while the provider is unavailable
+	mutex    sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	insecure bool
+}
+
+// session is the content of the session cookie
+type session struct {
+	User    string   `json:"user"`
+	Groups  []string `json:"groups,omitempty"`
+	Expires int64    `json:"exp"`
+}
+
+// login is the content of the cookie kept during the login flow
+type login struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	ReturnTo string `json:"returnTo"`
+	Expires  int64  `json:"exp"`
+}
+
+// NewOIDCAuthenticator creates an authenticator logging users in with an OpenID Connect provider
+func NewOIDCAuthenticator(config OIDCConfig) (*OIDCAuthenticator, error) {
+	if config.IssuerURL == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, fmt.Errorf("OIDC issuer URL, client ID and redirect URL are required")
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "sub"
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+	if config.SessionTTL <= 0 {
+		config.SessionTTL = defaultSessionTTL
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: outboundTimeout}
+	}
+	if len(config.SessionKey) == 0 {
+		klog.Warning("No OIDC session key set, sessions will not survive a restart of the server")
+		config.SessionKey = make([]byte, 32)
+		if _, err := rand.Read(config.SessionKey); err != nil {
+			return nil, fmt.Errorf("failed to generate a session key: %v", err)
+		}
+	}
+
+	redirect, err := url.Parse(config.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OIDC redirect URL: %v", err)
+	}
+	if !redirect.IsAbs() || redirect.Host == "" {
+		return nil, fmt.Errorf("OIDC redirect URL %q must be absolute, such as https://docs.example.com/auth/callback", config.RedirectURL)
+	}
+	return &OIDCAuthenticator{config: config, insecure: redirect.Scheme != "https"}, nil
+}
+
+// Authenticate reads the session cookie of a request. The cookie is removed from the request
+// so that it is not forwarded to the proxied services.
+func (a *OIDCAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil, nil
+	}
+	removeCookie(r, sessionCookie)
+
+	var s session
+	if err := a.decode(sessionCookie, cookie.Value, &s); err != nil {
+		return nil, err
+	}
+	if s.User == "" {
+		return nil, fmt.Errorf("session has no user")
+	}
+	if time.Now().Unix() > s.Expires {
+		return nil, fmt.Errorf("session of %q expired", s.User)
+	}
+	return &User{Name: s.User, Groups: s.Groups}, nil
+}
+
+// Challenge redirects browsers to the login page and rejects the other requests
+func (a *OIDCAuthenticator) Challenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Redirect(w, r, "/auth/login?redirect="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+		return
+	}
+	writeJSONError(w, http.StatusUnauthorized, "authentication required")
+}
+
+// ServeHTTP serves the login flow
+func (a *OIDCAuthenticator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/auth/login":
+		a.handleLogin(w, r)
+	case "/auth/callback":
+		a.handleCallback(w, r)
+	case "/auth/logout":
+		// A GET would let any page log its visitors out with an image or a link
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSONError(w, http.StatusMethodNotAllowed, "log out with a POST request")
+			return
+		}
+		a.deleteCookie(w, sessionCookie)
+		http.Redirect(w, r, "/", http.StatusFound)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleLogin redirects the browser to the provider
+func (a *OIDCAuthenticator) handleLogin(w http.ResponseWriter, r *http.Request) {
+	config, err := a.discover(r.Context())
+	if err != nil {
+		klog.Errorf("Failed to reach the OIDC provider: %v", err)
+		writeJSONError(w, http.StatusBadGateway, "identity provider unavailable")
+		return
+	}
+
+	returnTo := r.URL.Query().Get("redirect")
+	// Only return to local pages, not to another site
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		returnTo = "/"
+	}
+	flow := login{
+		State:    randomToken(),
+		Nonce:    randomToken(),
+		ReturnTo: returnTo,
+		Expires:  time.Now().Add(loginTimeout).Unix(),
+	}
+	a.setCookie(w, loginCookie, a.encode(loginCookie, flow), loginTimeout)
+	http.Redirect(w, r, config.AuthCodeURL(flow.State, oauth2.SetAuthURLParam("nonce", flow.Nonce)), http.StatusFound)
+}
+
+// handleCallback exchanges the authorization code, verifies the ID token and opens a session
+func (a *OIDCAuthenticator) handleCallback(w http.ResponseWriter, r *http.Request) {
+	var flow login
+	cookie, err := r.Cookie(loginCookie)
+	if err != nil || a.decode(loginCookie, cookie.Value, &flow) != nil || time.Now().Unix() > flow.Expires {
+		writeJSONError(w, http.StatusBadRequest, "login expired, please try again")
+		return
+	}
+	a.deleteCookie(w, loginCookie)
+
+	query := r.URL.Query()
+	if message := query.Get("error"); message != "" {
+		writeJSONError(w, http.StatusUnauthorized, fmt.Sprintf("login failed: %s %s", message, query.Get("error_description")))
+		return
+	}
+	if !hmac.Equal([]byte(query.Get("state")), []byte(flow.State)) {
+		writeJSONError(w, http.StatusBadRequest, "invalid login state")
+		return
+	}
+
+	config, err := a.discover(r.Context())
+	if err != nil {
+		klog.Errorf("Failed to reach the OIDC provider: %v", err)
+		writeJSONError(w, http.StatusBadGateway, "identity provider unavailable")
+		return
+	}
+	ctx := context.WithValue(r.Context(), oauth2.HTTPClient, a.config.HTTPClient)
+	token, err := config.Exchange(ctx, query.Get("code"))
+	if err != nil {
+		klog.Warningf("Failed to exchange the OIDC authorization code: %v", err)
+		writeJSONError(w, http.StatusUnauthorized, "login failed")
+		return
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	idToken, err := a.verify(r.Context(), rawIDToken)
+	if err != nil {
+		klog.Warningf("Rejected OIDC ID token: %v", err)
+		writeJSONError(w, http.StatusUnauthorized, "login failed")
+		return
+	}
+	if !hmac.Equal([]byte(idToken.Nonce), []byte(flow.Nonce)) {
+		writeJSONError(w, http.StatusUnauthorized, "invalid login nonce")
+		return
+	}
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		klog.Warningf("Failed to decode the OIDC ID token claims: %v", err)
+		writeJSONError(w, http.StatusUnauthorized, "login failed")
+		return
+	}
+
+	user, _ := claims[a.config.UsernameClaim].(string)
+	if user == "" {
+		writeJSONError(w, http.StatusUnauthorized, fmt.Sprintf("ID token has no %q claim", a.config.UsernameClaim))
+		return
+	}
+	s := session{User: user, Expires: time.Now().Add(a.config.SessionTTL).Unix()}
+	switch groups := claims[a.config.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				s.Groups = append(s.Groups, name)
+			}
+		}
+	case string:
+		s.Groups = splitList(groups)
+	}
+
+	a.setCookie(w, sessionCookie, a.encode(sessionCookie, s), a.config.SessionTTL)
+	http.Redirect(w, r, flow.ReturnTo, http.StatusFound)
+}
+
+// discover reads the endpoints and signing keys of the provider once
+func (a *OIDCAuthenticator) discover(ctx context.Context) (*oauth2.Config, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.oauth2 != nil {
+		return a.oauth2, nil
+	}
+
+	// The provider keeps the client of the context to refresh its signing keys
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, a.config.HTTPClient), a.config.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+	a.verifier = provider.Verifier(&oidc.Config{ClientID: a.config.ClientID})
+	a.oauth2 = &oauth2.Config{
+		ClientID:     a.config.ClientID,
+		ClientSecret: a.config.ClientSecret,
+		RedirectURL:  a.config.RedirectURL,
+		Scopes:       append([]string{oidc.ScopeOpenID}, a.config.Scopes...),
+		Endpoint:     provider.Endpoint(),
+	}
+	return a.oauth2, nil
+}
+
+// verify checks the signature, issuer, audience and expiry of an ID token
+func (a *OIDCAuthenticator) verify(ctx context.Context, rawIDToken string) (*oidc.IDToken, error) {
+	if _, err := a.discover(ctx); err != nil {
+		return nil, err
+	}
+	return a.verifier.Verify(ctx, rawIDToken)
+}
+
+// encode signs the value of the cookie name. The name is part of the signature, so that
+// the value of a cookie, such as the login cookie, is not accepted as another one.
+func (a *OIDCAuthenticator) encode(name string, value interface{}) string {
+	payload, _ := json.Marshal(value)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + a.signature(name, encoded)
+}
+
+// decode checks the signature of the value of the cookie name and decodes it
+func (a *OIDCAuthenticator) decode(name, cookie string, value interface{}) error {
+	encoded, signature, ok := strings.Cut(cookie, ".")
+	if !ok {
+		return fmt.Errorf("malformed cookie")
+	}
+	if !hmac.Equal([]byte(signature), []byte(a.signature(name, encoded))) {
+		return fmt.Errorf("invalid cookie signature")
+	}
+	return decodeSegment(encoded, value)
+}
+
+// signature returns the HMAC of an encoded value of the cookie name
+func (a *OIDCAuthenticator) signature(name, encoded string) string {
+	mac := hmac.New(sha256.New, a.config.SessionKey)
+	mac.Write([]byte(name + "\x00" + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// setCookie sets a cookie of the authenticator for a lifetime
+func (a *OIDCAuthenticator) setCookie(w http.ResponseWriter, name, value string, lifetime time.Duration) {
+	http.SetCookie(w, a.cookie(name, value, int(lifetime.Seconds())))
+}
+
+// deleteCookie expires a cookie of the authenticator. A zero MaxAge would send no Max-Age
+// and turn the cookie into a session cookie instead of deleting it.
+func (a *OIDCAuthenticator) deleteCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, a.cookie(name, "", -1))
+}
+
+// cookie returns a cookie of the authenticator, deleted when maxAge is negative
+func (a *OIDCAuthenticator) cookie(name, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   !a.insecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// decodeSegment decodes a base64url encoded JSON value
+func decodeSegment(segment string, value interface{}) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, value)
+}
+
+// randomToken returns a random URL-safe token
+func randomToken() string {
+	token := make([]byte, 24)
+	rand.Read(token)
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// removeCookie removes a cookie from the Cookie headers of a request
+func removeCookie(r *http.Request, name string) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name != name {
+			r.AddCookie(cookie)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package redoc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testProvider is an OpenID Connect provider signing its ID tokens with a single RSA key
+type testProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// ID token returned by the token endpoint
+	idToken string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	p := &testProvider{key: newRSAKey(t)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     p.idToken,
+		})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// claims returns valid claims for the client "redokube", to be edited by the tests
+func (p *testProvider) claims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":    p.server.URL,
+		"sub":    "alice",
+		"aud":    "redokube",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"iat":    time.Now().Unix(),
+		"groups": []string{"dev", "ops"},
+	}
+}
+
+// sign returns an RS256 ID token holding claims
+func sign(t *testing.T, key *rsa.PrivateKey, keyID string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func newTestOIDCAuthenticator(t *testing.T, p *testProvider) *OIDCAuthenticator {
+	t.Helper()
+	a, err := NewOIDCAuthenticator(OIDCConfig{
+		IssuerURL:   p.server.URL,
+		ClientID:    "redokube",
+		RedirectURL: "http://docs.example.com/auth/callback",
+		SessionKey:  []byte("test session key"),
+		HTTPClient:  p.server.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestOIDCVerify(t *testing.T) {
+	p := newTestProvider(t)
+	a := newTestOIDCAuthenticator(t, p)
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr string
+	}{
+		{"valid", func() string { return sign(t, p.key, "test-key", p.claims()) }, ""},
+		{"wrong audience", func() string {
+			claims := p.claims()
+			claims["aud"] = "another-client"
+			return sign(t, p.key, "test-key", claims)
+		}, "expected audience"},
+		{"expired", func() string {
+			claims := p.claims()
+			claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			return sign(t, p.key, "test-key", claims)
+		}, "token is expired"},
+		{"unknown kid", func() string { return sign(t, newRSAKey(t), "unknown-key", p.claims()) }, "failed to verify signature"},
+		{"wrong issuer", func() string {
+			claims := p.claims()
+			claims["iss"] = "https://issuer.example.com"
+			return sign(t, p.key, "test-key", claims)
+		}, "id token issued by a different provider"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idToken, err := a.verify(context.Background(), tt.token())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if idToken.Subject != "alice" {
+				t.Errorf("subject = %q, want alice", idToken.Subject)
+			}
+		})
+	}
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	p := newTestProvider(t)
+	a := newTestOIDCAuthenticator(t, p)
+
+	// The login redirects to the provider with a state and a nonce kept in the login cookie
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/login?redirect=/docs/ns/pets", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want %d", w.Code, http.StatusFound)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := location.Scheme + "://" + location.Host + location.Path; got != p.server.URL+"/authorize" {
+		t.Fatalf("login redirects to %s", got)
+	}
+	state, nonce := location.Query().Get("state"), location.Query().Get("nonce")
+	loginCookies := w.Result().Cookies()
+
+	claims := p.claims()
+	claims["nonce"] = nonce
+	p.idToken = sign(t, p.key, "test-key", claims)
+
+	r := httptest.NewRequest(http.MethodGet, "/auth/callback?code=code&state="+url.QueryEscape(state), nil)
+	for _, cookie := range loginCookies {
+		r.AddCookie(cookie)
+	}
+	w = httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/docs/ns/pets" {
+		t.Fatalf("callback status = %d, location = %q: %s", w.Code, w.Header().Get("Location"), w.Body)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == loginCookie && cookie.MaxAge >= 0 {
+			t.Errorf("login cookie kept after the callback: %s", cookie)
+		}
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/docs/ns/pets", nil)
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == sessionCookie {
+			r.AddCookie(cookie)
+		}
+	}
+	user, err := a.Authenticate(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user == nil || user.Name != "alice" || len(user.Groups) != 2 {
+		t.Errorf("user = %+v, want alice in dev and ops", user)
+	}
+}
+
+func TestOIDCLogoutDeletesSession(t *testing.T) {
+	a := newTestOIDCAuthenticator(t, newTestProvider(t))
+
+	tests := []struct {
+		method      string
+		wantStatus  int
+		wantDeleted bool
+	}{
+		{http.MethodPost, http.StatusFound, true},
+		{http.MethodGet, http.StatusMethodNotAllowed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, httptest.NewRequest(tt.method, "/auth/logout", nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			header := w.Header().Get("Set-Cookie")
+			deleted := strings.HasPrefix(header, sessionCookie+"=;") && strings.Contains(header, "Max-Age=0")
+			if deleted != tt.wantDeleted {
+				t.Errorf("Set-Cookie = %q, want the session cookie expired: %v", header, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestNewOIDCAuthenticatorRequiresAbsoluteRedirect(t *testing.T) {
+	for _, redirect := range []string{"/auth/callback", "docs.example.com/auth/callback"} {
+		_, err := NewOIDCAuthenticator(OIDCConfig{IssuerURL: "https://accounts.example.com", ClientID: "redokube", RedirectURL: redirect})
+		if err == nil {
+			t.Errorf("expected an error for the redirect URL %q", redirect)
+		}
+	}
+}
+
+func TestOIDCRejectsLoginCookieAsSession(t *testing.T) {
+	a := newTestOIDCAuthenticator(t, newTestProvider(t))
+	s := newTestServer(t, WithAuthenticator(a))
+
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/login", nil))
+	var login *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == loginCookie {
+			login = cookie
+		}
+	}
+	if login == nil {
+		t.Fatalf("no login cookie set: %s", w.Body)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"login cookie", login.Value},
+		{"session without user", a.encode(sessionCookie, session{Expires: time.Now().Add(time.Hour).Unix()})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/specs", nil)
+			r.AddCookie(&http.Cookie{Name: sessionCookie, Value: tt.value})
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, r)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
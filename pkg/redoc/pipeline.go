@@ -0,0 +1,235 @@
+package redoc
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+	"github.com/BombartSimon/redokube/pkg/converter"
+	"github.com/BombartSimon/redokube/pkg/mockers"
+	"github.com/BombartSimon/redokube/pkg/openapi"
+	"github.com/BombartSimon/redokube/pkg/overlay"
+)
+
+// fetchSpec returns the raw specification content, either inline or from a file or URL
+func (s *Server) fetchSpec(name string, openAPISpec *docsv1.OpenAPISpec) ([]byte, error) {
+	specPath := openAPISpec.Spec.SpecPath
+	specContent := openAPISpec.Spec.SpecContent
+
+	// Check if we have direct content or need to fetch from path
+	if specContent != "" {
+		klog.Infof("Using direct OpenAPI spec content for %s", name)
+		return []byte(specContent), nil
+	}
+
+	if specPath == "" {
+		return nil, fmt.Errorf("neither specPath nor specContent provided in OpenAPISpec %s", name)
+	}
+
+	// If it's a URL, download directly to maintain the exact format
+	if strings.HasPrefix(specPath, "http://") || strings.HasPrefix(specPath, "https://") {
+		klog.Infof("Downloading OpenAPI spec from URL: %s", specPath)
+		resp, err := s.httpClient.Get(specPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download OpenAPI spec from URL %s: %v", specPath, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to download OpenAPI spec from URL %s: status code %d", specPath, resp.StatusCode)
+		}
+
+		content, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spec content: %v", err)
+		}
+		return content, nil
+	}
+
+	// For local files, read the content directly
+	content, err := os.ReadFile(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OpenAPI spec file %s: %v", specPath, err)
+	}
+	return content, nil
+}
+
+// applyOverlays applies the overlays of the OpenAPISpec in order to the fetched content.
+// Missing optional ConfigMaps are skipped.
+func (s *Server) applyOverlays(name string, openAPISpec *docsv1.OpenAPISpec, content []byte) ([]byte, []docsv1.AppliedOverlay, error) {
+	if len(openAPISpec.Spec.Overlays) == 0 {
+		return content, nil, nil
+	}
+	doc, err := openapi.Parse(content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var applied []docsv1.AppliedOverlay
+	for i, source := range openAPISpec.Spec.Overlays {
+		origin := fmt.Sprintf("inline[%d]", i)
+		raw := []byte(source.Content)
+		if ref := source.ConfigMapKeyRef; ref != nil {
+			origin = fmt.Sprintf("configmap/%s/%s", ref.Name, ref.Key)
+			if raw, err = s.configMapValue(openAPISpec.Namespace, ref); err != nil {
+				return nil, nil, err
+			}
+			if raw == nil {
+				klog.Infof("Skipping missing optional overlay %s of %s", origin, name)
+				continue
+			}
+		}
+
+		parsed, err := overlay.Parse(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid overlay %s: %v", origin, err)
+		}
+		result, err := overlay.Apply(doc, parsed)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to apply overlay %s: %v", origin, err)
+		}
+		if len(result.UnmatchedTargets) > 0 {
+			klog.Warningf("Targets of overlay %s matched nothing in %s: %s", origin, name, strings.Join(result.UnmatchedTargets, ", "))
+		}
+		applied = append(applied, docsv1.AppliedOverlay{
+			Source:           origin,
+			Title:            parsed.Info.Title,
+			Version:          parsed.Info.Version,
+			Actions:          int32(len(parsed.Actions)),
+			UnmatchedTargets: result.UnmatchedTargets,
+		})
+	}
+
+	if content, err = openapi.Marshal(doc); err != nil {
+		return nil, nil, err
+	}
+	klog.Infof("Applied %d overlays to %s", len(applied), name)
+	return content, applied, nil
+}
+
+// processedSpec is the outcome of the transformation pipeline
+type processedSpec struct {
+	content         []byte
+	mockWarnings    []string
+	invalidExamples []string
+
+	// Published document as OpenAPI 3, used to serve live mocks and fixtures
+	document    openapi.Document
+	mockOptions mockers.Options
+}
+
+// transformSpec applies the transformations requested on the OpenAPISpec to the raw content.
+// Recordings are promoted to examples when requested.
+func transformSpec(name string, openAPISpec *docsv1.OpenAPISpec, content []byte, recordings []Recording) (*processedSpec, error) {
+	out := &processedSpec{mockOptions: mockOptions(openAPISpec)}
+
+	// Upgrade to a newer OpenAPI version if requested
+	if target := openAPISpec.Spec.UpgradeTo; target != "" {
+		doc, err := openapi.Parse(content)
+		if err != nil {
+			return nil, err
+		}
+		doc, err = converter.Upgrade(doc, target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upgrade OpenAPI spec to %s: %v", target, err)
+		}
+		content, err = openapi.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		klog.Infof("Upgraded OpenAPI spec %s to version %s", name, openapi.Version(doc))
+	}
+
+	// Remove the internal elements, before examples are generated for them
+	if strip := openAPISpec.Spec.StripInternal; strip != nil {
+		doc, err := openapi.Parse(content)
+		if err != nil {
+			return nil, err
+		}
+		if removed := openapi.StripInternal(doc, strip.Extension); removed > 0 {
+			if content, err = openapi.Marshal(doc); err != nil {
+				return nil, err
+			}
+			klog.Infof("Removed %d internal elements from %s", removed, name)
+		}
+	}
+
+	// Promote recorded traffic to examples, before fake ones fill the gaps
+	if recording := openAPISpec.Spec.Recording; recording != nil && recording.PromoteToExamples && len(recordings) > 0 {
+		doc, err := openapi.Parse(content)
+		if err != nil {
+			return nil, err
+		}
+		if promoted := promoteRecordings(doc, recordings); promoted > 0 {
+			if content, err = openapi.Marshal(doc); err != nil {
+				return nil, err
+			}
+			klog.Infof("Promoted %d recordings of %s to examples", promoted, name)
+		}
+	}
+
+	// Apply mocking if enabled
+	if openAPISpec.Spec.Mock {
+		klog.Infof("Mock is enabled for %s, generating fake examples", name)
+		mocked, err := mockers.MockOpenAPISpec(string(content), out.mockOptions)
+		if err != nil {
+			klog.Warningf("Failed to generate mock data: %v. Using original content.", err)
+			out.mockWarnings = append(out.mockWarnings, err.Error())
+		} else {
+			content = []byte(mocked.Content)
+			out.mockWarnings = append(out.mockWarnings, mocked.Warnings...)
+			out.invalidExamples = mocked.InvalidExamples
+			if len(mocked.InvalidExamples) > 0 {
+				klog.Warningf("%d generated examples of %s do not satisfy their schema", len(mocked.InvalidExamples), name)
+			}
+			klog.Info("Successfully generated mock examples")
+		}
+	}
+
+	out.content = content
+	out.document = generationDocument(name, content)
+	return out, nil
+}
+
+// generationDocument parses the published content for the generators, converting Swagger 2.0 documents
+func generationDocument(name string, content []byte) openapi.Document {
+	doc, err := openapi.Parse(content)
+	if err != nil {
+		klog.Warningf("Cannot parse OpenAPI spec %s for mock generation: %v", name, err)
+		return nil
+	}
+	if openapi.IsSwagger2(doc) {
+		if doc, err = converter.ConvertSwagger2(doc); err != nil {
+			klog.Warningf("Cannot convert OpenAPI spec %s for mock generation: %v", name, err)
+			return nil
+		}
+	}
+	return doc
+}
+
+// mockOptions translates the mock options of the CRD to the mocker options
+func mockOptions(openAPISpec *docsv1.OpenAPISpec) mockers.Options {
+	// Derive a stable seed so examples only change when the spec does
+	options := mockers.Options{
+		Seed: mockers.SeedFor(openAPISpec.Namespace, openAPISpec.Name),
+	}
+
+	opts := openAPISpec.Spec.MockOptions
+	if opts == nil {
+		return options
+	}
+	options.MaxDepth = opts.MaxDepth
+	options.MaxArrayLength = opts.MaxArrayLength
+	options.ExamplePolicy = mockers.ExamplePolicy(opts.ExamplePolicy)
+	options.Locale = opts.Locale
+	options.Consistent = opts.Consistent
+	if opts.Seed != nil {
+		options.Seed = *opts.Seed
+	}
+	return options
+}
@@ -0,0 +1,153 @@
+package redoc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"k8s.io/apimachinery/pkg/types"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+// Portal is a view of the server listing only some visibility levels, such as a public developer site.
+// It is served on a dedicated port, or on the main port for requests sent to its host name.
+type Portal struct {
+	// Port of a dedicated listener, 0 to serve the portal on the main port
+	Port int
+
+	// Host name matched on the main port, such as "developer.example.com"
+	Host string
+
+	// Visibility levels listed by the portal
+	Visibilities []string
+}
+
+// portalKey is the context key of the portal serving a request
+type portalKey struct{}
+
+// ParsePortal parses a portal written as "levels@target", where the levels are joined with "+"
+// and the target is a port or a host name, such as "public@:8090" or "public+internal@docs.example.com"
+func ParsePortal(value string) (Portal, error) {
+	levels, target, ok := strings.Cut(value, "@")
+	if !ok || levels == "" || target == "" {
+		return Portal{}, fmt.Errorf("invalid portal %q, expected levels@:port or levels@host", value)
+	}
+
+	var portal Portal
+	for _, level := range strings.Split(levels, "+") {
+		switch level {
+		case docsv1.VisibilityPublic, docsv1.VisibilityInternal, docsv1.VisibilityPrivate:
+			portal.Visibilities = append(portal.Visibilities, level)
+		default:
+			return Portal{}, fmt.Errorf("unknown visibility %q in portal %q", level, value)
+		}
+	}
+
+	if port, isPort := strings.CutPrefix(target, ":"); isPort {
+		number, err := strconv.Atoi(port)
+		if err != nil || number <= 0 || number > 65535 {
+			return Portal{}, fmt.Errorf("invalid port in portal %q", value)
+		}
+		portal.Port = number
+	} else {
+		portal.Host = strings.ToLower(target)
+	}
+	return portal, nil
+}
+
+// WithPortals serves portals listing only some visibility levels. Requests that match no portal
+// see every spec, so a public site should be given a dedicated port or host name.
+func WithPortals(portals ...Portal) ServerOption {
+	return func(s *Server) {
+		s.portals = append(s.portals, portals...)
+	}
+}
+
+// withPortal stores the portal serving a request in its context. Requests on the main port
+// are matched against the host names of the portals.
+func (s *Server) withPortal(portal *Portal, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		matched := portal
+		if matched == nil {
+			matched = s.portalForHost(r.Host)
+		}
+		if matched != nil {
+			r = r.WithContext(context.WithValue(r.Context(), portalKey{}, matched))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// portalForHost returns the portal served on the main port for a host name, nil when none matches
+func (s *Server) portalForHost(host string) *Portal {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	for i, portal := range s.portals {
+		if portal.Host != "" && strings.EqualFold(portal.Host, host) {
+			return &s.portals[i]
+		}
+	}
+	return nil
+}
+
+// portalFromContext returns the portal serving a request, nil when it shows every spec
+func portalFromContext(ctx context.Context) *Portal {
+	portal, _ := ctx.Value(portalKey{}).(*Portal)
+	return portal
+}
+
+// visible tells whether the portal serving a request lists a visibility level
+func visible(r *http.Request, visibility string) bool {
+	portal := portalFromContext(r.Context())
+	return portal == nil || slices.Contains(portal.Visibilities, visibility)
+}
+
+// restrictVisibility answers the requests for a spec that the portal does not list as if the spec did not exist
+func (s *Server) restrictVisibility(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, specInfo := s.requestedSpec(r); specInfo != nil && !visible(r, specInfo.Visibility) {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestedSpec returns the spec targeted by the namespace and name, or file, of a request.
+// The spec is nil for requests that do not target a known spec.
+func (s *Server) requestedSpec(r *http.Request) (types.NamespacedName, *SpecInfo) {
+	vars := mux.Vars(r)
+	key := types.NamespacedName{Namespace: vars["namespace"], Name: vars["name"]}
+	if file, isFile := vars["file"]; isFile {
+		key.Name = strings.TrimSuffix(file, ".json")
+	}
+	if key.Namespace == "" || key.Name == "" {
+		return key, nil
+	}
+
+	s.specsMutex.RLock()
+	defer s.specsMutex.RUnlock()
+	return key, s.specs[key]
+}
+
+// newPortalServers creates the listeners of the portals with a dedicated port
+func (s *Server) newPortalServers(handler http.Handler) []*http.Server {
+	var servers []*http.Server
+	for i, portal := range s.portals {
+		if portal.Port == 0 || portal.Port == s.port {
+			continue
+		}
+		servers = append(servers, &http.Server{
+			Addr:    fmt.Sprintf(":%d", portal.Port),
+			Handler: s.withPortal(&s.portals[i], handler),
+		})
+	}
+	return servers
+}
@@ -0,0 +1,38 @@
+package redoc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPortalVisibilityCollidingNames(t *testing.T) {
+	s := newTestServer(t, WithPortals(Portal{Host: "public.example.com", Visibilities: []string{"public"}}))
+	registerCollidingSpecs(t, s)
+
+	tests := []struct {
+		host       string
+		path       string
+		wantStatus int
+	}{
+		{"public.example.com", "/docs/a/b-c", http.StatusOK},
+		{"public.example.com", "/specs/a/b-c.json", http.StatusOK},
+		{"public.example.com", "/mock/a/b-c/items", http.StatusOK},
+		{"public.example.com", "/docs/a-b/c", http.StatusNotFound},
+		{"public.example.com", "/specs/a-b/c.json", http.StatusNotFound},
+		{"public.example.com", "/mock/a-b/c/items", http.StatusNotFound},
+		{"internal.example.com", "/docs/a-b/c", http.StatusOK},
+		{"internal.example.com", "/docs/a/b-c", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			r.Host = tt.host
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,416 @@
+package redoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// Bounds of the recording proxy
+const (
+	defaultRecordingsPerOperation = 10
+	maxRecordedBodySize           = 1 << 20
+)
+
+// unrecordedHeaders are the response headers that are not replayed
+var unrecordedHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Encoding":  true,
+	"Content-Length":    true,
+	"Date":              true,
+	"Keep-Alive":        true,
+	"Set-Cookie":        true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+}
+
+// Recording is a request/response pair captured by the recording proxy
+type Recording struct {
+	Time    time.Time `json:"time"`
+	Method  string    `json:"method"`
+	PathKey string    `json:"pathKey"`
+	// RequestURI is the path and query of the request, relative to the API
+	RequestURI         string `json:"requestURI"`
+	RequestContentType string `json:"requestContentType,omitempty"`
+	RequestBody        []byte `json:"requestBody,omitempty"`
+
+	StatusCode      int               `json:"statusCode"`
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+	ResponseBody    []byte            `json:"responseBody,omitempty"`
+}
+
+// recordingStore keeps the latest recordings of each operation of a spec,
+// in a JSON file when a recording directory is configured
+type recordingStore struct {
+	mutex      sync.Mutex
+	file       string
+	operations map[string][]Recording
+}
+
+// operationKey identifies an operation in the recording store
+func operationKey(method, pathKey string) string {
+	return strings.ToUpper(method) + " " + pathKey
+}
+
+// WithRecordingDirectory persists the recordings in a directory, so they survive restarts.
+// The directory must not be the spec directory, which is served publicly.
+func WithRecordingDirectory(dir string) ServerOption {
+	return func(s *Server) {
+		s.recordingDirectory = dir
+	}
+}
+
+// recordingStore returns the recordings of a spec, loading them on first registration
+func (s *Server) recordingStore(key types.NamespacedName) *recordingStore {
+	s.specsMutex.RLock()
+	previous, ok := s.specs[key]
+	s.specsMutex.RUnlock()
+	if ok {
+		return previous.recordings
+	}
+
+	name := key.String()
+	store := &recordingStore{operations: make(map[string][]Recording)}
+	if s.recordingDirectory == "" {
+		return store
+	}
+	store.file = filepath.Join(s.recordingDirectory, key.Namespace, key.Name+".json")
+	content, err := os.ReadFile(store.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.Warningf("Failed to read recordings of %s: %v", name, err)
+		}
+		return store
+	}
+	if err := json.Unmarshal(content, &store.operations); err != nil {
+		klog.Warningf("Failed to decode recordings of %s: %v", name, err)
+	}
+	return store
+}
+
+// add stores a recording, dropping the oldest ones of the operation beyond the limit
+func (r *recordingStore) add(recording Recording, limit int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := operationKey(recording.Method, recording.PathKey)
+	recordings := append(r.operations[key], recording)
+	if len(recordings) > limit {
+		recordings = recordings[len(recordings)-limit:]
+	}
+	r.operations[key] = recordings
+	r.save()
+}
+
+// clear drops all recordings
+func (r *recordingStore) clear() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.operations = make(map[string][]Recording)
+	r.save()
+}
+
+// save writes the recordings to their file, the caller holds the lock
+func (r *recordingStore) save() {
+	if r.file == "" {
+		return
+	}
+	content, err := json.Marshal(r.operations)
+	if err == nil {
+		// Recordings are stored in a directory per namespace
+		err = os.MkdirAll(filepath.Dir(r.file), 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(r.file, content, 0644)
+	}
+	if err != nil {
+		klog.Warningf("Failed to save recordings to %s: %v", r.file, err)
+	}
+}
+
+// list returns the recordings ordered by operation then time
+func (r *recordingStore) list() []Recording {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	keys := make([]string, 0, len(r.operations))
+	for key := range r.operations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out []Recording
+	for _, key := range keys {
+		out = append(out, r.operations[key]...)
+	}
+	return out
+}
+
+// match returns the latest recording of an operation, preferring one for the same path and query
+func (r *recordingStore) match(method, pathKey, requestURI string) (Recording, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	recordings := r.operations[operationKey(method, pathKey)]
+	for i := len(recordings) - 1; i >= 0; i-- {
+		if recordings[i].RequestURI == requestURI {
+			return recordings[i], true
+		}
+	}
+	if len(recordings) == 0 {
+		return Recording{}, false
+	}
+	return recordings[len(recordings)-1], true
+}
+
+// serviceURL returns the base URL of a Service in a namespace
+func serviceURL(namespace string, target docsv1.ServiceTarget) *url.URL {
+	scheme := target.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return &url.URL{
+		Scheme: scheme,
+		Host:   fmt.Sprintf("%s.%s.svc:%d", target.ServiceName, namespace, target.Port),
+	}
+}
+
+// handleRecord forwards the requests of the operations declared in the spec under /record/{namespace}/{name}/
+// to the recorded Service and stores the request/response pairs
+func (s *Server) handleRecord(w http.ResponseWriter, r *http.Request) {
+	key, specInfo := s.requestedSpec(r)
+	name, ok := key.String(), specInfo != nil
+
+	if !ok || specInfo.recording == nil || specInfo.OpenAPI == nil {
+		writeJSONError(w, http.StatusNotFound, "recording is not enabled for this API")
+		return
+	}
+
+	// Only the operations declared in the spec are forwarded, like the try it out proxy
+	requestPath := strings.TrimPrefix(r.URL.Path, "/record/"+name)
+	pathKey, _, ok := openapi.MatchPath(specInfo.OpenAPI, requestPath)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("no operation matches %s", requestPath))
+		return
+	}
+	if operationOf(specInfo.OpenAPI, pathKey, r.Method) == nil {
+		writeJSONError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s %s is not declared in the spec", r.Method, pathKey))
+		return
+	}
+
+	// Buffer the request body so it can be both forwarded and recorded
+	requestBody, err := io.ReadAll(io.LimitReader(r.Body, maxRecordedBodySize+1))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("cannot read request body: %v", err))
+		return
+	}
+	recordable := len(requestBody) <= maxRecordedBodySize
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(requestBody), r.Body))
+
+	requestURI := requestPath
+	if r.URL.RawQuery != "" {
+		requestURI += "?" + r.URL.RawQuery
+	}
+
+	target := specInfo.recordingTarget
+	proxy := &httputil.ReverseProxy{
+		Transport: s.httpClient.Transport,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.Out.URL.Path = strings.TrimSuffix(target.Path, "/") + requestPath
+			pr.Out.URL.RawPath = ""
+			// Recorded bodies are stored uncompressed
+			pr.Out.Header.Del("Accept-Encoding")
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if !recordable {
+				return nil
+			}
+			responseBody, err := io.ReadAll(io.LimitReader(resp.Body, maxRecordedBodySize+1))
+			if err != nil {
+				return err
+			}
+			resp.Body = readCloser{io.MultiReader(bytes.NewReader(responseBody), resp.Body), resp.Body}
+			if len(responseBody) > maxRecordedBodySize {
+				return nil
+			}
+
+			recording := Recording{
+				Time:               time.Now(),
+				Method:             r.Method,
+				PathKey:            pathKey,
+				RequestURI:         requestURI,
+				RequestContentType: r.Header.Get("Content-Type"),
+				RequestBody:        requestBody,
+				StatusCode:         resp.StatusCode,
+				ResponseHeaders:    make(map[string]string),
+				ResponseBody:       responseBody,
+			}
+			for header := range resp.Header {
+				if !unrecordedHeaders[header] {
+					recording.ResponseHeaders[header] = resp.Header.Get(header)
+				}
+			}
+			limit := specInfo.recording.MaxPerOperation
+			if limit == 0 {
+				limit = defaultRecordingsPerOperation
+			}
+			specInfo.recordings.add(recording, limit)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			klog.Warningf("Failed to forward %s %s for %s: %v", r.Method, requestPath, name, err)
+			writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("cannot reach %s: %v", target.Host, err))
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// readCloser reads a buffered body while closing the original one
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// writeRecording replays a recorded response
+func writeRecording(w http.ResponseWriter, recording Recording) {
+	for header, value := range recording.ResponseHeaders {
+		w.Header().Set(header, value)
+	}
+	w.Header().Set("X-Redokube-Replay", recording.Time.Format(time.RFC3339))
+	w.WriteHeader(recording.StatusCode)
+	w.Write(recording.ResponseBody)
+}
+
+// operationOf returns the operation of a path template for an HTTP method
+func operationOf(doc openapi.Document, pathKey, method string) map[string]interface{} {
+	paths, _ := doc["paths"].(map[string]interface{})
+	pathItem, _ := paths[pathKey].(map[string]interface{})
+	operation, _ := pathItem[strings.ToLower(method)].(map[string]interface{})
+	return operation
+}
+
+// handleRecordings lists the recordings of a spec, or drops them on DELETE. Dropping them requires
+// the delete verb when an authorizer is set, and an authenticated user otherwise.
+func (s *Server) handleRecordings(w http.ResponseWriter, r *http.Request) {
+	key, specInfo := s.requestedSpec(r)
+	name, ok := key.String(), specInfo != nil
+
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("API %s not found", name))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if s.authorizer == nil && UserFromContext(r.Context()) == nil {
+			writeJSONError(w, http.StatusForbidden, "deleting recordings requires authentication")
+			return
+		}
+		specInfo.recordings.clear()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	recordings := specInfo.recordings.list()
+	if recordings == nil {
+		recordings = []Recording{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recordings)
+}
+
+// promoteRecordings adds recorded bodies to the examples of the matching request bodies and
+// responses, such as "recorded-1". Media types with a single example written by the author
+// are left untouched. It returns the number of examples added.
+func promoteRecordings(doc openapi.Document, recordings []Recording) int {
+	if openapi.IsSwagger2(doc) {
+		klog.Warning("Recordings can only be promoted to examples of OpenAPI 3 documents")
+		return 0
+	}
+
+	promoted := 0
+	for _, recording := range recordings {
+		operation := operationOf(doc, recording.PathKey, recording.Method)
+		if operation == nil {
+			continue
+		}
+		summary := fmt.Sprintf("Recorded %s %s", recording.Method, recording.RequestURI)
+
+		if len(recording.RequestBody) > 0 {
+			requestBody, _ := operation["requestBody"].(map[string]interface{})
+			if addRecordedExample(requestBody, recording.RequestContentType, recording.RequestBody, summary) {
+				promoted++
+			}
+		}
+
+		responses, _ := operation["responses"].(map[string]interface{})
+		response, _ := responses[strconv.Itoa(recording.StatusCode)].(map[string]interface{})
+		if len(recording.ResponseBody) > 0 && addRecordedExample(response, recording.ResponseHeaders["Content-Type"], recording.ResponseBody, summary) {
+			promoted++
+		}
+	}
+	return promoted
+}
+
+// addRecordedExample adds a recorded body to the examples of the media type it was sent as
+func addRecordedExample(object map[string]interface{}, contentType string, body []byte, summary string) bool {
+	content, _ := object["content"].(map[string]interface{})
+	mediaTypeName, _, err := mime.ParseMediaType(contentType)
+	if err != nil || content == nil {
+		return false
+	}
+
+	var mediaType map[string]interface{}
+	for key, value := range content {
+		if strings.EqualFold(key, mediaTypeName) {
+			mediaType, _ = value.(map[string]interface{})
+		}
+	}
+	if mediaType == nil {
+		return false
+	}
+	if _, ok := mediaType["example"]; ok {
+		return false
+	}
+
+	var value interface{} = string(body)
+	if mediaTypeName == "application/json" || strings.HasSuffix(mediaTypeName, "+json") {
+		if err := json.Unmarshal(body, &value); err != nil {
+			return false
+		}
+	}
+
+	examples, ok := mediaType["examples"].(map[string]interface{})
+	if !ok {
+		examples = make(map[string]interface{})
+		mediaType["examples"] = examples
+	}
+	name := ""
+	for i := 1; name == "" || examples[name] != nil; i++ {
+		name = fmt.Sprintf("recorded-%d", i)
+	}
+	examples[name] = map[string]interface{}{
+		"summary": summary,
+		"value":   value,
+	}
+	return true
+}
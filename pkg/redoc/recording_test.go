@@ -0,0 +1,251 @@
+package redoc
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+func TestRecordingsSurviveRestarts(t *testing.T) {
+	recordingDirectory := t.TempDir()
+	service := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`"recorded"`)),
+			Request:    r,
+		}, nil
+	})
+	register := func(s *Server) {
+		registerTestSpec(t, s, "ns", "pets", collisionSpec, func(spec *docsv1.OpenAPISpec) {
+			spec.Spec.Mock = true
+			spec.Spec.Recording = &docsv1.RecordingOptions{
+				Target: docsv1.ServiceTarget{ServiceName: "pets", Port: 8080},
+				Replay: true,
+			}
+		})
+	}
+
+	s := newTestServer(t, WithRecordingDirectory(recordingDirectory))
+	s.httpClient = &http.Client{Transport: service}
+	register(s)
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/record/ns/pets/items?limit=1", nil))
+	if w.Code != http.StatusOK || w.Body.String() != `"recorded"` {
+		t.Fatalf("record status = %d: %s", w.Code, w.Body)
+	}
+	if _, err := os.Stat(filepath.Join(recordingDirectory, "ns", "pets.json")); err != nil {
+		t.Fatalf("recordings not saved: %v", err)
+	}
+
+	// A new server loads the recordings from the directory and replays them
+	restarted := newTestServer(t, WithRecordingDirectory(recordingDirectory))
+	register(restarted)
+	w = httptest.NewRecorder()
+	restarted.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/specs/ns/pets/recordings", nil))
+	var recordings []Recording
+	if err := json.NewDecoder(w.Body).Decode(&recordings); err != nil {
+		t.Fatal(err)
+	}
+	if len(recordings) != 1 || recordings[0].PathKey != "/items" || recordings[0].RequestURI != "/items?limit=1" {
+		t.Fatalf("recordings = %+v", recordings)
+	}
+
+	w = httptest.NewRecorder()
+	restarted.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/mock/ns/pets/items?limit=1", nil))
+	if w.Body.String() != `"recorded"` || w.Header().Get("X-Redokube-Replay") == "" {
+		t.Errorf("replayed response = %s, headers %v", w.Body, w.Header())
+	}
+}
+
+func TestReadOnlyUserCannotDeleteRecordings(t *testing.T) {
+	s := newTestServer(t, WithAuthorizer(namespaceAuthorizer{"ns": true}))
+	registerTestSpec(t, s, "ns", "pets", collisionSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.Recording = &docsv1.RecordingOptions{Target: docsv1.ServiceTarget{ServiceName: "pets", Port: 8080}}
+	})
+	specInfo := s.specs[types.NamespacedName{Namespace: "ns", Name: "pets"}]
+	specInfo.recordings.add(Recording{Method: http.MethodGet, PathKey: "/items", RequestURI: "/items", StatusCode: http.StatusOK}, 10)
+
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/specs/ns/pets/recordings", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("delete status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if recordings := specInfo.recordings.list(); len(recordings) != 1 {
+		t.Errorf("recordings = %+v, want them kept", recordings)
+	}
+
+	w = httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/specs/ns/pets/recordings", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("list status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestRecordingMatch(t *testing.T) {
+	store := &recordingStore{operations: make(map[string][]Recording)}
+	for _, uri := range []string{"/pets/1", "/pets/2", "/pets/1?verbose=true"} {
+		store.add(Recording{Method: http.MethodGet, PathKey: "/pets/{id}", RequestURI: uri, ResponseBody: []byte(uri)}, 10)
+	}
+
+	tests := []struct {
+		method     string
+		requestURI string
+		want       string
+		wantOK     bool
+	}{
+		{http.MethodGet, "/pets/2", "/pets/2", true},
+		{http.MethodGet, "/pets/1", "/pets/1", true},
+		// Unknown paths and queries replay the latest recording of the operation
+		{http.MethodGet, "/pets/3", "/pets/1?verbose=true", true},
+		{http.MethodDelete, "/pets/1", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.requestURI, func(t *testing.T) {
+			recording, ok := store.match(tt.method, "/pets/{id}", tt.requestURI)
+			if ok != tt.wantOK || string(recording.ResponseBody) != tt.want {
+				t.Errorf("match = %q, %v, want %q, %v", recording.ResponseBody, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRecordingsBeyondLimitDropOldest(t *testing.T) {
+	store := &recordingStore{operations: make(map[string][]Recording)}
+	for _, uri := range []string{"/items?page=1", "/items?page=2", "/items?page=3"} {
+		store.add(Recording{Method: http.MethodGet, PathKey: "/items", RequestURI: uri}, 2)
+	}
+	recordings := store.list()
+	if len(recordings) != 2 || recordings[0].RequestURI != "/items?page=2" {
+		t.Errorf("recordings = %+v, want the 2 latest", recordings)
+	}
+}
+
+func TestPromoteRecordings(t *testing.T) {
+	doc := openapi.Document{
+		"openapi": "3.0.3",
+		"paths": map[string]interface{}{
+			"/items": map[string]interface{}{
+				"post": map[string]interface{}{
+					"requestBody": map[string]interface{}{
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{"example": map[string]interface{}{"name": "author"}},
+						},
+					},
+					"responses": map[string]interface{}{
+						"201": map[string]interface{}{
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	recordings := []Recording{{
+		Method:             http.MethodPost,
+		PathKey:            "/items",
+		RequestURI:         "/items",
+		RequestContentType: "application/json",
+		RequestBody:        []byte(`{"name":"recorded"}`),
+		StatusCode:         http.StatusCreated,
+		ResponseHeaders:    map[string]string{"Content-Type": "application/json; charset=utf-8"},
+		ResponseBody:       []byte(`{"id":1}`),
+	}}
+
+	if promoted := promoteRecordings(doc, recordings); promoted != 1 {
+		t.Errorf("promoted = %d, want 1", promoted)
+	}
+	operation := operationOf(doc, "/items", http.MethodPost)
+	request := operation["requestBody"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	if _, ok := request["examples"]; ok || request["example"].(map[string]interface{})["name"] != "author" {
+		t.Errorf("request media type = %v, want the example of the author untouched", request)
+	}
+	response := operation["responses"].(map[string]interface{})["201"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})
+	example, _ := response["examples"].(map[string]interface{})["recorded-1"].(map[string]interface{})
+	if value, _ := example["value"].(map[string]interface{}); value["id"] != float64(1) {
+		t.Errorf("response examples = %v, want the recorded body as recorded-1", response["examples"])
+	}
+}
+
+func TestRecordForwardsDeclaredOperationsOnly(t *testing.T) {
+	s := newTestServer(t)
+	var forwarded []string
+	s.httpClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		forwarded = append(forwarded, r.Method+" "+r.URL.Path)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`"ok"`)), Request: r}, nil
+	})}
+	registerTestSpec(t, s, "ns", "pets", collisionSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.Recording = &docsv1.RecordingOptions{Target: docsv1.ServiceTarget{ServiceName: "pets", Port: 8080}}
+	})
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{http.MethodGet, "/record/ns/pets/items", http.StatusOK},
+		{http.MethodGet, "/record/ns/pets/admin/users", http.StatusNotFound},
+		{http.MethodPost, "/record/ns/pets/items", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+	if len(forwarded) != 1 || forwarded[0] != "GET /items" {
+		t.Errorf("forwarded = %v, want only GET /items", forwarded)
+	}
+}
+
+func TestDeleteRecordingsRequiresAuthentication(t *testing.T) {
+	users, err := os.CreateTemp(t.TempDir(), "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users.WriteString("alice:secret\n")
+	users.Close()
+	basic, err := LoadBasicAuthenticator(users.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authenticator Authenticator
+		wantStatus    int
+	}{
+		{"anonymous", nil, http.StatusForbidden},
+		{"authenticated", basic, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, WithAuthenticator(tt.authenticator))
+			registerTestSpec(t, s, "ns", "pets", collisionSpec, func(spec *docsv1.OpenAPISpec) {
+				spec.Spec.Recording = &docsv1.RecordingOptions{Target: docsv1.ServiceTarget{ServiceName: "pets", Port: 8080}}
+			})
+			r := httptest.NewRequest(http.MethodDelete, "/api/v1/specs/ns/pets/recordings", nil)
+			r.SetBasicAuth("alice", "secret")
+			w := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
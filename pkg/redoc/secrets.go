@@ -0,0 +1,69 @@
+package redoc
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WithSecretReader sets the client used to read the Secrets and ConfigMaps referenced by the OpenAPISpecs
+func WithSecretReader(reader client.Reader) ServerOption {
+	return func(s *Server) {
+		s.secretReader = reader
+	}
+}
+
+// secretValue reads a key of a Secret in the namespace of a resource.
+// It returns nil when an optional Secret or key is missing.
+func (s *Server) secretValue(namespace string, ref *corev1.SecretKeySelector) ([]byte, error) {
+	optional := ref.Optional != nil && *ref.Optional
+	if s.secretReader == nil {
+		return nil, fmt.Errorf("cannot read secret %s: no Kubernetes client configured", ref.Name)
+	}
+
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: namespace, Name: ref.Name}
+	if err := s.secretReader.Get(context.Background(), key, secret); err != nil {
+		if apierrors.IsNotFound(err) && optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read secret %s: %v", ref.Name, err)
+	}
+
+	value, ok := secret.Data[ref.Key]
+	if !ok && !optional {
+		return nil, fmt.Errorf("secret %s has no key %s", ref.Name, ref.Key)
+	}
+	return value, nil
+}
+
+// configMapValue reads a key of a ConfigMap in the namespace of a resource.
+// It returns nil when an optional ConfigMap or key is missing.
+func (s *Server) configMapValue(namespace string, ref *corev1.ConfigMapKeySelector) ([]byte, error) {
+	optional := ref.Optional != nil && *ref.Optional
+	if s.secretReader == nil {
+		return nil, fmt.Errorf("cannot read configmap %s: no Kubernetes client configured", ref.Name)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: namespace, Name: ref.Name}
+	if err := s.secretReader.Get(context.Background(), key, configMap); err != nil {
+		if apierrors.IsNotFound(err) && optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read configmap %s: %v", ref.Name, err)
+	}
+
+	if value, ok := configMap.Data[ref.Key]; ok {
+		return []byte(value), nil
+	}
+	value, ok := configMap.BinaryData[ref.Key]
+	if !ok && !optional {
+		return nil, fmt.Errorf("configmap %s has no key %s", ref.Name, ref.Key)
+	}
+	return value, nil
+}
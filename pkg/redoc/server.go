@@ -3,21 +3,28 @@ package redoc
 import (
 	"fmt"
 	"html/template"
-	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-openapi/loads"
 	"github.com/gorilla/mux"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	docsv1 "github.com/BombartSimon/redokube/api/v1"
 	"github.com/BombartSimon/redokube/pkg/mockers"
+	"github.com/BombartSimon/redokube/pkg/openapi"
 )
 
+// outboundTimeout bounds the requests sent by the server to other services
+const outboundTimeout = 10 * time.Second
+
 const (
 	defaultPort = 8080
 	redocHTML   = `<!DOCTYPE html>
@@ -27,15 +34,23 @@ const (
     <meta charset="utf-8"/>
     <meta name="viewport" content="width=device-width, initial-scale=1">
     <link href="https://fonts.googleapis.com/css?family=Montserrat:300,400,700|Roboto:300,400,700" rel="stylesheet">
+    {{ with .Branding.FaviconURL }}<link rel="icon" href="{{ . }}">{{ end }}
     <style>
       body {
         margin: 0;
         padding: 0;
       }
+      .redokube-header { display: flex; align-items: center; gap: 16px; padding: 8px 16px; font-family: Roboto, sans-serif; }
+      .redokube-header img { max-height: 40px; }
+      .redokube-footer { padding: 8px 16px; font-family: Roboto, sans-serif; }
     </style>
+    {{ with .Branding.CSS }}<style>{{ . }}</style>{{ end }}
   </head>
   <body>
+    {{ if or .Branding.LogoURL .Branding.Header }}<header class="redokube-header">{{ with .Branding.LogoURL }}<img src="{{ . }}" alt="logo">{{ end }}{{ .Branding.Header }}</header>{{ end }}
+    {{ if .Health }}<div style="padding: 8px 16px; font-family: Roboto, sans-serif; font-size: 14px; border-bottom: 1px solid #eee">{{ .Health }}</div>{{ end }}
     <redoc spec-url="{{ .SpecURL }}"></redoc>
+    {{ with .Branding.Footer }}<footer class="redokube-footer">{{ . }}</footer>{{ end }}
     <script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
   </body>
 </html>`
@@ -45,11 +60,36 @@ const (
 type Server struct {
 	router        *mux.Router
 	server        *http.Server
-	specs         map[string]*SpecInfo
+	specs         map[types.NamespacedName]*SpecInfo
 	specsMutex    sync.RWMutex
 	port          int
 	externalURL   string
 	specDirectory string
+
+	// Reads the Secrets referenced by the specs, such as callback signing keys
+	secretReader client.Reader
+	// Sends the contract test requests and health checks
+	httpClient *http.Client
+	// Subscribers allowed to receive callbacks and webhooks, any public address when nil
+	callbackAllowlist *CallbackAllowlist
+	// Sends the callbacks and webhooks to the allowed subscribers only
+	callbackClient *http.Client
+	// Bounds the deliveries in progress
+	deliverySlots chan struct{}
+	// Directory where recordings are persisted, kept in memory only when empty
+	recordingDirectory string
+	// Grouping of the index page and path of a custom index template
+	indexGroupBy  string
+	indexTemplate string
+	// Cluster-wide branding of the pages, nil when unset
+	branding *BrandingConfig
+	// Identifies the callers, nil when the server is open to anyone
+	authenticator Authenticator
+	// Restricts the specs to the users allowed to read their namespace, nil when everyone may read them
+	authorizer Authorizer
+	// Views of the server listing only some visibility levels, and the listeners of those with a dedicated port
+	portals       []Portal
+	portalServers []*http.Server
 }
 
 // SpecInfo holds information about a registered OpenAPI spec
@@ -58,37 +98,101 @@ type SpecInfo struct {
 	SpecPath string
 	SpecURL  string
 	Document *loads.Document
+
+	// Catalog information shown on the index page
+	Namespace   string
+	Visibility  string
+	Description string
+	Version     string
+	Category    string
+	Tags        []string
+	LastUpdated time.Time
+
+	// Published document as OpenAPI 3, used to generate live mock responses and fixtures
+	OpenAPI     openapi.Document
+	Mock        bool
+	MockOptions mockers.Options
+
+	// Callback delivery settings, nil when callbacks are disabled
+	callbacks  *callbackOptions
+	deliveries *deliveryLog
+
+	// Recording proxy settings, nil when recording is disabled
+	recording       *docsv1.RecordingOptions
+	recordingTarget *url.URL
+	recordings      *recordingStore
+
+	// Try it out proxy settings, nil when the proxy is disabled
+	tryItOut *tryItOutSettings
+
+	// Latest contract test report, nil until a test ran
+	contractTest *docsv1.ContractTestStatus
+
+	// Background health checks, nil when disabled
+	health *healthMonitor
+
+	// Branding overrides of the spec, nil when unset
+	branding *docsv1.Branding
 }
 
 // NewServer creates a new documentation server
 func NewServer(options ...ServerOption) *Server {
 	s := &Server{
 		router:        mux.NewRouter(),
-		specs:         make(map[string]*SpecInfo),
+		specs:         make(map[types.NamespacedName]*SpecInfo),
 		port:          defaultPort,
 		specDirectory: "/tmp/redokube-specs", // Default directory to store specs
+		httpClient:    &http.Client{Timeout: outboundTimeout},
+		indexGroupBy:  GroupByNamespace,
 	}
 
 	// Apply options
 	for _, opt := range options {
 		opt(s)
 	}
+	s.callbackClient = s.callbackAllowlist.client()
+	s.deliverySlots = make(chan struct{}, maxConcurrentDeliveries)
 
 	// Ensure spec directory exists
 	if err := os.MkdirAll(s.specDirectory, 0755); err != nil {
 		klog.Fatalf("Failed to create spec directory: %v", err)
 	}
+	if s.recordingDirectory != "" {
+		if err := os.MkdirAll(s.recordingDirectory, 0755); err != nil {
+			klog.Fatalf("Failed to create recording directory: %v", err)
+		}
+	}
 
 	// Setup routes
-	s.router.PathPrefix("/specs/").Handler(http.StripPrefix("/specs/", http.FileServer(http.Dir(s.specDirectory))))
-	s.router.HandleFunc("/docs/{name}", s.handleDoc)
+	s.router.Use(s.restrictVisibility, s.authorize)
+	// Specs are addressed by namespace and name, as both may contain dashes
+	s.router.PathPrefix("/specs/{namespace}/{file}").Handler(http.StripPrefix("/specs/", http.FileServer(http.Dir(s.specDirectory))))
+	s.router.HandleFunc("/docs/{namespace}/{name}", s.handleDoc)
+	s.router.HandleFunc("/docs/{namespace}/{name}/deliveries", s.handleDeliveriesPage)
+	s.router.HandleFunc("/docs/{namespace}/{name}/contract", s.handleContractPage)
+	s.router.PathPrefix("/mock/{namespace}/{name}/").HandlerFunc(s.handleMock)
+	s.router.PathPrefix("/record/{namespace}/{name}/").HandlerFunc(s.handleRecord)
+	s.router.PathPrefix("/proxy/{namespace}/{name}/").HandlerFunc(s.handleProxy)
+	s.router.HandleFunc("/api/v1/specs/{namespace}/{name}/fixtures", s.handleFixtures).Methods(http.MethodGet)
+	s.router.HandleFunc("/api/v1/specs/{namespace}/{name}/deliveries", s.handleDeliveries).Methods(http.MethodGet)
+	s.router.HandleFunc("/api/v1/specs/{namespace}/{name}/webhooks/{webhook}", s.handleWebhook).Methods(http.MethodPost)
+	s.router.HandleFunc("/api/v1/specs/{namespace}/{name}/recordings", s.handleRecordings).Methods(http.MethodGet, http.MethodDelete)
+	s.router.HandleFunc("/api/v1/specs/{namespace}/{name}/contract", s.handleContractReport).Methods(http.MethodGet)
+	s.router.HandleFunc("/api/v1/specs/{namespace}/{name}/health", s.handleHealth).Methods(http.MethodGet)
+	s.router.HandleFunc("/api/v1/specs", s.handleCatalog).Methods(http.MethodGet)
+	s.router.HandleFunc("/branding/{asset}", s.handleBrandingAsset).Methods(http.MethodGet)
+	if handler, ok := s.authenticator.(http.Handler); ok {
+		s.router.PathPrefix("/auth/").Handler(handler)
+	}
 	s.router.HandleFunc("/", s.handleIndex)
 
 	// Setup server
+	handler := s.authenticate(s.redirectLegacy(s.router))
 	s.server = &http.Server{
 		Addr:    fmt.Sprintf(":%d", s.port),
-		Handler: s.router,
+		Handler: s.withPortal(nil, handler),
 	}
+	s.portalServers = s.newPortalServers(handler)
 
 	return s
 }
@@ -117,145 +221,99 @@ func WithSpecDirectory(dir string) ServerOption {
 	}
 }
 
-// Start starts the documentation server
+// Start starts the documentation server and the listeners of the portals, until one of them fails
 func (s *Server) Start() error {
+	errs := make(chan error, 1+len(s.portalServers))
+	for _, server := range s.portalServers {
+		go func(server *http.Server) {
+			klog.Infof("Starting Redokube portal on %s", server.Addr)
+			errs <- server.ListenAndServe()
+		}(server)
+	}
+
 	klog.Infof("Starting Redokube documentation server on port %d", s.port)
-	return s.server.ListenAndServe()
+	go func() {
+		errs <- s.server.ListenAndServe()
+	}()
+	return <-errs
 }
 
 // Stop stops the documentation server
 func (s *Server) Stop() error {
 	klog.Info("Stopping Redokube documentation server")
+	for _, server := range s.portalServers {
+		if err := server.Close(); err != nil {
+			klog.Errorf("Failed to stop the portal on %s: %v", server.Addr, err)
+		}
+	}
 	return s.server.Close()
 }
 
-// RegisterSpec registers an OpenAPI spec from a CRD
-func (s *Server) RegisterSpec(openAPISpec *docsv1.OpenAPISpec) (string, error) {
-	s.specsMutex.Lock()
-	defer s.specsMutex.Unlock()
+// Registration describes the outcome of registering an OpenAPI spec
+type Registration struct {
+	// URL of the rendered documentation
+	URL string
 
-	name := fmt.Sprintf("%s-%s", openAPISpec.Namespace, openAPISpec.Name)
-	specPath := openAPISpec.Spec.SpecPath
-	specContent := openAPISpec.Spec.SpecContent
-	isMockEnabled := openAPISpec.Spec.Mock
-
-	// Create spec filename
-	specFilename := fmt.Sprintf("%s.json", name)
-	specFilePath := filepath.Join(s.specDirectory, specFilename)
-
-	// Check if we have direct content or need to fetch from path
-	if specContent != "" {
-		klog.Infof("Using direct OpenAPI spec content for %s", name)
-
-		// Apply mocking if enabled
-		if isMockEnabled {
-			klog.Infof("Mock is enabled for %s, generating fake examples", name)
-			mockedContent, err := mockers.MockOpenAPISpec(specContent)
-			if err != nil {
-				klog.Warningf("Failed to generate mock data: %v. Using original content.", err)
-			} else {
-				specContent = mockedContent
-				klog.Info("Successfully generated mock examples")
-			}
-		}
+	// Problems met while generating fake examples
+	MockWarnings []string
 
-		// Create the spec file
-		specFile, err := os.Create(specFilePath)
-		if err != nil {
-			return "", fmt.Errorf("failed to create spec file: %v", err)
-		}
-		defer specFile.Close()
+	// Generated examples that do not satisfy their schema
+	InvalidExamples []string
 
-		// Write the content directly to file
-		_, err = specFile.WriteString(specContent)
-		if err != nil {
-			return "", fmt.Errorf("failed to write spec content to file: %v", err)
-		}
-	} else if specPath != "" {
-		// If it's a URL, download directly to maintain the exact format
-		if strings.HasPrefix(specPath, "http://") || strings.HasPrefix(specPath, "https://") {
-			klog.Infof("Downloading OpenAPI spec from URL: %s", specPath)
-			resp, err := http.Get(specPath)
-			if err != nil {
-				return "", fmt.Errorf("failed to download OpenAPI spec from URL %s: %v", specPath, err)
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode != http.StatusOK {
-				return "", fmt.Errorf("failed to download OpenAPI spec from URL %s: status code %d", specPath, resp.StatusCode)
-			}
-
-			// Read the content for potential mocking
-			content, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return "", fmt.Errorf("failed to read spec content: %v", err)
-			}
-
-			// Apply mocking if enabled
-			if isMockEnabled {
-				klog.Infof("Mock is enabled for %s, generating fake examples", name)
-				mockedContent, err := mockers.MockOpenAPISpec(string(content))
-				if err != nil {
-					klog.Warningf("Failed to generate mock data: %v. Using original content.", err)
-				} else {
-					content = []byte(mockedContent)
-					klog.Info("Successfully generated mock examples")
-				}
-			}
+	// Overlays applied to the fetched spec, in order
+	Overlays []docsv1.AppliedOverlay
+}
 
-			// Create the spec file
-			specFile, err := os.Create(specFilePath)
-			if err != nil {
-				return "", fmt.Errorf("failed to create spec file: %v", err)
-			}
-			defer specFile.Close()
+// RegisterSpec registers an OpenAPI spec from a CRD
+func (s *Server) RegisterSpec(openAPISpec *docsv1.OpenAPISpec) (*Registration, error) {
+	key := types.NamespacedName{Namespace: openAPISpec.Namespace, Name: openAPISpec.Name}
+	name := key.String()
+	specPath := openAPISpec.Spec.SpecPath
 
-			// Write the content to file
-			_, err = specFile.Write(content)
-			if err != nil {
-				return "", fmt.Errorf("failed to write spec to file: %v", err)
-			}
-		} else {
-			// For local files, read content for potential mocking
-			sourceFile, err := os.Open(specPath)
-			if err != nil {
-				return "", fmt.Errorf("failed to open OpenAPI spec file %s: %v", specPath, err)
-			}
+	// Create spec filename, in a directory per namespace
+	specFilename := fmt.Sprintf("%s/%s.json", openAPISpec.Namespace, openAPISpec.Name)
+	specFilePath := filepath.Join(s.specDirectory, openAPISpec.Namespace, openAPISpec.Name+".json")
+	if err := os.MkdirAll(filepath.Dir(specFilePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spec directory: %v", err)
+	}
 
-			content, err := io.ReadAll(sourceFile)
-			sourceFile.Close() // Close after reading
+	// Fetching and transforming the spec may take a while, the lock is only held to swap the result
+	content, err := s.fetchSpec(name, openAPISpec)
+	if err != nil {
+		return nil, err
+	}
 
-			if err != nil {
-				return "", fmt.Errorf("failed to read spec content: %v", err)
-			}
+	content, overlays, err := s.applyOverlays(name, openAPISpec, content)
+	if err != nil {
+		return nil, err
+	}
 
-			// Apply mocking if enabled
-			if isMockEnabled {
-				klog.Infof("Mock is enabled for %s, generating fake examples", name)
-				mockedContent, err := mockers.MockOpenAPISpec(string(content))
-				if err != nil {
-					klog.Warningf("Failed to generate mock data: %v. Using original content.", err)
-				} else {
-					content = []byte(mockedContent)
-					klog.Info("Successfully generated mock examples")
-				}
-			}
+	recordings := s.recordingStore(key)
+	processed, err := transformSpec(name, openAPISpec, content, recordings.list())
+	if err != nil {
+		return nil, err
+	}
 
-			// Create the spec file
-			specFile, err := os.Create(specFilePath)
-			if err != nil {
-				return "", fmt.Errorf("failed to create spec file: %v", err)
-			}
-			defer specFile.Close()
+	callbacks, err := s.callbackSettings(openAPISpec)
+	if err != nil {
+		return nil, err
+	}
 
-			// Write the content to file
-			_, err = specFile.Write(content)
-			if err != nil {
-				return "", fmt.Errorf("failed to write spec to file: %v", err)
-			}
+	tryItOut, err := s.tryItOutSettings(openAPISpec)
+	if err != nil {
+		return nil, err
+	}
+	if tryItOut != nil {
+		// Relative server URLs resolve against the address the spec was downloaded from
+		proxyURL := fmt.Sprintf("%s/proxy/%s/%s", s.externalURL, openAPISpec.Namespace, openAPISpec.Name)
+		if processed.content, err = proxyServers(processed.content, proxyURL); err != nil {
+			return nil, fmt.Errorf("failed to point the servers at the try it out proxy: %v", err)
 		}
-	} else {
-		return "", fmt.Errorf("neither specPath nor specContent provided in OpenAPISpec %s", name)
+	}
+
+	// Write the content to file
+	if err := os.WriteFile(specFilePath, processed.content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write spec to file: %v", err)
 	}
 
 	// Try to load the spec to validate it (but we don't modify it)
@@ -277,24 +335,106 @@ func (s *Server) RegisterSpec(openAPISpec *docsv1.OpenAPISpec) (string, error) {
 		SpecPath: specPath,
 		SpecURL:  fmt.Sprintf("%s/specs/%s", baseURL, specFilename),
 		Document: document,
+
+		Namespace:   openAPISpec.Namespace,
+		Visibility:  openAPISpec.Spec.Visibility,
+		Description: openAPISpec.Spec.Description,
+		Version:     openAPISpec.Spec.Version,
+		Category:    openAPISpec.Labels[CategoryLabel],
+		Tags:        documentTags(processed.document),
+		LastUpdated: time.Now(),
+
+		OpenAPI:     processed.document,
+		Mock:        openAPISpec.Spec.Mock,
+		MockOptions: processed.mockOptions,
+
+		callbacks:  callbacks,
+		deliveries: &deliveryLog{},
+
+		recording:  openAPISpec.Spec.Recording,
+		recordings: recordings,
+
+		tryItOut: tryItOut,
+
+		// Show the report stored in the status until the next run
+		contractTest: openAPISpec.Status.ContractTest,
+
+		health: newHealthMonitor(openAPISpec),
+
+		branding: openAPISpec.Spec.Branding,
+	}
+	if specInfo.Visibility == "" {
+		specInfo.Visibility = docsv1.VisibilityInternal
+	}
+	if specInfo.Description == "" {
+		specInfo.Description = documentInfo(processed.document, "description")
+	}
+	if specInfo.Version == "" {
+		specInfo.Version = documentInfo(processed.document, "version")
+	}
+	if specInfo.recording != nil {
+		specInfo.recordingTarget = serviceURL(openAPISpec.Namespace, specInfo.recording.Target)
 	}
 
-	s.specs[name] = specInfo
+	s.specsMutex.Lock()
+	defer s.specsMutex.Unlock()
+
+	// Keep the delivery log and the contract test report across updates of the spec
+	if previous, ok := s.specs[key]; ok {
+		specInfo.deliveries = previous.deliveries
+		if previous.contractTest != nil {
+			specInfo.contractTest = previous.contractTest
+		}
+		// Restart the health checks with the new settings, keeping the history
+		if previous.health != nil {
+			close(previous.health.stop)
+			if specInfo.health != nil {
+				specInfo.health.probes = previous.health.history()
+			}
+		}
+	}
+	if specInfo.health != nil {
+		go specInfo.health.run(s.httpClient)
+	}
+
+	s.specs[key] = specInfo
 
 	// Return the documentation URL
-	return fmt.Sprintf("%s/docs/%s", baseURL, name), nil
+	return &Registration{
+		URL:             fmt.Sprintf("%s/docs/%s/%s", baseURL, key.Namespace, key.Name),
+		MockWarnings:    processed.mockWarnings,
+		InvalidExamples: processed.invalidExamples,
+		Overlays:        overlays,
+	}, nil
 }
 
-// handleDoc handles requests for specific API documentation
-func (s *Server) handleDoc(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	name := vars["name"]
-
-	s.specsMutex.RLock()
-	specInfo, ok := s.specs[name]
-	s.specsMutex.RUnlock()
+// UnregisterSpec removes a deleted OpenAPISpec: its pages, mocks and try it out proxy stop
+// being served and its health checks stop. Recordings stay on disk for a spec recreated later.
+func (s *Server) UnregisterSpec(namespace, name string) {
+	s.specsMutex.Lock()
+	defer s.specsMutex.Unlock()
 
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	specInfo, ok := s.specs[key]
 	if !ok {
+		return
+	}
+	if specInfo.health != nil {
+		close(specInfo.health.stop)
+	}
+	delete(s.specs, key)
+
+	specFilePath := filepath.Join(s.specDirectory, namespace, name+".json")
+	if err := os.Remove(specFilePath); err != nil && !os.IsNotExist(err) {
+		klog.Warningf("Failed to remove the spec file of %s: %v", key, err)
+	}
+	klog.Infof("Unregistered OpenAPI spec %s", key)
+}
+
+// handleDoc handles requests for specific API documentation
+func (s *Server) handleDoc(w http.ResponseWriter, r *http.Request) {
+	_, specInfo := s.requestedSpec(r)
+	if specInfo == nil {
 		http.Error(w, "API documentation not found", http.StatusNotFound)
 		return
 	}
@@ -306,12 +446,22 @@ func (s *Server) handleDoc(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Portals may be served on another host than the external URL
+	specURL := specInfo.SpecURL
+	if portalFromContext(r.Context()) != nil {
+		specURL = fmt.Sprintf("/specs/%s/%s", specInfo.Namespace, path.Base(specInfo.SpecURL))
+	}
+
 	data := struct {
-		Title   string
-		SpecURL string
+		Title    string
+		SpecURL  string
+		Health   template.HTML
+		Branding PageBranding
 	}{
-		Title:   specInfo.Title,
-		SpecURL: specInfo.SpecURL,
+		Title:    specInfo.Title,
+		SpecURL:  specURL,
+		Health:   template.HTML(healthBadge(specInfo.health.summary())),
+		Branding: s.pageBranding(specInfo.branding),
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -320,23 +470,3 @@ func (s *Server) handleDoc(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
-
-// handleIndex handles requests to the root path
-func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	s.specsMutex.RLock()
-	defer s.specsMutex.RUnlock()
-
-	if len(s.specs) == 0 {
-		fmt.Fprintf(w, "<html><body><h1>Redokube Documentation</h1><p>No API documentation available yet.</p></body></html>")
-		return
-	}
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	fmt.Fprintf(w, "<html><body><h1>Redokube Documentation</h1><ul>")
-
-	for name, specInfo := range s.specs {
-		fmt.Fprintf(w, "<li><a href=\"/docs/%s\">%s</a></li>", name, specInfo.Title)
-	}
-
-	fmt.Fprintf(w, "</ul></body></html>")
-}
@@ -0,0 +1,107 @@
+package redoc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+// blockingReader returns Secrets holding a key "token" once released
+type blockingReader struct {
+	client.Reader
+	release chan struct{}
+}
+
+func (r blockingReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	<-r.release
+	obj.(*corev1.Secret).Data = map[string][]byte{"token": []byte("secret")}
+	return nil
+}
+
+// registerSlowSpec registers a spec whose registration waits on release, checking that
+// the documentation of ns/pets stays readable meanwhile
+func registerSlowSpec(t *testing.T, s *Server, spec *docsv1.OpenAPISpec, release chan struct{}) {
+	t.Helper()
+	done := make(chan error)
+	go func() {
+		_, err := s.RegisterSpec(spec)
+		done <- err
+	}()
+
+	served := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/ns/pets", nil))
+		served <- w.Code
+	}()
+	select {
+	case code := <-served:
+		if code != http.StatusOK {
+			t.Errorf("status = %d, want %d", code, http.StatusOK)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the documentation was blocked by the registration of another spec")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/"+spec.Namespace+"/"+spec.Name, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status of the registered spec = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestRegisterSpecFetchesWithoutLock(t *testing.T) {
+	s := newTestServer(t)
+	registerTestSpec(t, s, "ns", "pets", mockSpec, nil)
+
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(mockSpec))
+	}))
+	defer upstream.Close()
+
+	spec := &docsv1.OpenAPISpec{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "slow"}}
+	spec.Spec.SpecPath = upstream.URL
+	registerSlowSpec(t, s, spec, release)
+}
+
+func TestRegisterSpecReadsSecretsWithoutLock(t *testing.T) {
+	secretRef := corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "hooks"}, Key: "token"}
+	tests := []struct {
+		name string
+		edit func(*docsv1.OpenAPISpec)
+	}{
+		{"callbacks", func(spec *docsv1.OpenAPISpec) {
+			spec.Spec.Mock = true
+			spec.Spec.MockOptions = &docsv1.MockOptions{Callbacks: &docsv1.CallbackOptions{SecretRef: &secretRef}}
+		}},
+		{"try it out", func(spec *docsv1.OpenAPISpec) {
+			spec.Spec.TryItOut = &docsv1.TryItOutOptions{Target: docsv1.ServiceTarget{ServiceName: "pets", Port: 80}, SecretHeaders: []docsv1.SecretHeader{{Name: "Authorization", SecretKeyRef: secretRef}}}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			s := newTestServer(t, WithSecretReader(blockingReader{release: release}))
+			registerTestSpec(t, s, "ns", "pets", mockSpec, nil)
+
+			spec := &docsv1.OpenAPISpec{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "slow"}}
+			spec.Spec.SpecContent = mockSpec
+			tt.edit(spec)
+			registerSlowSpec(t, s, spec, release)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package redoc
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+	"github.com/BombartSimon/redokube/pkg/openapi"
+)
+
+// Defaults of the try it out proxy
+const (
+	defaultTryItOutRateLimit = 60
+	rateLimitWindow          = time.Minute
+)
+
+// tryItOutSettings holds the proxy settings of a spec, with its secret headers resolved
+type tryItOutSettings struct {
+	target     *url.URL
+	pathPrefix string
+	headers    http.Header
+	limiter    *rateLimiter
+}
+
+// tryItOutSettings resolves the try it out options of an OpenAPISpec, nil when the proxy is disabled
+func (s *Server) tryItOutSettings(openAPISpec *docsv1.OpenAPISpec) (*tryItOutSettings, error) {
+	spec := openAPISpec.Spec.TryItOut
+	if spec == nil {
+		return nil, nil
+	}
+
+	settings := &tryItOutSettings{
+		target:     serviceURL(openAPISpec.Namespace, spec.Target),
+		pathPrefix: "/" + strings.Trim(spec.PathPrefix, "/"),
+		headers:    make(http.Header),
+	}
+	if settings.pathPrefix == "/" {
+		settings.pathPrefix = ""
+	}
+	for header, value := range spec.Headers {
+		settings.headers.Set(header, value)
+	}
+	for _, header := range spec.SecretHeaders {
+		value, err := s.secretValue(openAPISpec.Namespace, &header.SecretKeyRef)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve the value of header %s: %v", header.Name, err)
+		}
+		if value != nil {
+			settings.headers.Set(header.Name, string(value))
+		}
+	}
+
+	limit := int(spec.RateLimit)
+	if limit == 0 {
+		limit = defaultTryItOutRateLimit
+	}
+	settings.limiter = newRateLimiter(limit, rateLimitWindow)
+	return settings, nil
+}
+
+// handleProxy forwards the calls made from the documentation under /proxy/{namespace}/{name}/
+// to the service, for the operations declared in the spec only
+func (s *Server) handleProxy(w http.ResponseWriter, r *http.Request) {
+	key, specInfo := s.requestedSpec(r)
+	name := key.String()
+
+	if specInfo == nil || specInfo.tryItOut == nil || specInfo.OpenAPI == nil {
+		writeJSONError(w, http.StatusNotFound, "try it out is not enabled for this API")
+		return
+	}
+	settings := specInfo.tryItOut
+
+	requestPath := strings.TrimPrefix(r.URL.Path, "/proxy/"+name)
+	pathKey, _, ok := openapi.MatchPath(specInfo.OpenAPI, requestPath)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("no operation matches %s", requestPath))
+		return
+	}
+	if operationOf(specInfo.OpenAPI, pathKey, r.Method) == nil {
+		writeJSONError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s %s is not declared in the spec", r.Method, pathKey))
+		return
+	}
+
+	if allowed, retryAfter := settings.limiter.allow(s.requestUser(r), time.Now()); !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded, try again later")
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		// Without a timeout, as the calls may stream their responses
+		Transport: s.httpClient.Transport,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(settings.target)
+			pr.Out.URL.Path = settings.pathPrefix + requestPath
+			pr.Out.URL.RawPath = ""
+			pr.SetXForwarded()
+			// Cookies of the portal must never reach the service
+			pr.Out.Header.Del("Cookie")
+			for header, values := range settings.headers {
+				pr.Out.Header[header] = values
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			resp.Header.Del("Set-Cookie")
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			klog.Warningf("Failed to forward %s %s for %s: %v", r.Method, requestPath, name, err)
+			writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("cannot reach %s: %v", settings.target.Host, err))
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// requestUser identifies the caller of a request for rate limiting, by its address when authentication is disabled.
+// Any client may send X-Forwarded-For, so it is only read from the trusted networks of the proxy authentication,
+// taking the address added by the proxy itself.
+func (s *Server) requestUser(r *http.Request) string {
+	if user := UserFromContext(r.Context()); user != nil {
+		return user.Name
+	}
+	proxy, ok := s.authenticator.(*ProxyAuthenticator)
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" && ok && proxy.trusted(r.RemoteAddr) {
+		hops := strings.Split(forwarded, ",")
+		if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+			return last
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimiter counts the requests of each user in fixed time windows
+type rateLimiter struct {
+	mutex   sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*rateWindow
+}
+
+// rateWindow is the request count of a user in the current window
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// newRateLimiter allows limit requests per user and window
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// allow records a request of a user, and returns the time to wait when the limit is reached
+func (l *rateLimiter) allow(user string, now time.Time) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	current, ok := l.windows[user]
+	if !ok || now.Sub(current.start) >= l.window {
+		// Forget the users whose window expired
+		for key, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, key)
+			}
+		}
+		current = &rateWindow{start: now}
+		l.windows[user] = current
+	}
+
+	if current.count >= l.limit {
+		return false, current.start.Add(l.window).Sub(now)
+	}
+	current.count++
+	return true, 0
+}
+
+// proxyServers points the servers of a document at the try it out proxy, so the
+// requests sent from the documentation go through it
+func proxyServers(content []byte, proxyURL string) ([]byte, error) {
+	doc, err := openapi.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if openapi.IsSwagger2(doc) {
+		parsed, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		delete(doc, "host")
+		if parsed.Host != "" {
+			doc["host"] = parsed.Host
+			doc["schemes"] = []interface{}{parsed.Scheme}
+		}
+		doc["basePath"] = parsed.Path
+		return openapi.Marshal(doc)
+	}
+
+	doc["servers"] = []interface{}{
+		map[string]interface{}{"url": proxyURL, "description": "Try it out proxy"},
+	}
+	// Servers of paths and operations would bypass the proxy
+	paths, _ := doc["paths"].(map[string]interface{})
+	for _, raw := range paths {
+		pathItem, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		delete(pathItem, "servers")
+		for _, method := range openapi.Methods {
+			if operation, ok := pathItem[method].(map[string]interface{}); ok {
+				delete(operation, "servers")
+			}
+		}
+	}
+	return openapi.Marshal(doc)
+}
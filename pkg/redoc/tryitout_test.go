@@ -0,0 +1,116 @@
+package redoc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	docsv1 "github.com/BombartSimon/redokube/api/v1"
+)
+
+func TestUnregisterSpecStopsTryItOut(t *testing.T) {
+	s := newTestServer(t)
+	var forwarded []string
+	s.httpClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		forwarded = append(forwarded, r.URL.String())
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("[]")), Request: r}, nil
+	})}
+	registerTestSpec(t, s, "ns", "pets", collisionSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.TryItOut = &docsv1.TryItOutOptions{
+			Target:     docsv1.ServiceTarget{ServiceName: "pets", Port: 8080},
+			PathPrefix: "/v1",
+		}
+	})
+
+	proxy := func() int {
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/proxy/ns/pets/items", nil))
+		return w.Code
+	}
+	if status := proxy(); status != http.StatusOK {
+		t.Fatalf("status before unregistering = %d, want %d", status, http.StatusOK)
+	}
+	if len(forwarded) != 1 || forwarded[0] != "http://pets.ns.svc:8080/v1/items" {
+		t.Fatalf("forwarded = %v", forwarded)
+	}
+
+	s.UnregisterSpec("ns", "pets")
+	if status := proxy(); status != http.StatusNotFound {
+		t.Errorf("status after unregistering = %d, want %d", status, http.StatusNotFound)
+	}
+	if len(forwarded) != 1 {
+		t.Errorf("forwarded %d requests after unregistering", len(forwarded)-1)
+	}
+}
+
+func TestRequestUser(t *testing.T) {
+	trusted, err := NewProxyAuthenticator("", "", []string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Built directly, as NewProxyAuthenticator requires trusted networks
+	untrusted := &ProxyAuthenticator{UserHeader: "X-Forwarded-User", GroupsHeader: "X-Forwarded-Groups"}
+
+	tests := []struct {
+		name          string
+		authenticator Authenticator
+		remoteAddr    string
+		forwardedFor  string
+		user          *User
+		want          string
+	}{
+		{"remote address", nil, "203.0.113.7:4321", "", nil, "203.0.113.7"},
+		{"forwarded without authentication", nil, "203.0.113.7:4321", "198.51.100.1", nil, "203.0.113.7"},
+		{"forwarded without trusted networks", untrusted, "10.0.0.2:4321", "198.51.100.1", nil, "10.0.0.2"},
+		{"forwarded by an untrusted address", trusted, "203.0.113.7:4321", "198.51.100.1", nil, "203.0.113.7"},
+		{"forwarded by a trusted proxy", trusted, "10.0.0.2:4321", "198.51.100.1", nil, "198.51.100.1"},
+		{"spoofed hop before the trusted proxy", trusted, "10.0.0.2:4321", "192.0.2.99, 198.51.100.1", nil, "198.51.100.1"},
+		{"authenticated user", trusted, "10.0.0.2:4321", "198.51.100.1", &User{Name: "alice"}, "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, WithAuthenticator(tt.authenticator))
+			r := httptest.NewRequest(http.MethodGet, "/proxy/ns/pets/items", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if tt.user != nil {
+				r = r.WithContext(context.WithValue(r.Context(), userKey{}, tt.user))
+			}
+			if got := s.requestUser(r); got != tt.want {
+				t.Errorf("requestUser = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTryItOutRateLimitIgnoresForwardedFor(t *testing.T) {
+	s := newTestServer(t)
+	s.httpClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("[]")), Request: r}, nil
+	})}
+	registerTestSpec(t, s, "ns", "pets", collisionSpec, func(spec *docsv1.OpenAPISpec) {
+		spec.Spec.TryItOut = &docsv1.TryItOutOptions{
+			Target:    docsv1.ServiceTarget{ServiceName: "pets", Port: 8080},
+			RateLimit: 2,
+		}
+	})
+
+	var statuses []int
+	for i := range 3 {
+		r := httptest.NewRequest(http.MethodGet, "/proxy/ns/pets/items", nil)
+		r.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		w := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(w, r)
+		statuses = append(statuses, w.Code)
+	}
+	if want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}; !slices.Equal(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+}